@@ -0,0 +1,221 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// An AccountService implements the divulge.AccountService interface by authorizing calls before
+// passing them off to another AccountService. Any member can see an account and who belongs to
+// it, admins manage it and only its owner can remove it or hand it over to someone else.
+type AccountService struct {
+	next divulge.AccountService
+	az   Authorizer
+}
+
+// NewAccountService returns a new AccountService.
+func NewAccountService(next divulge.AccountService, az Authorizer) AccountService {
+	return AccountService{
+		next: next,
+		az:   az,
+	}
+}
+
+// SaveAccount lets anyone create an account of their own. Updating one requires an admin, and
+// changing its owner requires the owner. Callers can't require two-factor authentication of
+// their own role without using it themselves, which would lock them out.
+func (s AccountService) SaveAccount(ctx context.Context, account divulge.Account) (uuid.UUID, error) {
+	if divulge.IsEmpty(account.ID) {
+		user, err := s.az.caller(ctx, divulge.ScopeAccountsWrite)
+		if err != nil {
+			return account.ID, err
+		}
+
+		if divulge.IsEmpty(account.OwnerID) {
+			account.OwnerID = user.ID
+		}
+
+		if account.OwnerID != user.ID {
+			return account.ID, divulge.ForbiddenError("accounts can only be created for yourself", nil)
+		}
+
+		if err := requireOwnTwoFactor(ctx, account, divulge.RoleOwner); err != nil {
+			return account.ID, err
+		}
+
+		return s.next.SaveAccount(ctx, account)
+	}
+
+	user, role, err := s.az.require(ctx, divulge.ScopeAccountsWrite, account.ID, divulge.RoleAdmin)
+	if err != nil {
+		return account.ID, err
+	}
+
+	if err := requireOwnTwoFactor(ctx, account, role); err != nil {
+		return account.ID, err
+	}
+
+	current, err := s.az.accounts.FetchAccount(ctx, account.ID)
+	if err != nil {
+		return account.ID, err
+	}
+
+	if divulge.IsEmpty(account.OwnerID) {
+		account.OwnerID = current.OwnerID
+	}
+
+	if account.OwnerID != current.OwnerID && user.ID != current.OwnerID {
+		return account.ID, divulge.ForbiddenError("only the owner can transfer an account", nil)
+	}
+
+	return s.next.SaveAccount(ctx, account)
+}
+
+// requireOwnTwoFactor keeps callers from saving a two-factor policy they don't meet.
+func requireOwnTwoFactor(ctx context.Context, account divulge.Account, role string) error {
+	if !divulge.HasTwoFactor(ctx) && twoFactorRequired(account.TwoFactorRole, role) {
+		return divulge.ForbiddenError("enable two-factor authentication before requiring it", nil)
+	}
+
+	return nil
+}
+
+// FetchAccount requires a member of the account.
+func (s AccountService) FetchAccount(ctx context.Context, id uuid.UUID) (divulge.Account, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopeAccountsRead, id, divulge.RoleViewer); err != nil {
+		return divulge.Account{}, err
+	}
+
+	return s.next.FetchAccount(ctx, id)
+}
+
+// ListAccounts only lists the accounts the caller is a member of.
+func (s AccountService) ListAccounts(ctx context.Context) ([]divulge.Account, error) {
+	user, err := s.az.caller(ctx, divulge.ScopeAccountsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.next.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	member := make(map[uuid.UUID]bool, len(user.Accounts))
+	for _, id := range user.Accounts {
+		member[id] = true
+	}
+
+	var visible []divulge.Account
+	for _, account := range accounts {
+		if member[account.ID] {
+			visible = append(visible, account)
+		}
+	}
+
+	return visible, nil
+}
+
+// RemoveAccount requires the account's owner.
+func (s AccountService) RemoveAccount(ctx context.Context, id uuid.UUID) error {
+	if err := s.requireOwner(ctx, id); err != nil {
+		return err
+	}
+
+	return s.next.RemoveAccount(ctx, id)
+}
+
+// AddAccountUser requires an admin. The owner role only changes hands when the account is
+// transferred.
+func (s AccountService) AddAccountUser(ctx context.Context, accountID, userID uuid.UUID, role string) error {
+	if _, _, err := s.az.require(ctx, divulge.ScopeAccountsWrite, accountID, divulge.RoleAdmin); err != nil {
+		return err
+	}
+
+	if role == divulge.RoleOwner {
+		return divulge.ValidationError("ownership can only be changed by transferring the account", nil)
+	}
+
+	account, err := s.az.accounts.FetchAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if userID == account.OwnerID {
+		return divulge.ConflictError("the owner's role can't be changed", nil)
+	}
+
+	return s.next.AddAccountUser(ctx, accountID, userID, role)
+}
+
+// ListAccountUsers requires a member of the account.
+func (s AccountService) ListAccountUsers(ctx context.Context, accountID uuid.UUID) ([]divulge.User, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopeAccountsRead, accountID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListAccountUsers(ctx, accountID)
+}
+
+// RemoveAccountUser requires an admin, except for members leaving on their own. The owner can't
+// be removed at all.
+func (s AccountService) RemoveAccountUser(ctx context.Context, accountID, userID uuid.UUID) error {
+	min := divulge.RoleAdmin
+	if user, ok := divulge.UserFromContext(ctx); ok && user.ID == userID {
+		min = divulge.RoleViewer
+	}
+
+	if _, _, err := s.az.require(ctx, divulge.ScopeAccountsWrite, accountID, min); err != nil {
+		return err
+	}
+
+	account, err := s.az.accounts.FetchAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if userID == account.OwnerID {
+		return divulge.ConflictError("the owner can't be removed from their account", nil)
+	}
+
+	return s.next.RemoveAccountUser(ctx, accountID, userID)
+}
+
+// FetchMembership requires a member of the account.
+func (s AccountService) FetchMembership(ctx context.Context, accountID, userID uuid.UUID) (divulge.Membership, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopeAccountsRead, accountID, divulge.RoleViewer); err != nil {
+		return divulge.Membership{}, err
+	}
+
+	return s.next.FetchMembership(ctx, accountID, userID)
+}
+
+// ListMemberships requires a member of the account.
+func (s AccountService) ListMemberships(ctx context.Context, accountID uuid.UUID) ([]divulge.Membership, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopeAccountsRead, accountID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListMemberships(ctx, accountID)
+}
+
+// requireOwner makes sure the caller is the account's OwnerID and meets its two-factor policy.
+func (s AccountService) requireOwner(ctx context.Context, id uuid.UUID) error {
+	user, _, err := s.az.require(ctx, divulge.ScopeAccountsWrite, id, divulge.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.az.accounts.FetchAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if account.OwnerID != user.ID {
+		return divulge.ForbiddenError("only the owner can do that", nil)
+	}
+
+	return nil
+}
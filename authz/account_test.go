@@ -0,0 +1,220 @@
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/authz"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func Test_AccountService_Ownership(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.AccountService{}
+	accounts := authz.NewAccountService(next, f.az)
+	transfer := f.account
+	transfer.OwnerID = f.members[divulge.RoleAdmin].ID
+	rename := f.account
+	rename.Name = "Renamed"
+
+	// RUN
+	adminRemoveErr := accounts.RemoveAccount(f.as(divulge.RoleAdmin), f.account.ID)
+	_, adminTransferErr := accounts.SaveAccount(f.as(divulge.RoleAdmin), transfer)
+	_, adminRenameErr := accounts.SaveAccount(f.as(divulge.RoleAdmin), rename)
+	_, ownerTransferErr := accounts.SaveAccount(f.as(divulge.RoleOwner), transfer)
+	ownerRemoveErr := accounts.RemoveAccount(f.as(divulge.RoleOwner), f.account.ID)
+
+	// ASSERT
+	if !errors.Is(adminRemoveErr, divulge.ErrForbidden) || !errors.Is(adminTransferErr, divulge.ErrForbidden) {
+		t.Fatalf("expected only the owner to remove or transfer, got %v, %v", adminRemoveErr, adminTransferErr)
+	}
+
+	if adminRenameErr != nil || ownerTransferErr != nil || ownerRemoveErr != nil {
+		t.Fatalf("unexpected errors: %v, %v, %v", adminRenameErr, ownerTransferErr, ownerRemoveErr)
+	}
+
+	if next.SaveAccountCount != 2 || next.RemoveAccountCount != 1 {
+		t.Fatal("expected only allowed calls to go through")
+	}
+}
+
+func Test_AccountService_Create(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	var saved divulge.Account
+	next := &mock.AccountService{
+		SaveAccountFn: func(ctx context.Context, account divulge.Account) (uuid.UUID, error) {
+			saved = account
+			return uuid.New(), nil
+		},
+	}
+	accounts := authz.NewAccountService(next, f.az)
+
+	// RUN
+	_, ownErr := accounts.SaveAccount(f.as(divulge.RoleViewer), divulge.Account{Name: "Mine"})
+	_, otherErr := accounts.SaveAccount(f.as(divulge.RoleViewer), divulge.Account{Name: "Theirs", OwnerID: uuid.New()})
+
+	// ASSERT
+	if ownErr != nil {
+		t.Fatalf("unexpected error: %s", ownErr)
+	}
+
+	if saved.OwnerID != f.members[divulge.RoleViewer].ID {
+		t.Fatalf("expected the caller to own the account, got %+v", saved)
+	}
+
+	if !errors.Is(otherErr, divulge.ErrForbidden) {
+		t.Fatalf("expected forbidden, got %v", otherErr)
+	}
+}
+
+func Test_AccountService_Members(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.AccountService{}
+	accounts := authz.NewAccountService(next, f.az)
+	newcomer := uuid.New()
+	authorID := f.members[divulge.RoleAuthor].ID
+	ownerID := f.members[divulge.RoleOwner].ID
+
+	// RUN
+	adminAddErr := accounts.AddAccountUser(f.as(divulge.RoleAdmin), f.account.ID, newcomer, divulge.RoleEditor)
+	editorAddErr := accounts.AddAccountUser(f.as(divulge.RoleEditor), f.account.ID, newcomer, divulge.RoleViewer)
+	grantOwnerErr := accounts.AddAccountUser(f.as(divulge.RoleOwner), f.account.ID, newcomer, divulge.RoleOwner)
+	demoteOwnerErr := accounts.AddAccountUser(f.as(divulge.RoleAdmin), f.account.ID, ownerID, divulge.RoleViewer)
+	leaveErr := accounts.RemoveAccountUser(f.as(divulge.RoleAuthor), f.account.ID, authorID)
+	kickErr := accounts.RemoveAccountUser(f.as(divulge.RoleEditor), f.account.ID, authorID)
+	removeOwnerErr := accounts.RemoveAccountUser(f.as(divulge.RoleAdmin), f.account.ID, ownerID)
+
+	// ASSERT
+	if adminAddErr != nil || leaveErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", adminAddErr, leaveErr)
+	}
+
+	if !errors.Is(editorAddErr, divulge.ErrForbidden) || !errors.Is(kickErr, divulge.ErrForbidden) {
+		t.Fatalf("expected editors not to manage members, got %v, %v", editorAddErr, kickErr)
+	}
+
+	if !errors.Is(grantOwnerErr, divulge.ErrValidation) {
+		t.Fatalf("expected the owner role not to be granted, got %v", grantOwnerErr)
+	}
+
+	if !errors.Is(demoteOwnerErr, divulge.ErrConflict) || !errors.Is(removeOwnerErr, divulge.ErrConflict) {
+		t.Fatalf("expected the owner to be protected, got %v, %v", demoteOwnerErr, removeOwnerErr)
+	}
+}
+
+func Test_AccountService_TwoFactor(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	f.account.TwoFactorRole = divulge.RoleAdmin
+	next := &mock.AccountService{}
+	accounts := authz.NewAccountService(next, f.az)
+	enrolled := f.members[divulge.RoleAdmin]
+	enrolled.TwoFactor = true
+	policy := f.account
+	policy.TwoFactorRole = divulge.RoleEditor
+
+	// RUN
+	_, adminErr := accounts.FetchAccount(f.as(divulge.RoleAdmin), f.account.ID)
+	_, editorErr := accounts.FetchAccount(f.as(divulge.RoleEditor), f.account.ID)
+	_, enrolledErr := accounts.FetchAccount(divulge.WithUser(context.TODO(), enrolled), f.account.ID)
+	_, securedErr := accounts.FetchAccount(divulge.WithTwoFactor(f.as(divulge.RoleAdmin)), f.account.ID)
+	_, ownPolicyErr := accounts.SaveAccount(f.as(divulge.RoleOwner), policy)
+	_, createErr := accounts.SaveAccount(f.as(divulge.RoleViewer), divulge.Account{Name: "Mine", TwoFactorRole: divulge.RoleOwner})
+	_, policyErr := accounts.SaveAccount(divulge.WithTwoFactor(f.as(divulge.RoleOwner)), policy)
+	f.account.TwoFactorRole = divulge.RoleOwner
+	removeErr := accounts.RemoveAccount(f.as(divulge.RoleOwner), f.account.ID)
+
+	// ASSERT
+	if !errors.Is(adminErr, divulge.ErrForbidden) {
+		t.Fatalf("expected admins without two-factor authentication to be forbidden, got %v", adminErr)
+	}
+
+	if !errors.Is(enrolledErr, divulge.ErrForbidden) {
+		t.Fatalf("expected credentials from before enrolling in two-factor authentication to be forbidden, got %v", enrolledErr)
+	}
+
+	if editorErr != nil || securedErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", editorErr, securedErr)
+	}
+
+	if !errors.Is(ownPolicyErr, divulge.ErrForbidden) || !errors.Is(createErr, divulge.ErrForbidden) {
+		t.Fatalf("expected callers not to lock themselves out, got %v, %v", ownPolicyErr, createErr)
+	}
+
+	if policyErr != nil {
+		t.Fatalf("unexpected error: %s", policyErr)
+	}
+
+	if !errors.Is(removeErr, divulge.ErrForbidden) {
+		t.Fatalf("expected owners without two-factor authentication not to remove the account, got %v", removeErr)
+	}
+
+	if next.FetchAccountCount != 2 || next.SaveAccountCount != 1 || next.RemoveAccountCount != 0 {
+		t.Fatal("expected only allowed calls to go through")
+	}
+}
+
+func Test_AccountService_ListAccounts(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.AccountService{
+		ListAccountsFn: func(ctx context.Context) ([]divulge.Account, error) {
+			return []divulge.Account{f.account, {ID: uuid.New()}}, nil
+		},
+	}
+	accounts := authz.NewAccountService(next, f.az)
+
+	// RUN
+	listed, err := accounts.ListAccounts(f.as(divulge.RoleViewer))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if len(listed) != 1 || listed[0].ID != f.account.ID {
+		t.Fatalf("expected only the caller's accounts, got %+v", listed)
+	}
+}
+
+func Test_UserService(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	var saved divulge.User
+	next := &mock.UserService{
+		SaveUserFn: func(ctx context.Context, user divulge.User) (uuid.UUID, error) {
+			saved = user
+			return user.ID, nil
+		},
+	}
+	users := authz.NewUserService(next, f.az)
+	self := f.members[divulge.RoleAuthor]
+	self.Accounts = []uuid.UUID{uuid.New()}
+
+	// RUN
+	_, signupErr := users.SaveUser(context.TODO(), divulge.User{Name: "New"})
+	_, joinErr := users.SaveUser(context.TODO(), divulge.User{Name: "New", Accounts: []uuid.UUID{f.account.ID}})
+	_, otherErr := users.SaveUser(f.as(divulge.RoleAuthor), f.members[divulge.RoleViewer])
+	_, selfErr := users.SaveUser(f.as(divulge.RoleAuthor), self)
+	removeErr := users.RemoveUser(f.as(divulge.RoleOwner), self.ID)
+
+	// ASSERT
+	if signupErr != nil || selfErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", signupErr, selfErr)
+	}
+
+	if saved.Accounts != nil {
+		t.Fatalf("expected updates to leave memberships alone, got %v", saved.Accounts)
+	}
+
+	for _, err := range []error{joinErr, otherErr, removeErr} {
+		if !errors.Is(err, divulge.ErrForbidden) {
+			t.Fatalf("expected forbidden, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package authz wraps divulge services so callers can only do what their Role within an Account
+// allows. The caller is the User attached to the context with divulge.WithUser; calls without
+// one fail with an ErrUnauthorized and calls the caller isn't allowed to make fail with an
+// ErrForbidden. Contexts limited with divulge.WithScopes, like those of APIToken requests, are
+// also refused anything outside of their scopes, and Accounts can refuse members of some roles
+// whose credentials weren't verified with two-factor authentication, as marked by
+// divulge.WithTwoFactor.
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// ranks orders roles by privilege. Unknown roles have no privileges at all.
+var ranks = map[string]int{
+	divulge.RoleViewer: 1,
+	divulge.RoleAuthor: 2,
+	divulge.RoleEditor: 3,
+	divulge.RoleAdmin:  4,
+	divulge.RoleOwner:  5,
+}
+
+// atLeast reports whether role can do everything min can.
+func atLeast(role, min string) bool {
+	rank, ok := ranks[role]
+	return ok && rank >= ranks[min]
+}
+
+// twoFactorRequired reports whether an account's TwoFactorRole requires role to use two-factor
+// authentication.
+func twoFactorRequired(policy, role string) bool {
+	_, ok := ranks[policy]
+	return ok && atLeast(role, policy)
+}
+
+// An Authorizer looks up who's allowed to do what. It reads from the stores directly, rather
+// than the services being wrapped, so it sees Posts in the trash and never loads content.
+type Authorizer struct {
+	accounts  divulge.AccountService
+	posts     divulge.PostService
+	revisions divulge.RevisionService
+}
+
+// New returns a new Authorizer.
+func New(accounts divulge.AccountService, posts divulge.PostService, revisions divulge.RevisionService) Authorizer {
+	return Authorizer{
+		accounts:  accounts,
+		posts:     posts,
+		revisions: revisions,
+	}
+}
+
+// caller returns the User making a request, as long as the request allows scope.
+func (a Authorizer) caller(ctx context.Context, scope string) (divulge.User, error) {
+	user, ok := divulge.UserFromContext(ctx)
+	if !ok {
+		return user, divulge.UnauthorizedError("authentication required", nil)
+	}
+
+	if !divulge.HasScope(ctx, scope) {
+		return user, divulge.ForbiddenError("requires the "+scope+" scope", nil)
+	}
+
+	return user, nil
+}
+
+// require returns the caller and their role within an account, as long as the request allows
+// scope, that role is at least min and the caller meets the account's two-factor policy.
+func (a Authorizer) require(ctx context.Context, scope string, accountID uuid.UUID, min string) (divulge.User, string, error) {
+	user, err := a.caller(ctx, scope)
+	if err != nil {
+		return user, "", err
+	}
+
+	membership, err := a.accounts.FetchMembership(ctx, accountID, user.ID)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return user, "", divulge.ForbiddenError("not a member of this account", err)
+		}
+
+		return user, "", err
+	}
+
+	if !atLeast(membership.Role, min) {
+		return user, membership.Role, divulge.ForbiddenError("requires the "+min+" role", nil)
+	}
+
+	if err := a.requireTwoFactor(ctx, accountID, membership.Role); err != nil {
+		return user, membership.Role, err
+	}
+
+	return user, membership.Role, nil
+}
+
+// requireTwoFactor enforces an account's two-factor policy. It's the credentials that need to
+// have been verified with two-factor authentication, not just the User enrolled in it. Verified
+// callers always meet the policy, so the account is only looked up for the ones without.
+func (a Authorizer) requireTwoFactor(ctx context.Context, accountID uuid.UUID, role string) error {
+	if divulge.HasTwoFactor(ctx) {
+		return nil
+	}
+
+	account, err := a.accounts.FetchAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if twoFactorRequired(account.TwoFactorRole, role) {
+		return divulge.ForbiddenError("this account requires two-factor authentication", nil)
+	}
+
+	return nil
+}
+
+// requirePost is like require for the account of the post with the given ID. The caller is
+// checked before the post is looked up, and missing posts are refused like posts in someone
+// else's account, so that neither gives away which IDs exist.
+func (a Authorizer) requirePost(ctx context.Context, scope string, id uuid.UUID, min string) (divulge.Post, divulge.User, string, error) {
+	user, err := a.caller(ctx, scope)
+	if err != nil {
+		return divulge.Post{}, user, "", err
+	}
+
+	post, err := a.posts.FetchPost(ctx, id)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return post, user, "", divulge.ForbiddenError("not a member of this account", err)
+		}
+
+		return post, user, "", err
+	}
+
+	user, role, err := a.require(ctx, scope, post.AccountID, min)
+	return post, user, role, err
+}
+
+// requireEdit returns the post with the given ID as long as the caller can edit it. Editors can
+// edit anything, while authors can only edit their own drafts.
+func (a Authorizer) requireEdit(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+	post, user, role, err := a.requirePost(ctx, divulge.ScopePostsWrite, id, divulge.RoleAuthor)
+	if err != nil {
+		return post, err
+	}
+
+	if atLeast(role, divulge.RoleEditor) {
+		return post, nil
+	}
+
+	if post.AuthorID != user.ID || post.PublishedAt != nil {
+		return post, divulge.ForbiddenError("authors can only edit their own drafts", nil)
+	}
+
+	return post, nil
+}
+
+// requireRevision is like require for the account of the revision with the given ID.
+func (a Authorizer) requireRevision(ctx context.Context, scope string, id uuid.UUID, min string) (divulge.Revision, error) {
+	if _, err := a.caller(ctx, scope); err != nil {
+		return divulge.Revision{}, err
+	}
+
+	revision, err := a.revisions.FetchRevision(ctx, id)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return revision, divulge.ForbiddenError("not a member of this account", err)
+		}
+
+		return revision, err
+	}
+
+	_, _, _, err = a.requirePost(ctx, scope, revision.PostID, min)
+	return revision, err
+}
@@ -0,0 +1,211 @@
+package authz
+
+import (
+	"context"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// A PostService implements the divulge.PostService interface by authorizing calls before
+// passing them off to another PostService. Authors can write and trash their own drafts,
+// editors can do anything with any post and admins can purge them for good. Any member can
+// read.
+type PostService struct {
+	next divulge.PostService
+	az   Authorizer
+}
+
+// NewPostService returns a new PostService.
+func NewPostService(next divulge.PostService, az Authorizer) PostService {
+	return PostService{
+		next: next,
+		az:   az,
+	}
+}
+
+// SavePost makes sure the caller can write the post. New posts are written by the caller unless
+// an editor says otherwise.
+func (s PostService) SavePost(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
+	if !divulge.IsEmpty(post.ID) {
+		if _, err := s.az.requireEdit(ctx, post.ID); err != nil {
+			return post.ID, err
+		}
+
+		return s.next.SavePost(ctx, post)
+	}
+
+	user, role, err := s.az.require(ctx, divulge.ScopePostsWrite, post.AccountID, divulge.RoleAuthor)
+	if err != nil {
+		return post.ID, err
+	}
+
+	if divulge.IsEmpty(post.AuthorID) {
+		post.AuthorID = user.ID
+	}
+
+	if post.AuthorID != user.ID && !atLeast(role, divulge.RoleEditor) {
+		return post.ID, divulge.ForbiddenError("only editors can write posts for someone else", nil)
+	}
+
+	return s.next.SavePost(ctx, post)
+}
+
+// PublishPost requires an editor.
+func (s PostService) PublishPost(ctx context.Context, id uuid.UUID) error {
+	if _, _, _, err := s.az.requirePost(ctx, divulge.ScopePostsPublish, id, divulge.RoleEditor); err != nil {
+		return err
+	}
+
+	return s.next.PublishPost(ctx, id)
+}
+
+// RedactPost requires an editor.
+func (s PostService) RedactPost(ctx context.Context, id uuid.UUID) error {
+	if _, _, _, err := s.az.requirePost(ctx, divulge.ScopePostsPublish, id, divulge.RoleEditor); err != nil {
+		return err
+	}
+
+	return s.next.RedactPost(ctx, id)
+}
+
+// SchedulePost requires an editor, since it publishes the post eventually.
+func (s PostService) SchedulePost(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if _, _, _, err := s.az.requirePost(ctx, divulge.ScopePostsPublish, id, divulge.RoleEditor); err != nil {
+		return err
+	}
+
+	return s.next.SchedulePost(ctx, id, at)
+}
+
+// UnschedulePost requires an editor.
+func (s PostService) UnschedulePost(ctx context.Context, id uuid.UUID) error {
+	if _, _, _, err := s.az.requirePost(ctx, divulge.ScopePostsPublish, id, divulge.RoleEditor); err != nil {
+		return err
+	}
+
+	return s.next.UnschedulePost(ctx, id)
+}
+
+// FetchPost requires a member of the post's account.
+func (s PostService) FetchPost(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+	if _, _, _, err := s.az.requirePost(ctx, divulge.ScopePostsRead, id, divulge.RoleViewer); err != nil {
+		return divulge.Post{}, err
+	}
+
+	return s.next.FetchPost(ctx, id)
+}
+
+// FetchPostBySlug requires a member of the account.
+func (s PostService) FetchPostBySlug(ctx context.Context, accountID uuid.UUID, slug string) (divulge.Post, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsRead, accountID, divulge.RoleViewer); err != nil {
+		return divulge.Post{}, err
+	}
+
+	return s.next.FetchPostBySlug(ctx, accountID, slug)
+}
+
+// ListPostsByAccount requires a member of the account.
+func (s PostService) ListPostsByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsRead, accountID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListPostsByAccount(ctx, accountID)
+}
+
+// RemovePost makes sure the caller can edit the post before moving it to the trash.
+func (s PostService) RemovePost(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.az.requireEdit(ctx, id); err != nil {
+		return err
+	}
+
+	return s.next.RemovePost(ctx, id)
+}
+
+// ListTrashByAccount requires a member of the account.
+func (s PostService) ListTrashByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsRead, accountID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListTrashByAccount(ctx, accountID)
+}
+
+// RestorePost makes sure the caller could have trashed the post.
+func (s PostService) RestorePost(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.az.requireEdit(ctx, id); err != nil {
+		return err
+	}
+
+	return s.next.RestorePost(ctx, id)
+}
+
+// PurgePost requires an admin, since purged posts can't be brought back.
+func (s PostService) PurgePost(ctx context.Context, id uuid.UUID) error {
+	if _, _, _, err := s.az.requirePost(ctx, divulge.ScopePostsWrite, id, divulge.RoleAdmin); err != nil {
+		return err
+	}
+
+	return s.next.PurgePost(ctx, id)
+}
+
+// A RevisionHistory implements the divulge.RevisionHistory interface by authorizing calls before
+// passing them off to another RevisionHistory. Any member can browse revisions, while restoring
+// one is an edit of its post.
+type RevisionHistory struct {
+	next divulge.RevisionHistory
+	az   Authorizer
+}
+
+// NewRevisionHistory returns a new RevisionHistory.
+func NewRevisionHistory(next divulge.RevisionHistory, az Authorizer) RevisionHistory {
+	return RevisionHistory{
+		next: next,
+		az:   az,
+	}
+}
+
+// FetchRevision requires a member of the post's account.
+func (h RevisionHistory) FetchRevision(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+	if _, err := h.az.requireRevision(ctx, divulge.ScopePostsRead, id, divulge.RoleViewer); err != nil {
+		return divulge.Revision{}, err
+	}
+
+	return h.next.FetchRevision(ctx, id)
+}
+
+// ListRevisionsByPost requires a member of the post's account.
+func (h RevisionHistory) ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error) {
+	if _, _, _, err := h.az.requirePost(ctx, divulge.ScopePostsRead, postID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return h.next.ListRevisionsByPost(ctx, postID)
+}
+
+// DiffRevisions requires a member of the accounts both revisions belong to.
+func (h RevisionHistory) DiffRevisions(ctx context.Context, fromID, toID uuid.UUID) ([]divulge.DiffLine, error) {
+	for _, id := range []uuid.UUID{fromID, toID} {
+		if _, err := h.az.requireRevision(ctx, divulge.ScopePostsRead, id, divulge.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.next.DiffRevisions(ctx, fromID, toID)
+}
+
+// RestoreRevision makes sure the caller can edit the revision's post.
+func (h RevisionHistory) RestoreRevision(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	revision, err := h.az.revisions.FetchRevision(ctx, id)
+	if err != nil {
+		return revision.PostID, err
+	}
+
+	if _, err := h.az.requireEdit(ctx, revision.PostID); err != nil {
+		return revision.PostID, err
+	}
+
+	return h.next.RestoreRevision(ctx, id)
+}
@@ -0,0 +1,257 @@
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/authz"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+// fixture is an account with one member for each role, a draft and a published post written by
+// the author.
+type fixture struct {
+	account   divulge.Account
+	members   map[string]divulge.User
+	draft     divulge.Post
+	published divulge.Post
+
+	accounts *mock.AccountService
+	posts    *mock.PostService
+	az       authz.Authorizer
+}
+
+func newFixture() *fixture {
+	f := &fixture{members: make(map[string]divulge.User)}
+	for _, role := range []string{divulge.RoleOwner, divulge.RoleAdmin, divulge.RoleEditor, divulge.RoleAuthor, divulge.RoleViewer} {
+		f.members[role] = divulge.User{ID: uuid.New(), Name: role}
+	}
+
+	f.account = divulge.Account{ID: uuid.New(), OwnerID: f.members[divulge.RoleOwner].ID}
+	for role, user := range f.members {
+		user.Accounts = []uuid.UUID{f.account.ID}
+		f.members[role] = user
+	}
+
+	now := time.Now()
+	f.draft = divulge.Post{ID: uuid.New(), AccountID: f.account.ID, AuthorID: f.members[divulge.RoleAuthor].ID}
+	f.published = divulge.Post{ID: uuid.New(), AccountID: f.account.ID, AuthorID: f.members[divulge.RoleAuthor].ID, PublishedAt: &now}
+
+	f.accounts = &mock.AccountService{
+		FetchAccountFn: func(ctx context.Context, id uuid.UUID) (divulge.Account, error) {
+			if id != f.account.ID {
+				return divulge.Account{}, divulge.NotFoundError("account not found", nil)
+			}
+
+			return f.account, nil
+		},
+		FetchMembershipFn: func(ctx context.Context, accountID, userID uuid.UUID) (divulge.Membership, error) {
+			for role, user := range f.members {
+				if accountID == f.account.ID && user.ID == userID {
+					return divulge.Membership{AccountID: accountID, UserID: userID, Role: role}, nil
+				}
+			}
+
+			return divulge.Membership{}, divulge.NotFoundError("membership not found", nil)
+		},
+	}
+	f.posts = &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			for _, post := range []divulge.Post{f.draft, f.published} {
+				if post.ID == id {
+					return post, nil
+				}
+			}
+
+			return divulge.Post{}, divulge.NotFoundError("post not found", nil)
+		},
+	}
+	f.az = authz.New(f.accounts, f.posts, &mock.RevisionService{})
+	return f
+}
+
+// as returns a context for a request made by the member with the given role.
+func (f *fixture) as(role string) context.Context {
+	return divulge.WithUser(context.TODO(), f.members[role])
+}
+
+func Test_PostService_Edit(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.PostService{}
+	posts := authz.NewPostService(next, f.az)
+
+	cases := []struct {
+		role    string
+		post    divulge.Post
+		allowed bool
+	}{
+		{divulge.RoleAuthor, f.draft, true},
+		{divulge.RoleAuthor, f.published, false},
+		{divulge.RoleEditor, f.published, true},
+		{divulge.RoleViewer, f.draft, false},
+	}
+
+	for _, c := range cases {
+		// RUN
+		_, err := posts.SavePost(f.as(c.role), c.post)
+
+		// ASSERT
+		if c.allowed && err != nil {
+			t.Fatalf("expected %s to be able to edit, got %s", c.role, err)
+		}
+
+		if !c.allowed && !errors.Is(err, divulge.ErrForbidden) {
+			t.Fatalf("expected %s to be forbidden, got %v", c.role, err)
+		}
+	}
+
+	if next.SavePostCount != 2 {
+		t.Fatalf("expected only allowed saves to go through, got %d", next.SavePostCount)
+	}
+}
+
+func Test_PostService_Create(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	var saved []divulge.Post
+	next := &mock.PostService{
+		SavePostFn: func(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
+			saved = append(saved, post)
+			return uuid.New(), nil
+		},
+	}
+	posts := authz.NewPostService(next, f.az)
+	editorID := f.members[divulge.RoleEditor].ID
+
+	// RUN
+	_, ownErr := posts.SavePost(f.as(divulge.RoleAuthor), divulge.Post{AccountID: f.account.ID})
+	_, onBehalfErr := posts.SavePost(f.as(divulge.RoleAuthor), divulge.Post{AccountID: f.account.ID, AuthorID: editorID})
+	_, viewerErr := posts.SavePost(f.as(divulge.RoleViewer), divulge.Post{AccountID: f.account.ID})
+	_, outsiderErr := posts.SavePost(divulge.WithUser(context.TODO(), divulge.User{ID: uuid.New()}), divulge.Post{AccountID: f.account.ID})
+	_, anonymousErr := posts.SavePost(context.TODO(), divulge.Post{AccountID: f.account.ID})
+
+	// ASSERT
+	if ownErr != nil {
+		t.Fatalf("unexpected error: %s", ownErr)
+	}
+
+	if len(saved) != 1 || saved[0].AuthorID != f.members[divulge.RoleAuthor].ID {
+		t.Fatalf("expected the caller to be the author, got %+v", saved)
+	}
+
+	for _, err := range []error{onBehalfErr, viewerErr, outsiderErr} {
+		if !errors.Is(err, divulge.ErrForbidden) {
+			t.Fatalf("expected forbidden, got %v", err)
+		}
+	}
+
+	if !errors.Is(anonymousErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", anonymousErr)
+	}
+}
+
+func Test_PostService_Publish(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.PostService{}
+	posts := authz.NewPostService(next, f.az)
+
+	// RUN
+	authorErr := posts.PublishPost(f.as(divulge.RoleAuthor), f.draft.ID)
+	editorErr := posts.PublishPost(f.as(divulge.RoleEditor), f.draft.ID)
+	redactErr := posts.RedactPost(f.as(divulge.RoleEditor), f.published.ID)
+	purgeErr := posts.PurgePost(f.as(divulge.RoleEditor), f.draft.ID)
+	adminPurgeErr := posts.PurgePost(f.as(divulge.RoleAdmin), f.draft.ID)
+
+	// ASSERT
+	if !errors.Is(authorErr, divulge.ErrForbidden) || !errors.Is(purgeErr, divulge.ErrForbidden) {
+		t.Fatalf("unexpected errors: %v, %v", authorErr, purgeErr)
+	}
+
+	if editorErr != nil || redactErr != nil || adminPurgeErr != nil {
+		t.Fatalf("unexpected errors: %v, %v, %v", editorErr, redactErr, adminPurgeErr)
+	}
+
+	if next.PublishPostCount != 1 || next.RedactPostCount != 1 || next.PurgePostCount != 1 {
+		t.Fatal("expected only allowed calls to go through")
+	}
+}
+
+func Test_PostService_Read(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.PostService{}
+	posts := authz.NewPostService(next, f.az)
+
+	// RUN
+	_, viewerErr := posts.FetchPost(f.as(divulge.RoleViewer), f.published.ID)
+	_, outsiderErr := posts.ListPostsByAccount(divulge.WithUser(context.TODO(), divulge.User{ID: uuid.New()}), f.account.ID)
+	_, missingErr := posts.FetchPost(divulge.WithUser(context.TODO(), divulge.User{ID: uuid.New()}), uuid.New())
+	_, existingErr := posts.FetchPost(divulge.WithUser(context.TODO(), divulge.User{ID: uuid.New()}), f.published.ID)
+
+	// ASSERT
+	if viewerErr != nil {
+		t.Fatalf("unexpected error: %s", viewerErr)
+	}
+
+	if !errors.Is(outsiderErr, divulge.ErrForbidden) {
+		t.Fatalf("expected forbidden, got %v", outsiderErr)
+	}
+
+	missing, _ := divulge.ErrorMessage(missingErr)
+	existing, _ := divulge.ErrorMessage(existingErr)
+	if !errors.Is(missingErr, divulge.ErrForbidden) || !errors.Is(existingErr, divulge.ErrForbidden) || missing != existing {
+		t.Fatalf("expected outsiders not to tell missing posts apart, got %v, %v", missingErr, existingErr)
+	}
+}
+
+func Test_PostService_Anonymous(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.PostService{}
+	posts := authz.NewPostService(next, f.az)
+
+	// RUN
+	_, existingErr := posts.FetchPost(context.TODO(), f.published.ID)
+	_, missingErr := posts.FetchPost(context.TODO(), uuid.New())
+
+	// ASSERT
+	if !errors.Is(existingErr, divulge.ErrUnauthorized) || existingErr.Error() != missingErr.Error() {
+		t.Fatalf("expected the same error for existing and missing posts, got %v, %v", existingErr, missingErr)
+	}
+
+	if f.posts.FetchPostCount != 0 || next.FetchPostCount != 0 {
+		t.Fatal("expected anonymous callers not to look up posts")
+	}
+}
+
+func Test_PostService_Scopes(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	next := &mock.PostService{}
+	posts := authz.NewPostService(next, f.az)
+	ctx := divulge.WithScopes(f.as(divulge.RoleOwner), []string{divulge.ScopePostsWrite})
+
+	// RUN
+	_, saveErr := posts.SavePost(ctx, f.draft)
+	publishErr := posts.PublishPost(ctx, f.draft.ID)
+	_, readErr := posts.FetchPost(ctx, f.draft.ID)
+
+	// ASSERT
+	if saveErr != nil {
+		t.Fatalf("unexpected error: %s", saveErr)
+	}
+
+	if !errors.Is(publishErr, divulge.ErrForbidden) || !errors.Is(readErr, divulge.ErrForbidden) {
+		t.Fatalf("expected scopes to limit the owner, got %v, %v", publishErr, readErr)
+	}
+
+	if next.PublishPostCount != 0 || next.FetchPostCount != 0 {
+		t.Fatal("expected out of scope calls not to go through")
+	}
+}
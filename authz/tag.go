@@ -0,0 +1,60 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// A TagService implements the divulge.TagService interface by authorizing calls before passing
+// them off to another TagService. Any member can browse tags, but only editors can reorganize
+// them since that touches posts they may not have written.
+type TagService struct {
+	next divulge.TagService
+	az   Authorizer
+}
+
+// NewTagService returns a new TagService.
+func NewTagService(next divulge.TagService, az Authorizer) TagService {
+	return TagService{
+		next: next,
+		az:   az,
+	}
+}
+
+// ListTags requires a member of the account.
+func (s TagService) ListTags(ctx context.Context, accountID uuid.UUID) ([]divulge.Tag, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsRead, accountID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListTags(ctx, accountID)
+}
+
+// ListPostsByTag requires a member of the account.
+func (s TagService) ListPostsByTag(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error) {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsRead, accountID, divulge.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListPostsByTag(ctx, accountID, tag)
+}
+
+// RenameTag requires an editor.
+func (s TagService) RenameTag(ctx context.Context, accountID uuid.UUID, tag, name string) error {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsWrite, accountID, divulge.RoleEditor); err != nil {
+		return err
+	}
+
+	return s.next.RenameTag(ctx, accountID, tag, name)
+}
+
+// MergeTags requires an editor.
+func (s TagService) MergeTags(ctx context.Context, accountID uuid.UUID, from, into string) error {
+	if _, _, err := s.az.require(ctx, divulge.ScopePostsWrite, accountID, divulge.RoleEditor); err != nil {
+		return err
+	}
+
+	return s.next.MergeTags(ctx, accountID, from, into)
+}
@@ -0,0 +1,92 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// A UserService implements the divulge.UserService interface by authorizing calls before passing
+// them off to another UserService. Anyone can sign up, but only users themselves can change or
+// remove their profile. Memberships are managed through accounts, never through users.
+type UserService struct {
+	next divulge.UserService
+	az   Authorizer
+}
+
+// NewUserService returns a new UserService.
+func NewUserService(next divulge.UserService, az Authorizer) UserService {
+	return UserService{
+		next: next,
+		az:   az,
+	}
+}
+
+// SaveUser lets anyone create a user without any memberships. Only users themselves can update
+// their profile, which leaves their memberships untouched.
+func (s UserService) SaveUser(ctx context.Context, user divulge.User) (uuid.UUID, error) {
+	if divulge.IsEmpty(user.ID) {
+		if len(user.Accounts) > 0 {
+			return user.ID, divulge.ForbiddenError("new users can't join accounts on their own", nil)
+		}
+
+		return s.next.SaveUser(ctx, user)
+	}
+
+	if err := s.requireSelf(ctx, user.ID); err != nil {
+		return user.ID, err
+	}
+
+	user.Accounts = nil
+	return s.next.SaveUser(ctx, user)
+}
+
+// FetchUser requires an authenticated caller.
+func (s UserService) FetchUser(ctx context.Context, id uuid.UUID) (divulge.User, error) {
+	if _, err := s.az.caller(ctx, divulge.ScopeAccountsRead); err != nil {
+		return divulge.User{}, err
+	}
+
+	return s.next.FetchUser(ctx, id)
+}
+
+// FetchUserByEmail requires an authenticated caller.
+func (s UserService) FetchUserByEmail(ctx context.Context, email string) (divulge.User, error) {
+	if _, err := s.az.caller(ctx, divulge.ScopeAccountsRead); err != nil {
+		return divulge.User{}, err
+	}
+
+	return s.next.FetchUserByEmail(ctx, email)
+}
+
+// ListUsers requires an authenticated caller.
+func (s UserService) ListUsers(ctx context.Context) ([]divulge.User, error) {
+	if _, err := s.az.caller(ctx, divulge.ScopeAccountsRead); err != nil {
+		return nil, err
+	}
+
+	return s.next.ListUsers(ctx)
+}
+
+// RemoveUser only lets users remove themselves.
+func (s UserService) RemoveUser(ctx context.Context, id uuid.UUID) error {
+	if err := s.requireSelf(ctx, id); err != nil {
+		return err
+	}
+
+	return s.next.RemoveUser(ctx, id)
+}
+
+func (s UserService) requireSelf(ctx context.Context, id uuid.UUID) error {
+	user, err := s.az.caller(ctx, divulge.ScopeAccountsWrite)
+	if err != nil {
+		return err
+	}
+
+	if user.ID != id {
+		return divulge.ForbiddenError("users can only change themselves", nil)
+	}
+
+	return nil
+}
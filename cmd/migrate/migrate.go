@@ -1,83 +1,158 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"strconv"
 
+	"github.com/eriktate/divulge/config"
+	"github.com/eriktate/divulge/migrations"
+	"github.com/eriktate/divulge/pg"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
-const createDatabaseQuery = "CREATE DATABASE divulge;"
+const usage = `usage: migrate [flags] <command> [args]
 
-func main() {
-	// enable migrating down
-	var down bool
-	flag.BoolVar(&down, "down", false, "migrate down")
-	flag.Parse()
+commands:
+	up         apply all pending migrations
+	down [N]   roll back the N most recent migrations (default 1)
+	status     list migrations and whether they've been applied
+	goto V     migrate up or down to version V (0 rolls back everything)
+
+run with -h to list flags
+`
 
+func main() {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{})
 
-	logger.WithField("down", down).Info("migrate option")
-	if down {
-		migrateDown(logger)
-		return
+	cfg, args, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			fmt.Fprint(os.Stderr, usage)
+			return
+		}
+
+		logger.WithError(err).Fatal("failed to load config")
 	}
 
-	migrateUp(logger)
-}
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
 
-func migrateUp(logger *logrus.Logger) {
-	logger.Info("starting migration...")
-	up, err := ioutil.ReadFile("./migrations/up.sql")
+	known, err := migrations.Load()
 	if err != nil {
-		logger.WithError(err).Fatal("failed to read migration file")
+		logger.WithError(err).Fatal("failed to load migrations")
 	}
 
-	// attempt to connect to database
-	db, err := connect()
-	if err != nil {
-		logger.WithError(err).Error("failed to connect to database, attempting to create")
-		if err := createDatabase(); err != nil {
-			logger.WithError(err).Fatal("failed to create database")
+	ctx := context.Background()
+	command := args[0]
+	logger.WithField("command", command).Info("migrate option")
+
+	switch command {
+	case "up":
+		migrator := migrations.NewMigrator(connectOrCreate(logger, cfg.DB), known)
+		applied, err := migrator.Up(ctx)
+		logChanges(logger, "applied", applied)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to migrate up")
+		}
+
+		logger.Info("successfully applied database schema")
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			n = intArg(logger, args[1])
 		}
 
-		db, err = connect()
+		migrator := migrations.NewMigrator(mustConnect(logger, cfg.DB), known)
+		rolledBack, err := migrator.Down(ctx, n)
+		logChanges(logger, "rolled back", rolledBack)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to connect to database")
+			logger.WithError(err).Fatal("failed to migrate down")
+		}
+	case "goto":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
 		}
-	}
 
-	// create schema
-	if _, err := db.Exec(string(up)); err != nil {
-		logger.WithError(err).Fatal("failed to create schema")
+		migrator := migrations.NewMigrator(connectOrCreate(logger, cfg.DB), known)
+		changed, err := migrator.Goto(ctx, intArg(logger, args[1]))
+		logChanges(logger, "migrated", changed)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to migrate")
+		}
+	case "status":
+		migrator := migrations.NewMigrator(mustConnect(logger, cfg.DB), known)
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to get migration status")
+		}
+
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
+}
 
-	logger.Info("successfully applied database schema")
+func logChanges(logger *logrus.Logger, action string, changed []migrations.Migration) {
+	for _, migration := range changed {
+		logger.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info(action)
+	}
 }
 
-func migrateDown(logger *logrus.Logger) {
-	logger.Info("beginning teardown")
-	down, err := ioutil.ReadFile("./migrations/down.sql")
-	if err != nil {
-		logger.WithError(err).Fatal("failed to read migration file")
+func intArg(logger *logrus.Logger, arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		logger.WithField("arg", arg).Fatal("expected a non-negative number")
 	}
 
-	db, err := connect()
+	return n
+}
+
+func mustConnect(logger *logrus.Logger, cfg pg.Config) *sqlx.DB {
+	db, err := connect(cfg)
 	if err != nil {
 		logger.WithError(err).Fatal("failed to connect to database")
 	}
 
-	if _, err := db.Exec(string(down)); err != nil {
-		logger.WithError(err).Fatal("failed to execute migration")
+	return db
+}
+
+// connectOrCreate connects to the database, creating it first if it doesn't exist yet.
+func connectOrCreate(logger *logrus.Logger, cfg pg.Config) *sqlx.DB {
+	db, err := connect(cfg)
+	if err != nil {
+		logger.WithError(err).Error("failed to connect to database, attempting to create")
+		if err := createDatabase(cfg); err != nil {
+			logger.WithError(err).Fatal("failed to create database")
+		}
+
+		return mustConnect(logger, cfg)
 	}
+
+	return db
 }
 
-func connect() (*sqlx.DB, error) {
-	db, err := sqlx.Connect("postgres", "user=postgres password=password dbname=divulge sslmode=disable")
+func connect(cfg pg.Config) (*sqlx.DB, error) {
+	db, err := sqlx.Connect("postgres", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
@@ -85,15 +160,19 @@ func connect() (*sqlx.DB, error) {
 	return db, nil
 }
 
-func createDatabase() error {
-	db, err := sqlx.Connect("postgres", "user=postgres password=password sslmode=disable")
+// createDatabase connects to the default postgres database in order to create the configured one.
+func createDatabase(cfg pg.Config) error {
+	name := cfg.Name
+	cfg.Name = "postgres"
+	db, err := sqlx.Connect("postgres", cfg.DSN())
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
+	defer db.Close()
 
-	if _, err := db.Exec(createDatabaseQuery); err != nil {
+	if _, err := db.Exec("CREATE DATABASE " + pq.QuoteIdentifier(name) + ";"); err != nil {
 		return err
 	}
 
-	return err
+	return nil
 }
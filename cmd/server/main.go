@@ -1,7 +1,142 @@
 package main
 
-import "log"
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/authz"
+	"github.com/eriktate/divulge/config"
+	"github.com/eriktate/divulge/disk"
+	divulgehttp "github.com/eriktate/divulge/http"
+	"github.com/eriktate/divulge/mail"
+	"github.com/eriktate/divulge/pg"
+	"github.com/eriktate/divulge/s3"
+	"github.com/eriktate/divulge/scheduler"
+	"github.com/eriktate/divulge/service"
+	"github.com/eriktate/divulge/site"
+	"github.com/sirupsen/logrus"
+)
 
 func main() {
-	log.Println("hello, world!")
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{})
+
+	cfg, _, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+
+		logger.WithError(err).Fatal("failed to load config")
+	}
+
+	db, err := pg.Connect(cfg.DB)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to connect to database")
+	}
+
+	fs, err := newFileStore(cfg.Store)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to create file store")
+	}
+
+	mailer, err := newMailer(cfg.Mail, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to create mailer")
+	}
+
+	posts := service.NewPostService(db, db, fs)
+	tags := service.NewTagService(db)
+	auth := service.NewAuthenticator(db, db, db, db, db, db, mailer, service.AuthConfig{
+		SessionTTL:   time.Duration(cfg.Auth.SessionTTL),
+		LoginLinkURL: cfg.Auth.LoginLinkURL,
+		LoginLinkTTL: time.Duration(cfg.Auth.LoginLinkTTL),
+		TOTPIssuer:   cfg.Auth.TOTPIssuer,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(site.Prefix+"/", site.New(posts, tags, db, fs, logger))
+	// the API acts on behalf of whoever's logged in, while the sites are public
+	az := authz.New(db, db, db)
+	mux.Handle("/", divulgehttp.NewServer(
+		authz.NewPostService(posts, az),
+		authz.NewRevisionHistory(posts, az),
+		authz.NewTagService(tags, az),
+		authz.NewAccountService(db, az),
+		authz.NewUserService(db, az),
+		auth,
+		logger,
+	))
+
+	srv := &http.Server{
+		Addr:    cfg.HTTP.Addr,
+		Handler: mux,
+	}
+
+	ctx, stopJobs := context.WithCancel(context.Background())
+	jobs := scheduler.New(logger)
+	jobs.Every("publish scheduled posts", time.Duration(cfg.Scheduler.PublishInterval), scheduler.PublishDue(db, cfg.Scheduler.BatchSize, logger))
+
+	reconciler := service.NewContentReconciler(db, fs, time.Duration(cfg.Scheduler.ReconcileGrace))
+	jobs.Every("reconcile content", time.Duration(cfg.Scheduler.ReconcileInterval), scheduler.ReconcileContent(reconciler, logger))
+	jobs.Every("purge trash", time.Duration(cfg.Scheduler.PurgeInterval), scheduler.PurgeTrash(db, posts, time.Duration(cfg.Scheduler.TrashRetention), cfg.Scheduler.BatchSize, logger))
+
+	jobsDone := make(chan struct{})
+	go func() {
+		jobs.Run(ctx)
+		close(jobsDone)
+	}()
+
+	go func() {
+		logger.WithField("addr", cfg.HTTP.Addr).Info("starting server")
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.WithError(err).Fatal("server failed")
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+
+	logger.Info("shutting down")
+	stopJobs()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.WithError(err).Error("failed to shut down cleanly")
+	}
+
+	<-jobsDone
+}
+
+func newFileStore(cfg config.Store) (divulge.FileStore, error) {
+	switch cfg.Backend {
+	case config.BackendDisk:
+		return disk.New(cfg.BasePath), nil
+	case config.BackendS3:
+		s3Config := cfg.S3.Config
+		s3Config.Timeout = time.Duration(cfg.S3.Timeout)
+		return s3.New(s3Config)
+	}
+
+	return nil, fmt.Errorf("unknown store backend: %q", cfg.Backend)
+}
+
+func newMailer(cfg config.Mail, logger *logrus.Logger) (divulge.Mailer, error) {
+	switch cfg.Backend {
+	case config.MailerLog:
+		return mail.NewLog(logger), nil
+	case config.MailerSMTP:
+		return mail.NewSMTP(cfg.SMTP)
+	}
+
+	return nil, fmt.Errorf("unknown mail backend: %q", cfg.Backend)
 }
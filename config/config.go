@@ -0,0 +1,427 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/eriktate/divulge/mail"
+	"github.com/eriktate/divulge/pg"
+	"github.com/eriktate/divulge/s3"
+)
+
+// envPrefix is prepended to every environment variable the config reads.
+const envPrefix = "DIVULGE_"
+
+// Supported FileStore backends.
+const (
+	BackendDisk = "disk"
+	BackendS3   = "s3"
+)
+
+// Supported Mailer backends.
+const (
+	MailerLog  = "log"
+	MailerSMTP = "smtp"
+)
+
+// A Config holds everything needed to run divulge.
+type Config struct {
+	DB        pg.Config `json:"db"`
+	Store     Store     `json:"store"`
+	HTTP      HTTP      `json:"http"`
+	Scheduler Scheduler `json:"scheduler"`
+	Auth      Auth      `json:"auth"`
+	Mail      Mail      `json:"mail"`
+}
+
+// Store configures where post content lives.
+type Store struct {
+	Backend  string `json:"backend"`
+	BasePath string `json:"basePath"`
+	S3       S3     `json:"s3"`
+}
+
+// S3 configures the s3 backend. It's an s3.Config with a Timeout that reads like every other
+// Duration.
+type S3 struct {
+	s3.Config
+	Timeout Duration `json:"timeout"`
+}
+
+// HTTP configures the API server.
+type HTTP struct {
+	Addr string `json:"addr"`
+}
+
+// Scheduler configures background jobs.
+type Scheduler struct {
+	PublishInterval Duration `json:"publishInterval"`
+	BatchSize       int      `json:"batchSize"`
+	// ReconcileInterval is how often content is reconciled. Orphaned content is only deleted
+	// once it's older than ReconcileGrace.
+	ReconcileInterval Duration `json:"reconcileInterval"`
+	ReconcileGrace    Duration `json:"reconcileGrace"`
+	// Trashed posts are purged every PurgeInterval once they've been in the trash for longer
+	// than TrashRetention.
+	PurgeInterval  Duration `json:"purgeInterval"`
+	TrashRetention Duration `json:"trashRetention"`
+}
+
+// Auth configures how users log in.
+type Auth struct {
+	SessionTTL Duration `json:"sessionTTL"`
+	// LoginLinkURL is the page emailed login links point to. It gets the link's token as the
+	// token query parameter. The default is the API server's own login page, which only works
+	// when it's served from the same address.
+	LoginLinkURL string   `json:"loginLinkURL"`
+	LoginLinkTTL Duration `json:"loginLinkTTL"`
+	// TOTPIssuer is the name authenticator apps show next to their codes.
+	TOTPIssuer string `json:"totpIssuer"`
+}
+
+// Mail configures how emails are sent. The log backend only logs them, which is handy in
+// development.
+type Mail struct {
+	Backend string      `json:"backend"`
+	SMTP    mail.Config `json:"smtp"`
+}
+
+// A Duration is a time.Duration that reads from JSON strings like "30s".
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("expected a duration string: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Default returns the Config used when nothing else is specified.
+func Default() Config {
+	return Config{
+		DB: pg.Config{
+			Host:    "localhost",
+			Port:    5432,
+			Name:    "divulge",
+			User:    "postgres",
+			SSLMode: "disable",
+		},
+		Store: Store{
+			Backend:  BackendDisk,
+			BasePath: "./data",
+			S3: S3{
+				Timeout: Duration(s3.DefaultTimeout),
+			},
+		},
+		HTTP: HTTP{
+			Addr: ":8080",
+		},
+		Scheduler: Scheduler{
+			PublishInterval:   Duration(30 * time.Second),
+			BatchSize:         100,
+			ReconcileInterval: Duration(time.Hour),
+			ReconcileGrace:    Duration(time.Hour),
+			PurgeInterval:     Duration(time.Hour),
+			TrashRetention:    Duration(30 * 24 * time.Hour),
+		},
+		Auth: Auth{
+			SessionTTL:   Duration(14 * 24 * time.Hour),
+			LoginLinkURL: "http://localhost:8080/login",
+			LoginLinkTTL: Duration(15 * time.Minute),
+			TOTPIssuer:   "Divulge",
+		},
+		Mail: Mail{
+			Backend: MailerLog,
+			SMTP: mail.Config{
+				Port: mail.DefaultSMTPPort,
+			},
+		},
+	}
+}
+
+// A setting is a single configurable value, settable by flag or environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(value string) error
+}
+
+func (cfg *Config) settings() []setting {
+	return []setting{
+		stringSetting("db-host", "DB_HOST", "database host", &cfg.DB.Host),
+		intSetting("db-port", "DB_PORT", "database port", &cfg.DB.Port),
+		stringSetting("db-name", "DB_NAME", "database name", &cfg.DB.Name),
+		stringSetting("db-user", "DB_USER", "database user", &cfg.DB.User),
+		stringSetting("db-password", "DB_PASSWORD", "database password", &cfg.DB.Password),
+		stringSetting("db-sslmode", "DB_SSLMODE", "database sslmode", &cfg.DB.SSLMode),
+		intSetting("db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections (0 is unlimited)", &cfg.DB.MaxOpenConns),
+		intSetting("db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections", &cfg.DB.MaxIdleConns),
+		stringSetting("store-backend", "STORE_BACKEND", "file store backend (disk or s3)", &cfg.Store.Backend),
+		stringSetting("store-base-path", "STORE_BASE_PATH", "base path for post content", &cfg.Store.BasePath),
+		stringSetting("store-s3-endpoint", "STORE_S3_ENDPOINT", "S3 API endpoint URL", &cfg.Store.S3.Endpoint),
+		stringSetting("store-s3-region", "STORE_S3_REGION", "S3 region", &cfg.Store.S3.Region),
+		stringSetting("store-s3-bucket", "STORE_S3_BUCKET", "S3 bucket for post content", &cfg.Store.S3.Bucket),
+		stringSetting("store-s3-prefix", "STORE_S3_PREFIX", "prefix for every S3 key", &cfg.Store.S3.Prefix),
+		stringSetting("store-s3-access-key-id", "STORE_S3_ACCESS_KEY_ID", "S3 access key ID", &cfg.Store.S3.AccessKeyID),
+		stringSetting("store-s3-secret-access-key", "STORE_S3_SECRET_ACCESS_KEY", "S3 secret access key", &cfg.Store.S3.SecretAccessKey),
+		boolSetting("store-s3-path-style", "STORE_S3_PATH_STYLE", "address the S3 bucket in the path instead of the host", &cfg.Store.S3.PathStyle),
+		stringSetting("store-s3-sse", "STORE_S3_SSE", "S3 server-side encryption (AES256 or aws:kms)", &cfg.Store.S3.SSE),
+		stringSetting("store-s3-kms-key-id", "STORE_S3_KMS_KEY_ID", "KMS key ID for aws:kms server-side encryption", &cfg.Store.S3.KMSKeyID),
+		int64Setting("store-s3-part-size", "STORE_S3_PART_SIZE", "size in bytes above which content is uploaded in parts", &cfg.Store.S3.PartSize),
+		durationSetting("store-s3-timeout", "STORE_S3_TIMEOUT", "how long a single S3 request can take", &cfg.Store.S3.Timeout),
+		stringSetting("http-addr", "HTTP_ADDR", "address for the HTTP server to listen on", &cfg.HTTP.Addr),
+		durationSetting("scheduler-publish-interval", "SCHEDULER_PUBLISH_INTERVAL", "how often to publish scheduled posts", &cfg.Scheduler.PublishInterval),
+		intSetting("scheduler-batch-size", "SCHEDULER_BATCH_SIZE", "how many scheduled posts to publish per query", &cfg.Scheduler.BatchSize),
+		durationSetting("scheduler-reconcile-interval", "SCHEDULER_RECONCILE_INTERVAL", "how often to clean up orphaned content", &cfg.Scheduler.ReconcileInterval),
+		durationSetting("scheduler-reconcile-grace", "SCHEDULER_RECONCILE_GRACE", "how old orphaned content must be before it's deleted", &cfg.Scheduler.ReconcileGrace),
+		durationSetting("scheduler-purge-interval", "SCHEDULER_PURGE_INTERVAL", "how often to purge expired posts from the trash", &cfg.Scheduler.PurgeInterval),
+		durationSetting("scheduler-trash-retention", "SCHEDULER_TRASH_RETENTION", "how long trashed posts are kept before they're purged", &cfg.Scheduler.TrashRetention),
+		durationSetting("auth-session-ttl", "AUTH_SESSION_TTL", "how long a login lasts", &cfg.Auth.SessionTTL),
+		stringSetting("auth-login-link-url", "AUTH_LOGIN_LINK_URL", "page emailed login links point to", &cfg.Auth.LoginLinkURL),
+		durationSetting("auth-login-link-ttl", "AUTH_LOGIN_LINK_TTL", "how long an emailed login link works", &cfg.Auth.LoginLinkTTL),
+		stringSetting("auth-totp-issuer", "AUTH_TOTP_ISSUER", "name authenticator apps show for two-factor codes", &cfg.Auth.TOTPIssuer),
+		stringSetting("mail-backend", "MAIL_BACKEND", "how to send email (log or smtp)", &cfg.Mail.Backend),
+		stringSetting("mail-smtp-host", "MAIL_SMTP_HOST", "SMTP server host", &cfg.Mail.SMTP.Host),
+		intSetting("mail-smtp-port", "MAIL_SMTP_PORT", "SMTP server port", &cfg.Mail.SMTP.Port),
+		stringSetting("mail-smtp-username", "MAIL_SMTP_USERNAME", "SMTP username", &cfg.Mail.SMTP.Username),
+		stringSetting("mail-smtp-password", "MAIL_SMTP_PASSWORD", "SMTP password", &cfg.Mail.SMTP.Password),
+		stringSetting("mail-smtp-from", "MAIL_SMTP_FROM", "address emails are sent from", &cfg.Mail.SMTP.From),
+	}
+}
+
+func stringSetting(flag, env, usage string, dest *string) setting {
+	return setting{flag, env, usage, func(value string) error {
+		*dest = value
+		return nil
+	}}
+}
+
+func intSetting(flag, env, usage string, dest *int) setting {
+	return setting{flag, env, usage, func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a number: %w", err)
+		}
+
+		*dest = n
+		return nil
+	}}
+}
+
+func int64Setting(flag, env, usage string, dest *int64) setting {
+	return setting{flag, env, usage, func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number: %w", err)
+		}
+
+		*dest = n
+		return nil
+	}}
+}
+
+func boolSetting(flag, env, usage string, dest *bool) setting {
+	return setting{flag, env, usage, func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false: %w", err)
+		}
+
+		*dest = b
+		return nil
+	}}
+}
+
+func durationSetting(flag, env, usage string, dest *Duration) setting {
+	return setting{flag, env, usage, func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration: %w", err)
+		}
+
+		*dest = Duration(d)
+		return nil
+	}}
+}
+
+// recorder is a flag.Value that holds on to whatever it's set to so flags can be applied last.
+type recorder string
+
+func (r *recorder) String() string {
+	return string(*r)
+}
+
+func (r *recorder) Set(value string) error {
+	*r = recorder(value)
+	return nil
+}
+
+// Load builds a Config for the named command from os.Args-style args and the environment. It
+// returns any positional args left over after flags.
+func Load(name string, args []string) (Config, []string, error) {
+	return LoadEnv(name, args, os.LookupEnv)
+}
+
+// LoadEnv is like Load but reads environment variables through lookup. Values are layered with
+// defaults first, then the config file, then environment variables and finally flags.
+func LoadEnv(name string, args []string, lookup func(string) (string, bool)) (Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath, _ := lookup(envPrefix + "CONFIG")
+	fs.StringVar(&configPath, "config", configPath, "path to a JSON config file (env: "+envPrefix+"CONFIG)")
+
+	recorded := make([]recorder, len(settings))
+	byFlag := make(map[string]int, len(settings))
+	for i, s := range settings {
+		byFlag[s.flag] = i
+		fs.Var(&recorded[i], s.flag, fmt.Sprintf("%s (env: %s%s)", s.usage, envPrefix, s.env))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	if configPath != "" {
+		if err := loadFile(configPath, &cfg); err != nil {
+			return cfg, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := lookup(envPrefix + s.env)
+		if !ok {
+			continue
+		}
+
+		if err := s.set(value); err != nil {
+			return cfg, nil, fmt.Errorf("invalid %s%s: %w", envPrefix, s.env, err)
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		i, ok := byFlag[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+
+		if err := settings[i].set(string(recorded[i])); err != nil {
+			flagErr = fmt.Errorf("invalid -%s: %w", f.Name, err)
+		}
+	})
+
+	if flagErr != nil {
+		return cfg, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks that the Config is usable.
+func (cfg Config) Validate() error {
+	switch cfg.Store.Backend {
+	case BackendDisk:
+		if cfg.Store.BasePath == "" {
+			return errors.New("store base path is required for the disk backend")
+		}
+	case BackendS3:
+		if cfg.Store.S3.Endpoint == "" || cfg.Store.S3.Bucket == "" {
+			return errors.New("store endpoint and bucket are required for the s3 backend")
+		}
+
+		if cfg.Store.S3.Timeout <= 0 {
+			return errors.New("store s3 timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown store backend: %q", cfg.Store.Backend)
+	}
+
+	if cfg.DB.Port < 0 || cfg.DB.Port > 65535 {
+		return fmt.Errorf("invalid database port: %d", cfg.DB.Port)
+	}
+
+	if cfg.DB.MaxOpenConns < 0 || cfg.DB.MaxIdleConns < 0 {
+		return errors.New("database pool sizes can't be negative")
+	}
+
+	if cfg.HTTP.Addr == "" {
+		return errors.New("http address is required")
+	}
+
+	if cfg.Scheduler.PublishInterval <= 0 || cfg.Scheduler.BatchSize <= 0 {
+		return errors.New("scheduler interval and batch size must be positive")
+	}
+
+	if cfg.Scheduler.ReconcileInterval <= 0 || cfg.Scheduler.ReconcileGrace < 0 {
+		return errors.New("reconcile interval must be positive and grace can't be negative")
+	}
+
+	if cfg.Scheduler.PurgeInterval <= 0 || cfg.Scheduler.TrashRetention < 0 {
+		return errors.New("purge interval must be positive and trash retention can't be negative")
+	}
+
+	if cfg.Auth.SessionTTL <= 0 || cfg.Auth.LoginLinkTTL <= 0 {
+		return errors.New("session and login link ttls must be positive")
+	}
+
+	if linkURL, err := url.Parse(cfg.Auth.LoginLinkURL); err != nil || linkURL.Scheme == "" || linkURL.Host == "" {
+		return fmt.Errorf("invalid login link url: %q", cfg.Auth.LoginLinkURL)
+	}
+
+	if cfg.Auth.TOTPIssuer == "" {
+		return errors.New("totp issuer is required")
+	}
+
+	switch cfg.Mail.Backend {
+	case MailerLog:
+	case MailerSMTP:
+		if cfg.Mail.SMTP.Host == "" || cfg.Mail.SMTP.From == "" {
+			return errors.New("mail host and from address are required for the smtp backend")
+		}
+	default:
+		return fmt.Errorf("unknown mail backend: %q", cfg.Mail.Backend)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge/config"
+)
+
+func lookupMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func Test_LoadEnv_Defaults(t *testing.T) {
+	// RUN
+	cfg, args, err := config.LoadEnv("test", []string{"up"}, lookupMap(nil))
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if cfg.DB.Name != "divulge" || cfg.Store.Backend != config.BackendDisk || cfg.HTTP.Addr != ":8080" {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+
+	if len(args) != 1 || args[0] != "up" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func Test_LoadEnv_Precedence(t *testing.T) {
+	// SETUP
+	dir, err := ioutil.TempDir("", "divulge-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	file := `{"db": {"host": "file-host", "name": "file-name", "user": "file-user", "maxOpenConns": 5}, "scheduler": {"publishInterval": "1m"}}`
+	if err := ioutil.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"DIVULGE_CONFIG":    path,
+		"DIVULGE_DB_NAME":   "env-name",
+		"DIVULGE_DB_USER":   "env-user",
+		"DIVULGE_HTTP_ADDR": ":9000",
+	}
+
+	// RUN
+	cfg, _, err := config.LoadEnv("test", []string{"-db-user", "flag-user"}, lookupMap(env))
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if cfg.DB.Host != "file-host" {
+		t.Fatalf("expected file to override defaults, got host: %s", cfg.DB.Host)
+	}
+
+	if cfg.DB.MaxOpenConns != 5 {
+		t.Fatalf("expected pool size from file, got: %d", cfg.DB.MaxOpenConns)
+	}
+
+	if cfg.DB.Name != "env-name" || cfg.HTTP.Addr != ":9000" {
+		t.Fatalf("expected env to override file, got: %+v", cfg)
+	}
+
+	if cfg.DB.User != "flag-user" {
+		t.Fatalf("expected flags to override env, got user: %s", cfg.DB.User)
+	}
+
+	if cfg.Scheduler.PublishInterval != config.Duration(time.Minute) {
+		t.Fatalf("expected duration from file, got: %v", cfg.Scheduler.PublishInterval)
+	}
+
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected untouched defaults to remain, got port: %d", cfg.DB.Port)
+	}
+}
+
+func Test_LoadEnv_S3(t *testing.T) {
+	// SETUP
+	env := map[string]string{
+		"DIVULGE_STORE_BACKEND":       "s3",
+		"DIVULGE_STORE_S3_ENDPOINT":   "http://localhost:9000",
+		"DIVULGE_STORE_S3_BUCKET":     "content",
+		"DIVULGE_STORE_S3_PATH_STYLE": "true",
+		"DIVULGE_STORE_S3_PART_SIZE":  "10485760",
+		"DIVULGE_STORE_S3_TIMEOUT":    "30s",
+	}
+
+	// RUN
+	cfg, _, err := config.LoadEnv("test", []string{"-store-s3-sse", "AES256"}, lookupMap(env))
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s3 := cfg.Store.S3
+	if s3.Endpoint != "http://localhost:9000" || s3.Bucket != "content" || !s3.PathStyle {
+		t.Fatalf("unexpected s3 config: %+v", s3)
+	}
+
+	if s3.PartSize != 10<<20 || s3.SSE != "AES256" || s3.Timeout != config.Duration(30*time.Second) {
+		t.Fatalf("unexpected s3 upload settings: %+v", s3)
+	}
+}
+
+func Test_LoadEnv_Invalid(t *testing.T) {
+	// SETUP
+	cases := []struct {
+		args []string
+		env  map[string]string
+	}{
+		{args: []string{"-db-port", "nope"}},
+		{env: map[string]string{"DIVULGE_DB_MAX_IDLE_CONNS": "many"}},
+		{env: map[string]string{"DIVULGE_STORE_BACKEND": "tape"}},
+		{env: map[string]string{"DIVULGE_STORE_BACKEND": "s3"}},
+		{env: map[string]string{"DIVULGE_STORE_S3_PATH_STYLE": "sometimes"}},
+		{env: map[string]string{"DIVULGE_SCHEDULER_PUBLISH_INTERVAL": "soon"}},
+		{env: map[string]string{"DIVULGE_SCHEDULER_RECONCILE_INTERVAL": "0s"}},
+		{env: map[string]string{"DIVULGE_AUTH_SESSION_TTL": "0s"}},
+		{env: map[string]string{"DIVULGE_AUTH_LOGIN_LINK_URL": "/login"}},
+		{env: map[string]string{"DIVULGE_MAIL_BACKEND": "pigeon"}},
+		{env: map[string]string{"DIVULGE_MAIL_BACKEND": "smtp"}},
+		{env: map[string]string{"DIVULGE_CONFIG": "/does/not/exist.json"}},
+	}
+
+	for _, c := range cases {
+		// RUN
+		_, _, err := config.LoadEnv("test", c.args, lookupMap(c.env))
+
+		// ASSERT
+		if err == nil {
+			t.Fatalf("expected error for args %v and env %v", c.args, c.env)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package divulge
+
+import "context"
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	scopesKey
+	twoFactorKey
+)
+
+// WithUser returns a copy of ctx carrying the authenticated User.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFromContext returns the authenticated User carried by ctx, if there is one.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey).(User)
+	return user, ok
+}
+
+// WithScopes returns a copy of ctx that only allows the given scopes. Contexts without any
+// scopes, like those of a logged in User, allow everything.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// ScopesFromContext returns the scopes ctx is limited to, if it's limited at all.
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+// HasScope reports whether ctx allows scope.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ScopesFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithTwoFactor returns a copy of ctx whose credentials were verified with two-factor
+// authentication.
+func WithTwoFactor(ctx context.Context) context.Context {
+	return context.WithValue(ctx, twoFactorKey, true)
+}
+
+// HasTwoFactor reports whether the credentials behind ctx were verified with two-factor
+// authentication. Enrolling in it doesn't verify credentials created beforehand.
+func HasTwoFactor(ctx context.Context) bool {
+	verified, _ := ctx.Value(twoFactorKey).(bool)
+	return verified
+}
@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/eriktate/divulge"
+)
+
+// Lines returns the line-by-line changes that turn a into b.
+func Lines(a, b string) []divulge.DiffLine {
+	return compute(split(a), split(b))
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// compute diffs two sets of lines. Common leading and trailing lines are peeled off before
+// running Myers' algorithm on whatever is left in the middle.
+func compute(a, b []string) []divulge.DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]divulge.DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		lines = append(lines, divulge.DiffLine{Op: divulge.DiffEqual, Text: line})
+	}
+
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, divulge.DiffLine{Op: divulge.DiffEqual, Text: line})
+	}
+
+	return lines
+}
+
+// myers finds a shortest edit script between a and b. Each step d records the furthest x
+// reached on every diagonal k so the path can be walked back afterwards.
+func myers(a, b []string) []divulge.DiffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []divulge.DiffLine {
+	var reversed []divulge.DiffLine
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, divulge.DiffLine{Op: divulge.DiffEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, divulge.DiffLine{Op: divulge.DiffInsert, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, divulge.DiffLine{Op: divulge.DiffDelete, Text: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	lines := make([]divulge.DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+
+	return lines
+}
@@ -0,0 +1,115 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/diff"
+)
+
+func eq(text string) divulge.DiffLine  { return divulge.DiffLine{Op: divulge.DiffEqual, Text: text} }
+func ins(text string) divulge.DiffLine { return divulge.DiffLine{Op: divulge.DiffInsert, Text: text} }
+func del(text string) divulge.DiffLine { return divulge.DiffLine{Op: divulge.DiffDelete, Text: text} }
+
+func Test_Lines(t *testing.T) {
+	// SETUP
+	cases := []struct {
+		name     string
+		a        string
+		b        string
+		expected []divulge.DiffLine
+	}{
+		{
+			name:     "identical",
+			a:        "one\ntwo\n",
+			b:        "one\ntwo\n",
+			expected: []divulge.DiffLine{eq("one"), eq("two")},
+		},
+		{
+			name:     "from empty",
+			a:        "",
+			b:        "one\ntwo",
+			expected: []divulge.DiffLine{ins("one"), ins("two")},
+		},
+		{
+			name:     "to empty",
+			a:        "one",
+			b:        "",
+			expected: []divulge.DiffLine{del("one")},
+		},
+		{
+			name:     "changed middle line",
+			a:        "one\ntwo\nthree",
+			b:        "one\n2\nthree",
+			expected: []divulge.DiffLine{eq("one"), del("two"), ins("2"), eq("three")},
+		},
+		{
+			name:     "interleaved",
+			a:        "a\nb\nc\na\nb\nb\na",
+			b:        "c\nb\na\nb\na\nc",
+			expected: nil,
+		},
+	}
+
+	for _, c := range cases {
+		// RUN
+		lines := diff.Lines(c.a, c.b)
+
+		// ASSERT
+		if c.expected != nil && !reflect.DeepEqual(lines, c.expected) {
+			t.Fatalf("%s: unexpected diff: %+v", c.name, lines)
+		}
+
+		// applying the diff to a must always produce b
+		var fromA, toB []string
+		for _, line := range lines {
+			if line.Op != divulge.DiffInsert {
+				fromA = append(fromA, line.Text)
+			}
+
+			if line.Op != divulge.DiffDelete {
+				toB = append(toB, line.Text)
+			}
+		}
+
+		if !reflect.DeepEqual(fromA, split(c.a)) || !reflect.DeepEqual(toB, split(c.b)) {
+			t.Fatalf("%s: diff doesn't reproduce inputs: %+v", c.name, lines)
+		}
+	}
+}
+
+func Test_Lines_Shortest(t *testing.T) {
+	// RUN
+	lines := diff.Lines("a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc")
+
+	// ASSERT
+	changes := 0
+	for _, line := range lines {
+		if line.Op != divulge.DiffEqual {
+			changes++
+		}
+	}
+
+	// the classic example from Myers' paper has an edit distance of 5
+	if changes != 5 {
+		t.Fatalf("expected 5 changes, got %d: %+v", changes, lines)
+	}
+}
+
+func split(s string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			lines = append(lines, s[start:i])
+			start = i + 1
+		}
+	}
+
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+
+	return lines
+}
@@ -1,13 +1,35 @@
 package disk
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+// Permissions for the files and directories a FileStore creates.
+const (
+	FileMode os.FileMode = 0644
+	DirMode  os.FileMode = 0755
 )
 
-// A FileStore is an on-disk implementation of divulge.FileStore.
+// tempPrefix marks files that are still being written. They're never listed, and ones left
+// behind by a crash are deleted by SweepTemp.
+const tempPrefix = ".divulge-tmp-"
+
+// A FileStore is an on-disk implementation of divulge.FileStore. Keys are slash separated paths
+// relative to the base path and can never point outside of it.
 type FileStore struct {
 	basePath string
 }
@@ -17,23 +39,309 @@ func New(basePath string) FileStore {
 	return FileStore{basePath}
 }
 
-// Write a file.
-func (fs FileStore) Write(ctx context.Context, key string, data []byte) error {
-	fullPath := fmt.Sprintf("%s/%s", fs.basePath, key)
-	if err := ioutil.WriteFile(fullPath, data, os.ModePerm); err != nil {
+// Create a file from the content of r. Content is written to a temporary file that's synced and
+// renamed into place, so readers only ever see complete files. If r doesn't produce exactly size
+// bytes nothing is written.
+func (fs FileStore) Create(ctx context.Context, key string, r io.Reader, size int64) error {
+	fullPath, err := fs.fullPath(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, DirMode); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, tempPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if err := writeTemp(tmp, r, size); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	// the rename itself isn't durable until the directory is synced
+	if err := syncDir(dir); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return nil
 }
 
+// writeTemp fills and closes a temporary file, making sure its content is on disk.
+func writeTemp(tmp *os.File, r io.Reader, size int64) error {
+	written, err := io.Copy(tmp, r)
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("expected %d bytes, got %d", size, written)
+	}
+
+	if err == nil {
+		err = tmp.Chmod(FileMode)
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// Open a file for reading. The caller must close it.
+func (fs FileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	fullPath, err := fs.fullPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, divulge.NotFoundError("content not found", err)
+		}
+
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return f, nil
+}
+
+// Write a file.
+func (fs FileStore) Write(ctx context.Context, key string, data []byte) error {
+	return fs.Create(ctx, key, bytes.NewReader(data), int64(len(data)))
+}
+
 // Read a file.
 func (fs FileStore) Read(ctx context.Context, key string) ([]byte, error) {
-	fullPath := fmt.Sprintf("%s/%s", fs.basePath, key)
-	data, err := ioutil.ReadFile(fullPath)
+	f, err := fs.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	return data, nil
 }
+
+// Delete a file. Deleting a file that doesn't exist is not an error.
+func (fs FileStore) Delete(ctx context.Context, key string) error {
+	fullPath, err := fs.fullPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks whether a file exists.
+func (fs FileStore) Exists(ctx context.Context, key string) (bool, error) {
+	fullPath, err := fs.fullPath(key)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return !info.IsDir(), nil
+}
+
+// Stat describes a file. The checksum is the hex SHA-256 of its content.
+func (fs FileStore) Stat(ctx context.Context, key string) (divulge.FileInfo, error) {
+	fullPath, err := fs.fullPath(key)
+	if err != nil {
+		return divulge.FileInfo{}, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return divulge.FileInfo{}, divulge.NotFoundError("content not found", err)
+		}
+
+		return divulge.FileInfo{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if info.IsDir() {
+		return divulge.FileInfo{}, divulge.NotFoundError("content not found", nil)
+	}
+
+	checksum, err := checksumFile(fullPath)
+	if err != nil {
+		return divulge.FileInfo{}, err
+	}
+
+	file := describe(key, info)
+	file.Checksum = checksum
+	return file, nil
+}
+
+// List describes every file whose key starts with prefix. Checksums would mean reading every
+// file, so they're left empty; use Stat for those.
+func (fs FileStore) List(ctx context.Context, prefix string) ([]divulge.FileInfo, error) {
+	// only the directory the prefix points into needs to be walked
+	root := fs.basePath
+	if dir := path.Dir(prefix); strings.Contains(prefix, "/") && dir != "." {
+		dirPath, err := fs.fullPath(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		root = dirPath
+	}
+
+	files := []divulge.FileInfo{}
+	err := filepath.Walk(root, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.IsDir() || strings.HasPrefix(info.Name(), tempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fs.basePath, fullPath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		files = append(files, describe(key, info))
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return files, nil
+}
+
+// SweepTemp deletes temporary files left behind by writes that never finished, as long as they
+// were last modified before cutoff. Writes still in progress keep touching theirs.
+func (fs FileStore) SweepTemp(ctx context.Context, cutoff time.Time) ([]divulge.FileInfo, error) {
+	swept := []divulge.FileInfo{}
+	err := filepath.Walk(fs.basePath, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.IsDir() || !strings.HasPrefix(info.Name(), tempPrefix) || !info.ModTime().Before(cutoff) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fs.basePath, fullPath)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		swept = append(swept, describe(filepath.ToSlash(rel), info))
+		return ctx.Err()
+	})
+	if err != nil {
+		return swept, fmt.Errorf("failed to sweep temporary files: %w", err)
+	}
+
+	return swept, nil
+}
+
+func describe(key string, info os.FileInfo) divulge.FileInfo {
+	return divulge.FileInfo{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// checksumFile returns the hex SHA-256 of a file's content.
+func checksumFile(fullPath string) (string, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to checksum file: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fullPath validates a key and returns the path it refers to. Keys must be relative, slash
+// separated and free of "." and ".." segments, which keeps every path inside the base path.
+// Segments can't start with tempPrefix either, since those files are hidden from List and
+// deleted by SweepTemp.
+func (fs FileStore) fullPath(key string) (string, error) {
+	if !validKey(key) {
+		return "", divulge.ValidationError(fmt.Sprintf("invalid content key: %q", key), nil)
+	}
+
+	return filepath.Join(fs.basePath, filepath.FromSlash(key)), nil
+}
+
+// validKey reports whether key can be used with a FileStore.
+func validKey(key string) bool {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.HasPrefix(segment, tempPrefix) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -4,9 +4,17 @@ package disk_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/eriktate/divulge"
 	"github.com/eriktate/divulge/disk"
 )
 
@@ -36,3 +44,204 @@ func Test_FileStore(t *testing.T) {
 		t.Fatalf("unexpected read data: %s", string(readData))
 	}
 }
+
+func Test_FileStore_NotFound(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fs := disk.New(os.TempDir())
+
+	// RUN
+	_, err := fs.Read(ctx, "does_not_exist.md")
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrNotFound) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
+
+func Test_FileStore_StatListDelete(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	basePath, err := ioutil.TempDir("", "divulge")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(basePath)
+
+	if err := os.MkdirAll(filepath.Join(basePath, "posts", "nested"), os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fs := disk.New(basePath)
+	for _, key := range []string{"posts/b.md", "posts/a.md", "posts/nested/c.md", "other.md"} {
+		if err := fs.Write(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// RUN
+	info, statErr := fs.Stat(ctx, "posts/a.md")
+	files, listErr := fs.List(ctx, "posts/")
+	deleteErr := fs.Delete(ctx, "posts/a.md")
+	exists, existsErr := fs.Exists(ctx, "posts/a.md")
+	deleteAgainErr := fs.Delete(ctx, "posts/a.md")
+	_, missingErr := fs.Stat(ctx, "posts/a.md")
+
+	// ASSERT
+	if statErr != nil || listErr != nil || deleteErr != nil || existsErr != nil || deleteAgainErr != nil {
+		t.Fatalf("unexpected errors: %v, %v, %v, %v, %v", statErr, listErr, deleteErr, existsErr, deleteAgainErr)
+	}
+
+	sum := sha256.Sum256([]byte("posts/a.md"))
+	if info.Key != "posts/a.md" || info.Size != 10 || info.ModTime.IsZero() || info.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected file info: %+v", info)
+	}
+
+	var keys []string
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+
+	if strings.Join(keys, ",") != "posts/a.md,posts/b.md,posts/nested/c.md" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	if files[0].Checksum != "" {
+		t.Fatalf("expected list not to read content, got %+v", files[0])
+	}
+
+	if exists {
+		t.Fatal("expected the file to be deleted")
+	}
+
+	if !errors.Is(missingErr, divulge.ErrNotFound) {
+		t.Fatalf("expected not found error, got: %v", missingErr)
+	}
+}
+
+func Test_FileStore_CreateOpen(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fs := disk.New(os.TempDir())
+	testData := "this is some streamed _markdown_"
+
+	// RUN
+	createErr := fs.Create(ctx, "test_stream.md", strings.NewReader(testData), int64(len(testData)))
+	f, openErr := fs.Open(ctx, "test_stream.md")
+	mismatchErr := fs.Create(ctx, "test_mismatch.md", strings.NewReader(testData), 1)
+	exists, _ := fs.Exists(ctx, "test_mismatch.md")
+
+	// ASSERT
+	if createErr != nil || openErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", createErr, openErr)
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if string(data) != testData {
+		t.Fatalf("unexpected read data: %s", string(data))
+	}
+
+	if mismatchErr == nil || exists {
+		t.Fatal("expected a size mismatch to fail without leaving a file behind")
+	}
+}
+
+func Test_FileStore_Keys(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	basePath, err := ioutil.TempDir("", "divulge")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(basePath)
+
+	fs := disk.New(filepath.Join(basePath, "store"))
+	invalid := []string{"", "../escape.md", "posts/../../escape.md", "/etc/passwd", "posts//a.md", "./a.md", `posts\a.md`, "posts/", "posts/.divulge-tmp-a.md", ".divulge-tmp-posts/a.md"}
+
+	// RUN
+	nestedErr := fs.Write(ctx, "accounts/1/posts/a.md", []byte("nested"))
+
+	// ASSERT
+	if nestedErr != nil {
+		t.Fatalf("unexpected error writing a nested key: %s", nestedErr)
+	}
+
+	info, err := os.Stat(filepath.Join(basePath, "store", "accounts", "1", "posts", "a.md"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if info.Mode().Perm() != disk.FileMode {
+		t.Fatalf("unexpected file mode: %s", info.Mode())
+	}
+
+	leftovers, _ := ioutil.ReadDir(filepath.Join(basePath, "store", "accounts", "1", "posts"))
+	if len(leftovers) != 1 {
+		t.Fatalf("expected no temporary files to be left behind, found %d files", len(leftovers))
+	}
+
+	for _, key := range invalid {
+		if err := fs.Write(ctx, key, []byte("escaped")); !errors.Is(err, divulge.ErrValidation) {
+			t.Fatalf("expected a validation error writing %q, got: %v", key, err)
+		}
+
+		if _, err := fs.Read(ctx, key); !errors.Is(err, divulge.ErrValidation) {
+			t.Fatalf("expected a validation error reading %q, got: %v", key, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(basePath, "escape.md")); !os.IsNotExist(err) {
+		t.Fatal("expected nothing to be written outside of the base path")
+	}
+}
+
+func Test_FileStore_SweepTemp(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	basePath, err := ioutil.TempDir("", "divulge")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(basePath)
+
+	fs := disk.New(basePath)
+	if err := fs.Write(ctx, "posts/a.md", []byte("content")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stale := filepath.Join(basePath, "posts", ".divulge-tmp-stale")
+	fresh := filepath.Join(basePath, "posts", ".divulge-tmp-fresh")
+	for _, path := range []string{stale, fresh} {
+		if err := ioutil.WriteFile(path, []byte("partial"), disk.FileMode); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	swept, err := fs.SweepTemp(ctx, time.Now().Add(-time.Hour))
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(swept) != 1 || swept[0].Key != "posts/.divulge-tmp-stale" {
+		t.Fatalf("unexpected swept files: %+v", swept)
+	}
+
+	for path, expected := range map[string]bool{stale: false, fresh: true, filepath.Join(basePath, "posts", "a.md"): true} {
+		if _, err := os.Stat(path); (err == nil) != expected {
+			t.Fatalf("unexpected state for %s: %v", path, err)
+		}
+	}
+}
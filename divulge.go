@@ -2,6 +2,8 @@ package divulge
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,25 +11,162 @@ import (
 
 var zeroUUID uuid.UUID
 
-// An Account is the owner of a blog/publication.
+// An Account is the owner of a blog/publication. Members with at least its TwoFactorRole have to
+// use two-factor authentication to work with it, unless that's TwoFactorNone.
 type Account struct {
-	ID        uuid.UUID  `json:"id,omitempty" db:"id"`
-	OwnerID   uuid.UUID  `json:"ownerId" db:"owner_id"`
-	Name      string     `json:"name" db:"name"`
-	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
-	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at"`
+	ID            uuid.UUID  `json:"id,omitempty" db:"id"`
+	OwnerID       uuid.UUID  `json:"ownerId" db:"owner_id"`
+	Name          string     `json:"name" db:"name"`
+	FeedContent   string     `json:"feedContent,omitempty" db:"feed_content"`
+	TwoFactorRole string     `json:"twoFactorRole,omitempty" db:"two_factor_role"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
+	DeletedAt     *time.Time `json:"deletedAt" db:"deleted_at"`
+}
+
+// Feed content settings, controlling whether an Account's feeds include full posts or just
+// their summaries.
+const (
+	FeedFull    = "full"
+	FeedSummary = "summary"
+)
+
+// TwoFactorNone is the TwoFactorRole of Accounts that leave two-factor authentication up to
+// their members.
+const TwoFactorNone = "none"
+
+// Roles a User can have within an Account, from most to least privileged. Each role can do
+// everything the ones below it can. There's exactly one owner, the Account's OwnerID.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+	RoleViewer = "viewer"
+)
+
+// A Membership is the Role a User has within an Account.
+type Membership struct {
+	UserID    uuid.UUID `json:"userId" db:"user_id"`
+	AccountID uuid.UUID `json:"accountId" db:"account_id"`
+	Role      string    `json:"role" db:"role"`
 }
 
 // A User is a member of an account. Responsible for creating blogs.
 type User struct {
-	ID        uuid.UUID
-	Accounts  []uuid.UUID
-	Name      string
-	Email     string
+	ID        uuid.UUID   `json:"id,omitempty" db:"id"`
+	Accounts  []uuid.UUID `json:"accounts" db:"-"`
+	Name      string      `json:"name" db:"name"`
+	Email     string      `json:"email" db:"email"`
+	TwoFactor bool        `json:"twoFactor" db:"two_factor"`
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time   `json:"updatedAt" db:"updated_at"`
+	DeletedAt *time.Time  `json:"deletedAt" db:"deleted_at"`
+}
+
+// A Session is a logged in User. Token is only known when the Session is created; only its hash
+// is ever stored. A Pending Session belongs to a User that still has to provide their second
+// factor, and can't be used for anything else, while a TwoFactor Session was started once they
+// had.
+type Session struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"userId" db:"user_id"`
+	Token     string     `json:"-" db:"-"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	Pending   bool       `json:"pending,omitempty" db:"pending"`
+	TwoFactor bool       `json:"twoFactor,omitempty" db:"two_factor"`
 	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
-	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+// An APIToken lets a User call the API without logging in, limited to its Scopes. Token is only
+// known when the APIToken is created; only its hash is ever stored, while Prefix is kept so the
+// User can tell their tokens apart. A nil ExpiresAt never expires. TwoFactor APITokens were
+// created from a Session that was verified with two-factor authentication.
+type APIToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"userId" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Token      string     `json:"token,omitempty" db:"-"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scopes     []string   `json:"scopes" db:"-"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+	TwoFactor  bool       `json:"twoFactor,omitempty" db:"two_factor"`
+}
+
+// A LoginLink logs a User in without a password. It's emailed to the User and can only be used
+// once before it expires. Like a Session, only the hash of its Token is ever stored.
+type LoginLink struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"userId" db:"user_id"`
+	Token     string     `json:"-" db:"-"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	UsedAt    *time.Time `json:"usedAt,omitempty" db:"used_at"`
+}
+
+// A TOTP is the secret a User's authenticator app generates codes from. It only counts as a
+// second factor once it's confirmed with a code. LastUsedStep is the time step of the last code
+// accepted, which keeps codes from being used twice.
+type TOTP struct {
+	UserID       uuid.UUID  `json:"-" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"`
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	ConfirmedAt  *time.Time `json:"confirmedAt,omitempty" db:"confirmed_at"`
+}
+
+// A TOTPEnrollment is what a User needs to set up their authenticator app. URI is usually shown
+// as a QR code, with Secret as a fallback for typing in by hand.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Scopes an APIToken can be limited to.
+const (
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopePostsPublish  = "posts:publish"
+	ScopeAccountsRead  = "accounts:read"
+	ScopeAccountsWrite = "accounts:write"
+)
+
+// Scopes lists every scope there is.
+var Scopes = []string{ScopePostsRead, ScopePostsWrite, ScopePostsPublish, ScopeAccountsRead, ScopeAccountsWrite}
+
+// Credentials are what a User logs in with.
+type Credentials struct {
+	UserID       uuid.UUID `json:"-" db:"user_id"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	CreatedAt    time.Time `json:"-" db:"created_at"`
+	UpdatedAt    time.Time `json:"-" db:"updated_at"`
+}
+
+// Password length limits. bcrypt ignores everything past 72 bytes, so longer passwords are
+// rejected instead of being silently truncated.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// ValidatePassword checks that a password can be used as a User's credentials.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return ValidationError(fmt.Sprintf("password must be at least %d characters", MinPasswordLength), nil)
+	}
+
+	if len(password) > MaxPasswordLength {
+		return ValidationError(fmt.Sprintf("password can't be longer than %d bytes", MaxPasswordLength), nil)
+	}
+
+	return nil
 }
 
 // A Post is just a blog post.
@@ -36,46 +175,295 @@ type Post struct {
 	AccountID   uuid.UUID  `json:"accountId" db:"account_id"`
 	AuthorID    uuid.UUID  `json:"authorId" db:"author_id"`
 	Title       string     `json:"title" db:"title"`
+	Slug        string     `json:"slug" db:"slug"`
 	Summary     string     `json:"summary" db:"summary"`
 	ContentPath string     `json:"contentPath,omitempty" db:"content_path"`
 	Content     string     `json:"content,omitempty" db:"-"`
+	HTML        string     `json:"html,omitempty" db:"-"`
+	TOC         []Heading  `json:"toc,omitempty" db:"-"`
+	Category    string     `json:"category,omitempty" db:"-"`
+	Tags        []string   `json:"tags" db:"-"`
 	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updatedAt" db:"updated_at"`
 	PublishedAt *time.Time `json:"publishedAt,omitempty" db:"published_at"`
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty" db:"scheduled_at"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+}
+
+// A Heading is an entry in the table of contents of a rendered Post. ID is the anchor of the
+// heading within the Post's HTML.
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
 }
 
-// An AccountService knows how to work with Accounts.
+// A Tag labels any number of Posts within an Account. Tags are identified by their slug, so two
+// names that slug the same way are the same Tag.
+type Tag struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	AccountID uuid.UUID `json:"accountId" db:"account_id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	PostCount int       `json:"postCount" db:"post_count"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// A Revision is an immutable snapshot of a Post, taken every time the Post is saved.
+type Revision struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	PostID      uuid.UUID `json:"postId" db:"post_id"`
+	Title       string    `json:"title" db:"title"`
+	Summary     string    `json:"summary" db:"summary"`
+	ContentPath string    `json:"contentPath,omitempty" db:"content_path"`
+	Content     string    `json:"content,omitempty" db:"-"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Diff operations describing how a line changed between two revisions.
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// A DiffLine is a single line of a diff between two revisions.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// An AccountService knows how to work with Accounts. Saving an Account makes its owner a
+// member with the owner Role, and adding a User that's already a member changes their Role. An
+// empty Role means RoleAuthor.
 type AccountService interface {
 	SaveAccount(ctx context.Context, account Account) (uuid.UUID, error)
 	FetchAccount(ctx context.Context, id uuid.UUID) (Account, error)
 	ListAccounts(ctx context.Context) ([]Account, error)
 	RemoveAccount(ctx context.Context, id uuid.UUID) error
+
+	AddAccountUser(ctx context.Context, accountID, userID uuid.UUID, role string) error
+	ListAccountUsers(ctx context.Context, accountID uuid.UUID) ([]User, error)
+	RemoveAccountUser(ctx context.Context, accountID, userID uuid.UUID) error
+	FetchMembership(ctx context.Context, accountID, userID uuid.UUID) (Membership, error)
+	ListMemberships(ctx context.Context, accountID uuid.UUID) ([]Membership, error)
 }
 
 // A UserService knows how to work with Users.
 type UserService interface {
 	SaveUser(ctx context.Context, user User) (uuid.UUID, error)
 	FetchUser(ctx context.Context, id uuid.UUID) (User, error)
+	FetchUserByEmail(ctx context.Context, email string) (User, error)
 	ListUsers(ctx context.Context) ([]User, error)
 	RemoveUser(ctx context.Context, id uuid.UUID) error
 }
 
-// A PostService knows how to work with Posts.
+// A CredentialService knows how to store the Credentials of Users. Credentials can only be
+// fetched by email for Users that haven't been removed.
+type CredentialService interface {
+	SaveCredentials(ctx context.Context, creds Credentials) error
+	FetchCredentials(ctx context.Context, userID uuid.UUID) (Credentials, error)
+	FetchCredentialsByEmail(ctx context.Context, email string) (Credentials, error)
+}
+
+// A SessionService knows how to store Sessions. Only active Sessions, the ones that haven't
+// expired or been revoked, are ever fetched or listed.
+type SessionService interface {
+	CreateSession(ctx context.Context, session Session) (uuid.UUID, error)
+	FetchSessionByTokenHash(ctx context.Context, tokenHash string) (Session, error)
+	ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, id uuid.UUID) error
+	RevokeSessionsByUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// An APITokenService knows how to store APITokens. Only active APITokens, the ones that haven't
+// expired or been revoked, are ever fetched, while listing includes expired ones. Touching an
+// APIToken records that it was just used.
+type APITokenService interface {
+	CreateAPIToken(ctx context.Context, token APIToken) (uuid.UUID, error)
+	FetchAPITokenByHash(ctx context.Context, tokenHash string) (APIToken, error)
+	ListAPITokensByUser(ctx context.Context, userID uuid.UUID) ([]APIToken, error)
+	RevokeAPIToken(ctx context.Context, userID, id uuid.UUID) error
+	RevokeAPITokensByUser(ctx context.Context, userID uuid.UUID) error
+	TouchAPIToken(ctx context.Context, id uuid.UUID) error
+}
+
+// A LoginLinkService knows how to store LoginLinks. Consuming a LoginLink marks it as used, and
+// only LoginLinks that haven't expired or been used before can be consumed.
+type LoginLinkService interface {
+	CreateLoginLink(ctx context.Context, link LoginLink) (uuid.UUID, error)
+	ConsumeLoginLink(ctx context.Context, tokenHash string) (LoginLink, error)
+}
+
+// A TwoFactorService knows how to store the TOTPs and recovery codes of Users. Saving a TOTP
+// replaces one that hasn't been confirmed yet, but conflicts with a confirmed one. Using a step
+// records it as the last one used and confirms the TOTP; steps that aren't after the last one
+// used aren't found. Recovery codes are stored as hashes and each can only be used once.
+type TwoFactorService interface {
+	SaveTOTP(ctx context.Context, totp TOTP) error
+	FetchTOTP(ctx context.Context, userID uuid.UUID) (TOTP, error)
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error
+	RemoveTOTP(ctx context.Context, userID uuid.UUID) error
+
+	SaveRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error
+}
+
+// A Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// A Mailer knows how to send Messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// An Authenticator logs Users in and out and manages their APITokens. Failing to log in or
+// authenticate returns an ErrUnauthorized that doesn't say why.
+//
+// Users with two-factor authentication get a Pending Session when they log in, which
+// VerifyLogin trades for a real one given a TOTP or recovery code. Confirming a TOTP and
+// regenerating recovery codes return the only copy of the new recovery codes.
+type Authenticator interface {
+	SetPassword(ctx context.Context, userID uuid.UUID, password string) error
+	ChangePassword(ctx context.Context, userID uuid.UUID, current, password string) error
+	Login(ctx context.Context, email, password string) (Session, error)
+	Authenticate(ctx context.Context, token string) (User, Session, error)
+	Logout(ctx context.Context, token string) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, id uuid.UUID) error
+
+	RequestLoginLink(ctx context.Context, email string) error
+	LoginWithLink(ctx context.Context, token string) (Session, error)
+
+	VerifyLogin(ctx context.Context, token, code string) (Session, error)
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) (Session, []string, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+
+	CreateAPIToken(ctx context.Context, token APIToken) (APIToken, error)
+	AuthenticateAPIToken(ctx context.Context, token string) (User, APIToken, error)
+	ListAPITokens(ctx context.Context, userID uuid.UUID) ([]APIToken, error)
+	RevokeAPIToken(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// A PostService knows how to work with Posts. Removing a Post moves it to the trash, where it
+// can be restored until it's purged for good.
 type PostService interface {
 	SavePost(ctx context.Context, post Post) (uuid.UUID, error)
 	PublishPost(ctx context.Context, id uuid.UUID) error
 	RedactPost(ctx context.Context, id uuid.UUID) error
+	SchedulePost(ctx context.Context, id uuid.UUID, at time.Time) error
+	UnschedulePost(ctx context.Context, id uuid.UUID) error
 
 	FetchPost(ctx context.Context, id uuid.UUID) (Post, error)
+	FetchPostBySlug(ctx context.Context, accountID uuid.UUID, slug string) (Post, error)
 	ListPostsByAccount(ctx context.Context, accountID uuid.UUID) ([]Post, error)
 
 	RemovePost(ctx context.Context, id uuid.UUID) error
+	ListTrashByAccount(ctx context.Context, accountID uuid.UUID) ([]Post, error)
+	RestorePost(ctx context.Context, id uuid.UUID) error
+	PurgePost(ctx context.Context, id uuid.UUID) error
+}
+
+// A TrashIndex knows which Posts have been in the trash for a given amount of time.
+type TrashIndex interface {
+	ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error)
 }
 
-// FileStore knows how to work with post content.
+// A PostScheduler publishes Posts once their scheduled time has passed.
+type PostScheduler interface {
+	PublishDuePosts(ctx context.Context, limit int) ([]uuid.UUID, error)
+}
+
+// A TagService knows how to organize the Tags within an Account. Tags are attached to and
+// detached from Posts by saving the Post.
+type TagService interface {
+	ListTags(ctx context.Context, accountID uuid.UUID) ([]Tag, error)
+	ListPostsByTag(ctx context.Context, accountID uuid.UUID, tag string) ([]Post, error)
+	RenameTag(ctx context.Context, accountID uuid.UUID, tag, name string) error
+	MergeTags(ctx context.Context, accountID uuid.UUID, from, into string) error
+}
+
+// A RevisionService knows how to store Revisions. Revisions are never updated once created.
+// SavePostRevision saves a Post together with the Revision recording that save.
+type RevisionService interface {
+	CreateRevision(ctx context.Context, revision Revision) (uuid.UUID, error)
+	SavePostRevision(ctx context.Context, post Post, revision Revision) (uuid.UUID, error)
+	FetchRevision(ctx context.Context, id uuid.UUID) (Revision, error)
+	ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]Revision, error)
+}
+
+// A RevisionHistory knows how to browse and restore the Revisions of a Post.
+type RevisionHistory interface {
+	FetchRevision(ctx context.Context, id uuid.UUID) (Revision, error)
+	ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]Revision, error)
+	DiffRevisions(ctx context.Context, fromID, toID uuid.UUID) ([]DiffLine, error)
+	RestoreRevision(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+}
+
+// FileInfo describes a file in a FileStore.
+type FileInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// Checksum changes whenever the content does. Its format depends on the FileStore, so it
+	// should only be compared with other checksums from the same store. List may leave it empty
+	// when it's expensive to compute, but Stat always sets it.
+	Checksum string `json:"checksum"`
+}
+
+// FileStore knows how to work with post content. Deleting a file that doesn't exist is not an
+// error, and List returns every file whose key starts with the prefix, ordered by key.
+//
+// Create and Open stream content. Create's size is the number of bytes r will produce, or -1 if
+// that isn't known ahead of time; a known size is checked once r is drained. Write and Read are
+// conveniences for content that's already in memory.
 type FileStore interface {
+	Create(ctx context.Context, key string, r io.Reader, size int64) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
 	Write(ctx context.Context, key string, data []byte) error
 	Read(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Stat(ctx context.Context, key string) (FileInfo, error)
+	List(ctx context.Context, prefix string) ([]FileInfo, error)
+}
+
+// A ContentRef is a reference from a Post, or one of its Revisions, to content in a FileStore.
+// RevisionID is nil for a Post's current content.
+type ContentRef struct {
+	Key        string     `json:"key" db:"content_path"`
+	PostID     uuid.UUID  `json:"postId" db:"post_id"`
+	RevisionID *uuid.UUID `json:"revisionId,omitempty" db:"revision_id"`
+}
+
+// A ContentReport describes what a ContentReconciler found. Deleted content was orphaned, while
+// Missing references point at content that no longer exists.
+type ContentReport struct {
+	Deleted []FileInfo   `json:"deleted"`
+	Missing []ContentRef `json:"missing"`
+}
+
+// A TempSweeper is a FileStore that can leave temporary files behind when it's interrupted
+// mid-write. SweepTemp deletes the ones last modified before cutoff and describes what it deleted.
+type TempSweeper interface {
+	SweepTemp(ctx context.Context, cutoff time.Time) ([]FileInfo, error)
+}
+
+// A ContentIndex knows which FileStore keys are referenced by Posts and Revisions.
+type ContentIndex interface {
+	ListContentRefs(ctx context.Context) ([]ContentRef, error)
+}
+
+// A ContentReconciler brings a FileStore back in line with the Posts and Revisions that
+// reference it.
+type ContentReconciler interface {
+	ReconcileContent(ctx context.Context) (ContentReport, error)
 }
 
 // IsEmpty returns true if the id provided is empty.
@@ -0,0 +1,94 @@
+package divulge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors describing the kinds of failures callers may want to handle. Use errors.Is
+// to check for them.
+var (
+	// ErrNotFound means the requested entity doesn't exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict means the operation collides with existing state, e.g. a duplicate email.
+	ErrConflict = errors.New("conflict")
+
+	// ErrValidation means the input was rejected.
+	ErrValidation = errors.New("validation failed")
+
+	// ErrUnauthorized means the caller couldn't be authenticated.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden means the caller isn't allowed to do what they asked.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// An Error classifies an underlying error as one of the sentinel errors while carrying a
+// message that's safe to show to clients.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Message, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the Error is of the target kind.
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// NotFoundError returns an ErrNotFound with the given message and cause.
+func NotFoundError(message string, err error) error {
+	return &Error{Kind: ErrNotFound, Message: message, Err: err}
+}
+
+// ConflictError returns an ErrConflict with the given message and cause.
+func ConflictError(message string, err error) error {
+	return &Error{Kind: ErrConflict, Message: message, Err: err}
+}
+
+// ValidationError returns an ErrValidation with the given message and cause.
+func ValidationError(message string, err error) error {
+	return &Error{Kind: ErrValidation, Message: message, Err: err}
+}
+
+// UnauthorizedError returns an ErrUnauthorized with the given message and cause.
+func UnauthorizedError(message string, err error) error {
+	return &Error{Kind: ErrUnauthorized, Message: message, Err: err}
+}
+
+// ForbiddenError returns an ErrForbidden with the given message and cause.
+func ForbiddenError(message string, err error) error {
+	return &Error{Kind: ErrForbidden, Message: message, Err: err}
+}
+
+// ErrorMessage returns a message describing err that's safe to show to clients, along with
+// whether err was classified at all.
+func ErrorMessage(err error) (string, bool) {
+	var derr *Error
+	if errors.As(err, &derr) {
+		return derr.Message, true
+	}
+
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrUnauthorized, ErrForbidden} {
+		if errors.Is(err, kind) {
+			return kind.Error(), true
+		}
+	}
+
+	return "", false
+}
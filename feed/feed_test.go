@@ -0,0 +1,166 @@
+package feed_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge/feed"
+	"github.com/google/uuid"
+)
+
+func testFeed() feed.Feed {
+	published := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	return feed.Feed{
+		ID:      uuid.New(),
+		Title:   "Test Blog",
+		Link:    "https://example.com/sites/blog/",
+		FeedURL: "https://example.com/sites/blog/feed.xml",
+		Updated: published.Add(time.Hour),
+		Items: []feed.Item{
+			{
+				ID:          uuid.New(),
+				Title:       "Full Post",
+				Link:        "https://example.com/sites/blog/posts/full-post",
+				Summary:     "A summary",
+				ContentHTML: "<p>Full & complete</p>",
+				Tags:        []string{"Go"},
+				Published:   published,
+				Updated:     published.Add(time.Hour),
+			},
+			{
+				ID:        uuid.New(),
+				Title:     "Summary Post",
+				Link:      "https://example.com/sites/blog/posts/summary-post",
+				Summary:   "Only a summary",
+				Published: published,
+				Updated:   published,
+			},
+		},
+	}
+}
+
+func Test_RSS(t *testing.T) {
+	// SETUP
+	f := testFeed()
+
+	// RUN
+	body, err := feed.RSS(f)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Items   []struct {
+			GUID struct {
+				IsPermaLink string `xml:"isPermaLink,attr"`
+				Value       string `xml:",chardata"`
+			} `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+			Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("unexpected error parsing feed: %s\n%s", err, body)
+	}
+
+	if doc.Version != "2.0" || len(doc.Items) != 2 {
+		t.Fatalf("unexpected feed:\n%s", body)
+	}
+
+	if doc.Items[0].GUID.Value != f.Items[0].ID.URN() || doc.Items[0].GUID.IsPermaLink != "false" {
+		t.Fatalf("unexpected guid: %+v", doc.Items[0].GUID)
+	}
+
+	if doc.Items[0].PubDate != "Thu, 04 Mar 2021 05:06:07 +0000" {
+		t.Fatalf("unexpected pubDate: %s", doc.Items[0].PubDate)
+	}
+
+	if doc.Items[0].Content != "<p>Full & complete</p>" || doc.Items[1].Content != "" {
+		t.Fatalf("unexpected content: %+v", doc.Items)
+	}
+}
+
+func Test_Atom(t *testing.T) {
+	// SETUP
+	f := testFeed()
+
+	// RUN
+	body, err := feed.Atom(f)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("unexpected error parsing feed: %s\n%s", err, body)
+	}
+
+	if doc.ID != f.ID.URN() || len(doc.Entries) != 2 {
+		t.Fatalf("unexpected feed:\n%s", body)
+	}
+
+	entry := doc.Entries[0]
+	if entry.ID != f.Items[0].ID.URN() || entry.Updated != "2021-03-04T06:06:07Z" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	if entry.Content.Type != "html" || entry.Content.Value != "<p>Full & complete</p>" {
+		t.Fatalf("unexpected content: %+v", entry.Content)
+	}
+}
+
+func Test_JSON(t *testing.T) {
+	// SETUP
+	f := testFeed()
+
+	// RUN
+	body, err := feed.JSON(f)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var doc struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID          string `json:"id"`
+			ContentHTML string `json:"content_html"`
+			ContentText string `json:"content_text"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("unexpected error parsing feed: %s", err)
+	}
+
+	if !strings.HasSuffix(doc.Version, "/1.1") || len(doc.Items) != 2 {
+		t.Fatalf("unexpected feed: %s", body)
+	}
+
+	if doc.Items[0].ID != f.Items[0].ID.String() || doc.Items[0].ContentHTML == "" {
+		t.Fatalf("unexpected first item: %+v", doc.Items[0])
+	}
+
+	if doc.Items[1].ContentText != "Only a summary" {
+		t.Fatalf("expected summaries to stand in for missing content: %+v", doc.Items[1])
+	}
+}
@@ -1,11 +1,23 @@
 module github.com/eriktate/divulge
 
-go 1.13
+go 1.19
 
 require (
+	github.com/go-chi/chi/v5 v5.0.7
 	github.com/google/uuid v1.1.1
-	github.com/jmoiron/sqlx v1.2.0
+	github.com/jmoiron/sqlx v1.3.5
 	github.com/lib/pq v1.3.0
-	github.com/satori/go.uuid v1.2.0 // indirect
+	github.com/microcosm-cc/bluemonday v1.0.27
 	github.com/sirupsen/logrus v1.4.2
+	github.com/yuin/goldmark v1.4.13
+	golang.org/x/crypto v0.24.0
+	golang.org/x/text v0.16.0
+)
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	github.com/satori/go.uuid v1.2.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 )
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+func (s *Server) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
+	var account divulge.Account
+	if !s.decode(w, r, &account) {
+		return
+	}
+
+	account.ID = uuid.UUID{}
+	id, err := s.accounts.SaveAccount(r.Context(), account)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, idResponse{ID: id})
+}
+
+func (s *Server) handleUpdateAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	var account divulge.Account
+	if !s.decode(w, r, &account) {
+		return
+	}
+
+	account.ID = id
+	if _, err := s.accounts.SaveAccount(r.Context(), account); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, idResponse{ID: id})
+}
+
+func (s *Server) handleFetchAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	account, err := s.accounts.FetchAccount(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, account)
+}
+
+func (s *Server) handleListAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.accounts.ListAccounts(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if accounts == nil {
+		accounts = []divulge.Account{}
+	}
+
+	s.writeJSON(w, http.StatusOK, accounts)
+}
+
+func (s *Server) handleRemoveAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	if err := s.accounts.RemoveAccount(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListAccountUsers(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	users, err := s.accounts.ListAccountUsers(r.Context(), accountID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if users == nil {
+		users = []divulge.User{}
+	}
+
+	s.writeJSON(w, http.StatusOK, users)
+}
+
+type membershipRequest struct {
+	Role string `json:"role"`
+}
+
+func (s *Server) handleListMemberships(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	memberships, err := s.accounts.ListMemberships(r.Context(), accountID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if memberships == nil {
+		memberships = []divulge.Membership{}
+	}
+
+	s.writeJSON(w, http.StatusOK, memberships)
+}
+
+func (s *Server) handleAddAccountUser(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	userID, ok := s.uuidParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	// the body is optional, adding a user without one gives them the default role
+	var req membershipRequest
+	if r.ContentLength != 0 && !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.accounts.AddAccountUser(r.Context(), accountID, userID, req.Role); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRemoveAccountUser(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	userID, ok := s.uuidParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	if err := s.accounts.RemoveAccountUser(r.Context(), accountID, userID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
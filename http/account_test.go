@@ -0,0 +1,128 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func Test_CreateAccount(t *testing.T) {
+	// SETUP
+	ownerID := uuid.New()
+	var saved divulge.Account
+	mockAS := &mock.AccountService{
+		SaveAccountFn: func(ctx context.Context, account divulge.Account) (uuid.UUID, error) {
+			saved = account
+			return uuid.New(), nil
+		},
+	}
+	server := newTestServer(services{accounts: mockAS})
+
+	body := `{"name": "Test Account", "ownerId": "` + ownerID.String() + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if saved.Name != "Test Account" || saved.OwnerID != ownerID {
+		t.Fatalf("unexpected saved account: %+v", saved)
+	}
+}
+
+func Test_ListAccountUsers(t *testing.T) {
+	// SETUP
+	accountID := uuid.New()
+	mockAS := &mock.AccountService{
+		ListAccountUsersFn: func(ctx context.Context, id uuid.UUID) ([]divulge.User, error) {
+			if id != accountID {
+				t.Fatalf("unexpected account ID: %s", id)
+			}
+
+			return []divulge.User{{ID: uuid.New(), Name: "Member"}}, nil
+		},
+	}
+	server := newTestServer(services{accounts: mockAS})
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/"+accountID.String()+"/users", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var users []divulge.User
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if len(users) != 1 || users[0].Name != "Member" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+}
+
+func Test_AddRemoveAccountUser(t *testing.T) {
+	// SETUP
+	accountID := uuid.New()
+	userID := uuid.New()
+	check := func(ctx context.Context, aID, uID uuid.UUID) error {
+		if aID != accountID || uID != userID {
+			t.Fatalf("unexpected membership: %s %s", aID, uID)
+		}
+
+		return nil
+	}
+	var roles []string
+	mockAS := &mock.AccountService{
+		AddAccountUserFn: func(ctx context.Context, aID, uID uuid.UUID, role string) error {
+			roles = append(roles, role)
+			return check(ctx, aID, uID)
+		},
+		RemoveAccountUserFn: check,
+	}
+	server := newTestServer(services{accounts: mockAS})
+	path := "/accounts/" + accountID.String() + "/users/" + userID.String()
+
+	// RUN
+	addRec := httptest.NewRecorder()
+	server.ServeHTTP(addRec, httptest.NewRequest(http.MethodPut, path, nil))
+
+	roleRec := httptest.NewRecorder()
+	server.ServeHTTP(roleRec, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"role": "editor"}`)))
+
+	removeRec := httptest.NewRecorder()
+	server.ServeHTTP(removeRec, httptest.NewRequest(http.MethodDelete, path, nil))
+
+	// ASSERT
+	if addRec.Code != http.StatusNoContent || roleRec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected add statuses: %d, %d", addRec.Code, roleRec.Code)
+	}
+
+	if len(roles) != 2 || roles[0] != "" || roles[1] != divulge.RoleEditor {
+		t.Fatalf("unexpected roles: %v", roles)
+	}
+
+	if removeRec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected remove status: %d", removeRec.Code)
+	}
+
+	if mockAS.RemoveAccountUserCount != 1 {
+		t.Fatal("expected remove to be called once")
+	}
+}
@@ -0,0 +1,503 @@
+package http
+
+import (
+	_ "embed"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+// SessionCookie is the name of the cookie holding a session token.
+const SessionCookie = "divulge_session"
+
+// PendingCookie is the name of the cookie holding the token of a pending session, which only
+// lives until the user provides their second factor.
+const PendingCookie = "divulge_pending"
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type passwordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password"`
+}
+
+// authenticate attaches the User behind an APIToken or session cookie to the request context.
+// An APIToken is passed as a Bearer token and limits the request to the token's scopes; unlike a
+// stale cookie, a bad one is rejected outright. Requests without either carry on anonymously
+// and it's up to each handler to decide whether that's allowed. Credentials that were verified
+// with two-factor authentication are marked with divulge.WithTwoFactor.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("Authorization"); header != "" {
+			raw, ok := bearerToken(header)
+			if !ok {
+				s.writeError(w, http.StatusUnauthorized, "expected a bearer token")
+				return
+			}
+
+			user, token, err := s.auth.AuthenticateAPIToken(r.Context(), raw)
+			if err != nil {
+				s.handleError(w, r, err)
+				return
+			}
+
+			ctx := divulge.WithScopes(divulge.WithUser(r.Context(), user), token.Scopes)
+			if token.TwoFactor {
+				ctx = divulge.WithTwoFactor(ctx)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		cookie, err := r.Cookie(SessionCookie)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, session, err := s.auth.Authenticate(r.Context(), cookie.Value)
+		if err != nil {
+			if errors.Is(err, divulge.ErrUnauthorized) {
+				s.clearSessionCookie(w, r)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			s.handleError(w, r, err)
+			return
+		}
+
+		ctx := divulge.WithUser(r.Context(), user)
+		if session.TwoFactor {
+			ctx = divulge.WithTwoFactor(ctx)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// currentUser returns the authenticated User, responding with a 401 if there isn't one.
+func (s *Server) currentUser(w http.ResponseWriter, r *http.Request) (divulge.User, bool) {
+	user, ok := divulge.UserFromContext(r.Context())
+	if !ok {
+		s.writeError(w, http.StatusUnauthorized, "authentication required")
+	}
+
+	return user, ok
+}
+
+// sessionUser is like currentUser, but only for Users that logged in. Requests made with an
+// APIToken are refused so a leaked token can't be used to take over an account.
+func (s *Server) sessionUser(w http.ResponseWriter, r *http.Request) (divulge.User, bool) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return user, false
+	}
+
+	if _, limited := divulge.ScopesFromContext(r.Context()); limited {
+		s.writeError(w, http.StatusForbidden, "requires logging in")
+		return user, false
+	}
+
+	return user, true
+}
+
+// bearerToken pulls the token out of an Authorization header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	session, err := s.auth.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, r, session)
+	s.writeJSON(w, http.StatusOK, session)
+}
+
+// LoginPath serves a page that finishes logging in with an emailed LoginLink. It's the default
+// login link URL, for deployments without a frontend of their own.
+const LoginPath = "/login"
+
+//go:embed login.html
+var loginPage []byte
+
+// handleLoginPage serves the page emailed login links point to. The page posts the link's token
+// back to the API, which sets the session cookie, and asks for a code when the user has
+// two-factor authentication.
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// the token is in the URL, so it mustn't leak to anything the page links to
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Write(loginPage)
+}
+
+type loginLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// handleRequestLoginLink accepts the request whether or not anyone uses the email, so it can't
+// be used to find out who has an account.
+func (s *Server) handleRequestLoginLink(w http.ResponseWriter, r *http.Request) {
+	var req loginLinkRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.auth.RequestLoginLink(r.Context(), req.Email); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type linkLoginRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *Server) handleLoginWithLink(w http.ResponseWriter, r *http.Request) {
+	var req linkLoginRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	session, err := s.auth.LoginWithLink(r.Context(), req.Token)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, r, session)
+	s.writeJSON(w, http.StatusOK, session)
+}
+
+type verifyLoginRequest struct {
+	Code string `json:"code"`
+}
+
+// handleVerifyLogin finishes logging in a user with two-factor authentication. The pending
+// session is used up whether or not the code is right.
+func (s *Server) handleVerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req verifyLoginRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	cookie, err := r.Cookie(PendingCookie)
+	if err != nil || cookie.Value == "" {
+		s.writeError(w, http.StatusUnauthorized, "login expired")
+		return
+	}
+
+	s.clearCookie(w, r, PendingCookie)
+	session, err := s.auth.VerifyLogin(r.Context(), cookie.Value, req.Code)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, r, session)
+	s.writeJSON(w, http.StatusOK, session)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+		if err := s.auth.Logout(r.Context(), cookie.Value); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	s.clearSessionCookie(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFetchCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, user)
+}
+
+// handleChangePassword sets a new password for the current user. Every session is revoked when
+// a password changes, including this one, so the user has to log in again.
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req passwordRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.auth.ChangePassword(r.Context(), user.ID, req.CurrentPassword, req.Password); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.clearSessionCookie(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.auth.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if sessions == nil {
+		sessions = []divulge.Session{}
+	}
+
+	s.writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := s.uuidParam(w, r, "sessionID")
+	if !ok {
+		return
+	}
+
+	if err := s.auth.RevokeSession(r.Context(), user.ID, id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := s.auth.EnrollTOTP(r.Context(), user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, enrollment)
+}
+
+type twoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// handleConfirmTOTP swaps the session cookie for the new Session ConfirmTOTP starts, since the
+// one the request was made with has been revoked along with every other.
+func (s *Server) handleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req twoFactorRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	session, codes, err := s.auth.ConfirmTOTP(r.Context(), user.ID, req.Code)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.setSessionCookie(w, r, session)
+	s.writeJSON(w, http.StatusOK, recoveryCodesResponse{codes})
+}
+
+func (s *Server) handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req twoFactorRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.auth.DisableTOTP(r.Context(), user.ID, req.Code); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req twoFactorRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	codes, err := s.auth.RegenerateRecoveryCodes(r.Context(), user.ID, req.Code)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, recoveryCodesResponse{codes})
+}
+
+type apiTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (s *Server) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req apiTokenRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	token, err := s.auth.CreateAPIToken(r.Context(), divulge.APIToken{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, token)
+}
+
+func (s *Server) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := s.auth.ListAPITokens(r.Context(), user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if tokens == nil {
+		tokens = []divulge.APIToken{}
+	}
+
+	s.writeJSON(w, http.StatusOK, tokens)
+}
+
+func (s *Server) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := s.uuidParam(w, r, "tokenID")
+	if !ok {
+		return
+	}
+
+	if err := s.auth.RevokeAPIToken(r.Context(), user.ID, id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setSessionCookie hands a new session token to the client. The cookie is out of reach of
+// scripts and isn't sent along with cross-site requests, which is what keeps other sites from
+// making requests on a user's behalf. Pending sessions get a cookie of their own so they're
+// never sent where a real session is expected.
+func (s *Server) setSessionCookie(w http.ResponseWriter, r *http.Request, session divulge.Session) {
+	name := SessionCookie
+	if session.Pending {
+		name = PendingCookie
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   secure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	s.clearCookie(w, r, SessionCookie)
+}
+
+func (s *Server) clearCookie(w http.ResponseWriter, r *http.Request, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// secure reports whether a request was made over HTTPS, trusting X-Forwarded-Proto so cookies
+// stay secure behind a TLS terminating proxy.
+func secure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
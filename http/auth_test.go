@@ -0,0 +1,526 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	divulgehttp "github.com/eriktate/divulge/http"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	return findCookie(rec, divulgehttp.SessionCookie)
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func Test_Login(t *testing.T) {
+	// SETUP
+	mockAuth := &mock.Authenticator{
+		LoginFn: func(ctx context.Context, email, password string) (divulge.Session, error) {
+			if email != "test@test.com" || password != "correct horse" {
+				return divulge.Session{}, divulge.UnauthorizedError("invalid email or password", nil)
+			}
+
+			return divulge.Session{ID: uuid.New(), Token: "secret-token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	body := `{"email": "test@test.com", "password": "correct horse"}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+
+	wrong := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email": "test@test.com", "password": "nope"}`))
+	wrongRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+	server.ServeHTTP(wrongRec, wrong)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	cookie := sessionCookie(rec)
+	if cookie == nil || cookie.Value != "secret-token" {
+		t.Fatalf("expected a session cookie, got %+v", cookie)
+	}
+
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected a secure cookie, got %+v", cookie)
+	}
+
+	if strings.Contains(rec.Body.String(), "secret-token") {
+		t.Fatal("expected the token to only be sent in the cookie")
+	}
+
+	if wrongRec.Code != http.StatusUnauthorized || sessionCookie(wrongRec) != nil {
+		t.Fatalf("expected a failed login to be unauthorized, got %d", wrongRec.Code)
+	}
+}
+
+func Test_Authenticate(t *testing.T) {
+	// SETUP
+	user := divulge.User{ID: uuid.New(), Email: "test@test.com"}
+	mockAuth := &mock.Authenticator{
+		AuthenticateFn: func(ctx context.Context, token string) (divulge.User, divulge.Session, error) {
+			if token != "secret-token" {
+				return divulge.User{}, divulge.Session{}, divulge.UnauthorizedError("session expired", nil)
+			}
+
+			return user, divulge.Session{UserID: user.ID}, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	valid := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	valid.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "secret-token"})
+	expired := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	expired.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "old-token"})
+	anonymous := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+
+	validRec := httptest.NewRecorder()
+	expiredRec := httptest.NewRecorder()
+	anonymousRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(validRec, valid)
+	server.ServeHTTP(expiredRec, expired)
+	server.ServeHTTP(anonymousRec, anonymous)
+
+	// ASSERT
+	if validRec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", validRec.Code)
+	}
+
+	var fetched divulge.User
+	if err := json.NewDecoder(validRec.Body).Decode(&fetched); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if fetched.ID != user.ID {
+		t.Fatalf("unexpected user: %+v", fetched)
+	}
+
+	if expiredRec.Code != http.StatusUnauthorized || anonymousRec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected statuses: %d, %d", expiredRec.Code, anonymousRec.Code)
+	}
+
+	if cookie := sessionCookie(expiredRec); cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf("expected an expired session cookie to be cleared, got %+v", cookie)
+	}
+}
+
+func Test_Logout(t *testing.T) {
+	// SETUP
+	var loggedOut string
+	mockAuth := &mock.Authenticator{
+		LogoutFn: func(ctx context.Context, token string) error {
+			loggedOut = token
+			return nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "secret-token"})
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if loggedOut != "secret-token" {
+		t.Fatalf("unexpected token logged out: %q", loggedOut)
+	}
+
+	if cookie := sessionCookie(rec); cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf("expected the session cookie to be cleared, got %+v", cookie)
+	}
+}
+
+func Test_RevokeSession(t *testing.T) {
+	// SETUP
+	user := divulge.User{ID: uuid.New()}
+	sessionID := uuid.New()
+	var revokedUser, revoked uuid.UUID
+	mockAuth := &mock.Authenticator{
+		AuthenticateFn: func(ctx context.Context, token string) (divulge.User, divulge.Session, error) {
+			return user, divulge.Session{UserID: user.ID}, nil
+		},
+		RevokeSessionFn: func(ctx context.Context, userID, id uuid.UUID) error {
+			revokedUser = userID
+			revoked = id
+			return nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	req := httptest.NewRequest(http.MethodDelete, "/auth/sessions/"+sessionID.String(), nil)
+	req.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "secret-token"})
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if revokedUser != user.ID || revoked != sessionID {
+		t.Fatalf("unexpected revocation: %s, %s", revokedUser, revoked)
+	}
+}
+
+func Test_CreateUser_Password(t *testing.T) {
+	// SETUP
+	userID := uuid.New()
+	mockUS := &mock.UserService{
+		SaveUserFn: func(ctx context.Context, user divulge.User) (uuid.UUID, error) {
+			return userID, nil
+		},
+	}
+	var passwordFor uuid.UUID
+	mockAuth := &mock.Authenticator{
+		SetPasswordFn: func(ctx context.Context, id uuid.UUID, password string) error {
+			passwordFor = id
+			return nil
+		},
+	}
+	server := newTestServer(services{users: mockUS, auth: mockAuth})
+
+	good := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "Test", "email": "test@test.com", "password": "correct horse"}`))
+	bad := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "Test", "email": "test@test.com", "password": "short"}`))
+	goodRec := httptest.NewRecorder()
+	badRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(goodRec, good)
+	server.ServeHTTP(badRec, bad)
+
+	// ASSERT
+	if goodRec.Code != http.StatusCreated || passwordFor != userID {
+		t.Fatalf("expected the new user's password to be set, got %d", goodRec.Code)
+	}
+
+	if badRec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected status: %d", badRec.Code)
+	}
+
+	if mockUS.SaveUserCount != 1 {
+		t.Fatal("expected a bad password to be rejected before the user is created")
+	}
+}
+
+func Test_Authenticate_Bearer(t *testing.T) {
+	// SETUP
+	user := divulge.User{ID: uuid.New(), Email: "test@test.com"}
+	mockAuth := &mock.Authenticator{
+		AuthenticateAPITokenFn: func(ctx context.Context, token string) (divulge.User, divulge.APIToken, error) {
+			if token != "dvg_secret" {
+				return divulge.User{}, divulge.APIToken{}, divulge.UnauthorizedError("invalid token", nil)
+			}
+
+			return user, divulge.APIToken{UserID: user.ID, Scopes: []string{divulge.ScopePostsRead}}, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	valid := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	valid.Header.Set("Authorization", "Bearer dvg_secret")
+	invalid := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	invalid.Header.Set("Authorization", "Bearer dvg_wrong")
+	malformed := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	malformed.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	manage := httptest.NewRequest(http.MethodGet, "/auth/tokens", nil)
+	manage.Header.Set("Authorization", "Bearer dvg_secret")
+
+	validRec := httptest.NewRecorder()
+	invalidRec := httptest.NewRecorder()
+	malformedRec := httptest.NewRecorder()
+	manageRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(validRec, valid)
+	server.ServeHTTP(invalidRec, invalid)
+	server.ServeHTTP(malformedRec, malformed)
+	server.ServeHTTP(manageRec, manage)
+
+	// ASSERT
+	if validRec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", validRec.Code)
+	}
+
+	if invalidRec.Code != http.StatusUnauthorized || malformedRec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected statuses: %d, %d", invalidRec.Code, malformedRec.Code)
+	}
+
+	if manageRec.Code != http.StatusForbidden {
+		t.Fatalf("expected tokens to be refused for managing tokens, got %d", manageRec.Code)
+	}
+
+	if mockAuth.ListAPITokensCount != 0 {
+		t.Fatal("expected tokens not to be listed")
+	}
+}
+
+func Test_CreateAPIToken(t *testing.T) {
+	// SETUP
+	user := divulge.User{ID: uuid.New(), Email: "test@test.com"}
+	var created divulge.APIToken
+	mockAuth := &mock.Authenticator{
+		AuthenticateFn: func(ctx context.Context, token string) (divulge.User, divulge.Session, error) {
+			return user, divulge.Session{UserID: user.ID}, nil
+		},
+		CreateAPITokenFn: func(ctx context.Context, token divulge.APIToken) (divulge.APIToken, error) {
+			created = token
+			token.ID = uuid.New()
+			token.Token = "dvg_secret"
+			return token, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	body := `{"name": "deploys", "scopes": ["posts:read", "posts:write"]}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/tokens", strings.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "secret-token"})
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if created.UserID != user.ID || created.Name != "deploys" || len(created.Scopes) != 2 {
+		t.Fatalf("unexpected token: %+v", created)
+	}
+
+	var token divulge.APIToken
+	if err := json.NewDecoder(rec.Body).Decode(&token); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if token.Token != "dvg_secret" {
+		t.Fatalf("expected the token to be returned once, got %+v", token)
+	}
+}
+
+func Test_LoginWithLink(t *testing.T) {
+	// SETUP
+	var requested string
+	mockAuth := &mock.Authenticator{
+		RequestLoginLinkFn: func(ctx context.Context, email string) error {
+			requested = email
+			return nil
+		},
+		LoginWithLinkFn: func(ctx context.Context, token string) (divulge.Session, error) {
+			if token != "link-token" {
+				return divulge.Session{}, divulge.UnauthorizedError("invalid or expired login link", nil)
+			}
+
+			return divulge.Session{ID: uuid.New(), Token: "secret-token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	request := httptest.NewRequest(http.MethodPost, "/auth/login/link", strings.NewReader(`{"email": "test@test.com"}`))
+	verify := httptest.NewRequest(http.MethodPost, "/auth/login/link/verify", strings.NewReader(`{"token": "link-token"}`))
+	wrong := httptest.NewRequest(http.MethodPost, "/auth/login/link/verify", strings.NewReader(`{"token": "used-token"}`))
+
+	requestRec := httptest.NewRecorder()
+	verifyRec := httptest.NewRecorder()
+	wrongRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(requestRec, request)
+	server.ServeHTTP(verifyRec, verify)
+	server.ServeHTTP(wrongRec, wrong)
+
+	// ASSERT
+	if requestRec.Code != http.StatusAccepted || requested != "test@test.com" {
+		t.Fatalf("unexpected request: %d for %q", requestRec.Code, requested)
+	}
+
+	if verifyRec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", verifyRec.Code)
+	}
+
+	if cookie := sessionCookie(verifyRec); cookie == nil || cookie.Value != "secret-token" {
+		t.Fatalf("expected a session cookie, got %+v", cookie)
+	}
+
+	if wrongRec.Code != http.StatusUnauthorized || sessionCookie(wrongRec) != nil {
+		t.Fatalf("expected a bad link to be unauthorized, got %d", wrongRec.Code)
+	}
+}
+
+func Test_VerifyLogin(t *testing.T) {
+	// SETUP
+	mockAuth := &mock.Authenticator{
+		LoginFn: func(ctx context.Context, email, password string) (divulge.Session, error) {
+			return divulge.Session{ID: uuid.New(), Token: "pending-token", Pending: true, ExpiresAt: time.Now().Add(5 * time.Minute)}, nil
+		},
+		VerifyLoginFn: func(ctx context.Context, token, code string) (divulge.Session, error) {
+			if token != "pending-token" || code != "123456" {
+				return divulge.Session{}, divulge.UnauthorizedError("invalid code", nil)
+			}
+
+			return divulge.Session{ID: uuid.New(), Token: "secret-token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	login := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email": "test@test.com", "password": "correct horse"}`))
+	verify := httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"code": "123456"}`))
+	verify.AddCookie(&http.Cookie{Name: divulgehttp.PendingCookie, Value: "pending-token"})
+	wrong := httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"code": "654321"}`))
+	wrong.AddCookie(&http.Cookie{Name: divulgehttp.PendingCookie, Value: "pending-token"})
+	missing := httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"code": "123456"}`))
+
+	loginRec := httptest.NewRecorder()
+	verifyRec := httptest.NewRecorder()
+	wrongRec := httptest.NewRecorder()
+	missingRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(loginRec, login)
+	server.ServeHTTP(verifyRec, verify)
+	server.ServeHTTP(wrongRec, wrong)
+	server.ServeHTTP(missingRec, missing)
+
+	// ASSERT
+	if loginRec.Code != http.StatusOK || sessionCookie(loginRec) != nil {
+		t.Fatalf("expected a pending login not to set a session cookie, got %d", loginRec.Code)
+	}
+
+	if cookie := findCookie(loginRec, divulgehttp.PendingCookie); cookie == nil || cookie.Value != "pending-token" || !cookie.HttpOnly {
+		t.Fatalf("expected a pending cookie, got %+v", cookie)
+	}
+
+	if verifyRec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", verifyRec.Code)
+	}
+
+	if cookie := sessionCookie(verifyRec); cookie == nil || cookie.Value != "secret-token" {
+		t.Fatalf("expected a session cookie, got %+v", cookie)
+	}
+
+	if cookie := findCookie(wrongRec, divulgehttp.PendingCookie); wrongRec.Code != http.StatusUnauthorized || cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf("expected a wrong code to be unauthorized and clear the pending cookie, got %d, %+v", wrongRec.Code, cookie)
+	}
+
+	if missingRec.Code != http.StatusUnauthorized || mockAuth.VerifyLoginCount != 2 {
+		t.Fatalf("expected a missing pending cookie to be unauthorized, got %d", missingRec.Code)
+	}
+}
+
+func Test_TwoFactor(t *testing.T) {
+	// SETUP
+	user := divulge.User{ID: uuid.New(), Email: "test@test.com"}
+	mockAuth := &mock.Authenticator{
+		AuthenticateFn: func(ctx context.Context, token string) (divulge.User, divulge.Session, error) {
+			return user, divulge.Session{UserID: user.ID}, nil
+		},
+		AuthenticateAPITokenFn: func(ctx context.Context, token string) (divulge.User, divulge.APIToken, error) {
+			return user, divulge.APIToken{UserID: user.ID, Scopes: []string{divulge.ScopeAccountsWrite}}, nil
+		},
+		EnrollTOTPFn: func(ctx context.Context, userID uuid.UUID) (divulge.TOTPEnrollment, error) {
+			return divulge.TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/Divulge:test@test.com?secret=SECRET"}, nil
+		},
+		ConfirmTOTPFn: func(ctx context.Context, userID uuid.UUID, code string) (divulge.Session, []string, error) {
+			session := divulge.Session{UserID: userID, Token: "verified-token", TwoFactor: true, ExpiresAt: time.Now().Add(time.Hour)}
+			return session, []string{"abcd-efgh-ijkl-mnop"}, nil
+		},
+	}
+	server := newTestServer(services{auth: mockAuth})
+
+	enroll := httptest.NewRequest(http.MethodPost, "/auth/2fa", nil)
+	enroll.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "secret-token"})
+	confirm := httptest.NewRequest(http.MethodPost, "/auth/2fa/confirm", strings.NewReader(`{"code": "123456"}`))
+	confirm.AddCookie(&http.Cookie{Name: divulgehttp.SessionCookie, Value: "secret-token"})
+	bearer := httptest.NewRequest(http.MethodPost, "/auth/2fa/disable", strings.NewReader(`{"code": "123456"}`))
+	bearer.Header.Set("Authorization", "Bearer dvg_secret")
+
+	enrollRec := httptest.NewRecorder()
+	confirmRec := httptest.NewRecorder()
+	bearerRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(enrollRec, enroll)
+	server.ServeHTTP(confirmRec, confirm)
+	server.ServeHTTP(bearerRec, bearer)
+
+	// ASSERT
+	var enrollment divulge.TOTPEnrollment
+	if err := json.NewDecoder(enrollRec.Body).Decode(&enrollment); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if enrollRec.Code != http.StatusCreated || enrollment.Secret != "SECRET" {
+		t.Fatalf("unexpected enrollment: %d, %+v", enrollRec.Code, enrollment)
+	}
+
+	var codes struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	if err := json.NewDecoder(confirmRec.Body).Decode(&codes); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if confirmRec.Code != http.StatusOK || len(codes.RecoveryCodes) != 1 {
+		t.Fatalf("unexpected confirmation: %d, %+v", confirmRec.Code, codes)
+	}
+
+	if cookie := sessionCookie(confirmRec); cookie == nil || cookie.Value != "verified-token" {
+		t.Fatalf("expected the session cookie to be replaced, got %+v", cookie)
+	}
+
+	if bearerRec.Code != http.StatusForbidden || mockAuth.DisableTOTPCount != 0 {
+		t.Fatalf("expected tokens to be refused for managing two-factor authentication, got %d", bearerRec.Code)
+	}
+}
+
+func Test_LoginPage(t *testing.T) {
+	// SETUP
+	server := newTestServer(services{auth: &mock.Authenticator{}})
+	req := httptest.NewRequest(http.MethodGet, divulgehttp.LoginPath+"?token=link-token", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	if rec.Header().Get("Referrer-Policy") != "no-referrer" || !strings.Contains(rec.Body.String(), "/auth/login/link/verify") {
+		t.Fatalf("expected a page that verifies the link, got:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,192 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/eriktate/divulge"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// A Server exposes divulge services over a JSON API.
+type Server struct {
+	posts     divulge.PostService
+	revisions divulge.RevisionHistory
+	tags      divulge.TagService
+	accounts  divulge.AccountService
+	users     divulge.UserService
+	auth      divulge.Authenticator
+	logger    *logrus.Logger
+	router    chi.Router
+}
+
+// NewServer returns a new Server backed by the given services.
+func NewServer(posts divulge.PostService, revisions divulge.RevisionHistory, tags divulge.TagService, accounts divulge.AccountService, users divulge.UserService, auth divulge.Authenticator, logger *logrus.Logger) *Server {
+	s := &Server{
+		posts:     posts,
+		revisions: revisions,
+		tags:      tags,
+		accounts:  accounts,
+		users:     users,
+		auth:      auth,
+		logger:    logger,
+		router:    chi.NewRouter(),
+	}
+
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.router.Use(s.authenticate)
+
+	s.router.Get(LoginPath, s.handleLoginPage)
+	s.router.Route("/auth", func(r chi.Router) {
+		r.Post("/login", s.handleLogin)
+		r.Post("/login/link", s.handleRequestLoginLink)
+		r.Post("/login/link/verify", s.handleLoginWithLink)
+		r.Post("/login/verify", s.handleVerifyLogin)
+		r.Post("/logout", s.handleLogout)
+		r.Get("/me", s.handleFetchCurrentUser)
+		r.Put("/password", s.handleChangePassword)
+		r.Get("/sessions", s.handleListSessions)
+		r.Delete("/sessions/{sessionID}", s.handleRevokeSession)
+		r.Get("/tokens", s.handleListAPITokens)
+		r.Post("/tokens", s.handleCreateAPIToken)
+		r.Delete("/tokens/{tokenID}", s.handleRevokeAPIToken)
+		r.Post("/2fa", s.handleEnrollTOTP)
+		r.Post("/2fa/confirm", s.handleConfirmTOTP)
+		r.Post("/2fa/disable", s.handleDisableTOTP)
+		r.Post("/2fa/recovery-codes", s.handleRegenerateRecoveryCodes)
+	})
+
+	s.router.Route("/posts", func(r chi.Router) {
+		r.Post("/", s.handleCreatePost)
+		r.Get("/{postID}", s.handleFetchPost)
+		r.Put("/{postID}", s.handleUpdatePost)
+		r.Delete("/{postID}", s.handleRemovePost)
+		r.Post("/{postID}/restore", s.handleRestorePost)
+		r.Post("/{postID}/purge", s.handlePurgePost)
+		r.Post("/{postID}/publish", s.handlePublishPost)
+		r.Post("/{postID}/redact", s.handleRedactPost)
+		r.Put("/{postID}/schedule", s.handleSchedulePost)
+		r.Delete("/{postID}/schedule", s.handleUnschedulePost)
+		r.Get("/{postID}/revisions", s.handleListRevisions)
+		r.Get("/{postID}/revisions/diff", s.handleDiffRevisions)
+		r.Get("/{postID}/revisions/{revisionID}", s.handleFetchRevision)
+		r.Post("/{postID}/revisions/{revisionID}/restore", s.handleRestoreRevision)
+	})
+
+	s.router.Route("/accounts", func(r chi.Router) {
+		r.Post("/", s.handleCreateAccount)
+		r.Get("/", s.handleListAccounts)
+		r.Get("/{accountID}", s.handleFetchAccount)
+		r.Put("/{accountID}", s.handleUpdateAccount)
+		r.Delete("/{accountID}", s.handleRemoveAccount)
+		r.Get("/{accountID}/posts", s.handleListPostsByAccount)
+		r.Get("/{accountID}/posts/by-slug/{slug}", s.handleFetchPostBySlug)
+		r.Get("/{accountID}/trash", s.handleListTrashByAccount)
+		r.Get("/{accountID}/tags", s.handleListTags)
+		r.Put("/{accountID}/tags/{tag}", s.handleRenameTag)
+		r.Get("/{accountID}/tags/{tag}/posts", s.handleListPostsByTag)
+		r.Post("/{accountID}/tags/{tag}/merge", s.handleMergeTags)
+		r.Get("/{accountID}/users", s.handleListAccountUsers)
+		r.Get("/{accountID}/members", s.handleListMemberships)
+		r.Put("/{accountID}/users/{userID}", s.handleAddAccountUser)
+		r.Delete("/{accountID}/users/{userID}", s.handleRemoveAccountUser)
+	})
+
+	s.router.Route("/users", func(r chi.Router) {
+		r.Post("/", s.handleCreateUser)
+		r.Get("/", s.handleListUsers)
+		r.Get("/{userID}", s.handleFetchUser)
+		r.Put("/{userID}", s.handleUpdateUser)
+		r.Delete("/{userID}", s.handleRemoveUser)
+	})
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// An errorEnvelope is the body returned for any failed request.
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// An idResponse is returned when a resource is created.
+type idResponse struct {
+	ID uuid.UUID `json:"id"`
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.WithError(err).Error("failed to encode response")
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, errorEnvelope{
+		Error: errorBody{
+			Status:  status,
+			Message: message,
+		},
+	})
+}
+
+// handleError maps divulge errors onto their matching status codes. Anything unexpected is logged
+// and written out as an internal error.
+func (s *Server) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, divulge.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, divulge.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, divulge.ErrValidation):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, divulge.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, divulge.ErrForbidden):
+		status = http.StatusForbidden
+	}
+
+	message, ok := divulge.ErrorMessage(err)
+	if !ok || status == http.StatusInternalServerError {
+		s.logger.WithError(err).WithField("path", r.URL.Path).Error("request failed")
+		s.writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	s.writeError(w, status, message)
+}
+
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, dest interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(dest); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) uuidParam(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, name))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid "+name)
+		return id, false
+	}
+
+	return id, true
+}
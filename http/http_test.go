@@ -0,0 +1,51 @@
+package http_test
+
+import (
+	"io/ioutil"
+
+	"github.com/eriktate/divulge"
+	divulgehttp "github.com/eriktate/divulge/http"
+	"github.com/eriktate/divulge/mock"
+	"github.com/sirupsen/logrus"
+)
+
+// services collects the dependencies of a test server. Any left nil are filled in with
+// empty mocks.
+type services struct {
+	posts     divulge.PostService
+	revisions divulge.RevisionHistory
+	tags      divulge.TagService
+	accounts  divulge.AccountService
+	users     divulge.UserService
+	auth      divulge.Authenticator
+}
+
+func newTestServer(svc services) *divulgehttp.Server {
+	if svc.posts == nil {
+		svc.posts = &mock.PostService{}
+	}
+
+	if svc.revisions == nil {
+		svc.revisions = &mock.RevisionHistory{}
+	}
+
+	if svc.tags == nil {
+		svc.tags = &mock.TagService{}
+	}
+
+	if svc.accounts == nil {
+		svc.accounts = &mock.AccountService{}
+	}
+
+	if svc.users == nil {
+		svc.users = &mock.UserService{}
+	}
+
+	if svc.auth == nil {
+		svc.auth = &mock.Authenticator{}
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	return divulgehttp.NewServer(svc.posts, svc.revisions, svc.tags, svc.accounts, svc.users, svc.auth, logger)
+}
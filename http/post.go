@@ -0,0 +1,232 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) handleCreatePost(w http.ResponseWriter, r *http.Request) {
+	var post divulge.Post
+	if !s.decode(w, r, &post) {
+		return
+	}
+
+	// creation always generates a new ID
+	post.ID = uuid.UUID{}
+	id, err := s.posts.SavePost(r.Context(), post)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, idResponse{ID: id})
+}
+
+func (s *Server) handleUpdatePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	var post divulge.Post
+	if !s.decode(w, r, &post) {
+		return
+	}
+
+	post.ID = id
+	if _, err := s.posts.SavePost(r.Context(), post); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, idResponse{ID: id})
+}
+
+func (s *Server) handleFetchPost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	post, err := s.posts.FetchPost(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, post)
+}
+
+// handleFetchPostBySlug redirects to the post's current slug when an old one is used.
+func (s *Server) handleFetchPostBySlug(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+	post, err := s.posts.FetchPostBySlug(r.Context(), accountID, slug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if post.Slug != slug {
+		location := "/accounts/" + accountID.String() + "/posts/by-slug/" + url.PathEscape(post.Slug)
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, post)
+}
+
+func (s *Server) handleListPostsByAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	posts, err := s.posts.ListPostsByAccount(r.Context(), accountID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if posts == nil {
+		posts = []divulge.Post{}
+	}
+
+	s.writeJSON(w, http.StatusOK, posts)
+}
+
+func (s *Server) handlePublishPost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	if err := s.posts.PublishPost(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRedactPost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	if err := s.posts.RedactPost(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// A scheduleRequest sets when a post should be published.
+type scheduleRequest struct {
+	At time.Time `json:"at"`
+}
+
+func (s *Server) handleSchedulePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	var req scheduleRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.posts.SchedulePost(r.Context(), id, req.At); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleUnschedulePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	if err := s.posts.UnschedulePost(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRemovePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	if err := s.posts.RemovePost(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListTrashByAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	posts, err := s.posts.ListTrashByAccount(r.Context(), accountID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if posts == nil {
+		posts = []divulge.Post{}
+	}
+
+	s.writeJSON(w, http.StatusOK, posts)
+}
+
+func (s *Server) handleRestorePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	if err := s.posts.RestorePost(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePurgePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	if err := s.posts.PurgePost(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
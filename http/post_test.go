@@ -0,0 +1,399 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func Test_CreatePost(t *testing.T) {
+	// SETUP
+	newID := uuid.New()
+	var saved divulge.Post
+	mockPS := &mock.PostService{
+		SavePostFn: func(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
+			saved = post
+			return newID, nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+
+	body := `{"id": "` + uuid.New().String() + `", "title": "Test Post"}`
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if !divulge.IsEmpty(saved.ID) {
+		t.Fatal("expected client supplied ID to be ignored")
+	}
+
+	if saved.Title != "Test Post" {
+		t.Fatalf("unexpected title: %s", saved.Title)
+	}
+
+	var res struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if res.ID != newID {
+		t.Fatalf("unexpected id: %s", res.ID)
+	}
+}
+
+func Test_CreatePost_BadBody(t *testing.T) {
+	// SETUP
+	mockPS := &mock.PostService{}
+	server := newTestServer(services{posts: mockPS})
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader("{"))
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if mockPS.SavePostCount != 0 {
+		t.Fatal("expected SavePost not to be called")
+	}
+}
+
+func Test_UpdatePost(t *testing.T) {
+	// SETUP
+	id := uuid.New()
+	var saved divulge.Post
+	mockPS := &mock.PostService{
+		SavePostFn: func(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
+			saved = post
+			return post.ID, nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+
+	req := httptest.NewRequest(http.MethodPut, "/posts/"+id.String(), strings.NewReader(`{"title": "Updated"}`))
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if saved.ID != id {
+		t.Fatalf("unexpected saved ID: %s", saved.ID)
+	}
+}
+
+func Test_FetchPost(t *testing.T) {
+	// SETUP
+	id := uuid.New()
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, postID uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: postID, Title: "Fetched"}, nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/"+id.String(), nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var post divulge.Post
+	if err := json.NewDecoder(rec.Body).Decode(&post); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if post.ID != id || post.Title != "Fetched" {
+		t.Fatalf("unexpected post: %+v", post)
+	}
+}
+
+func Test_FetchPost_BadID(t *testing.T) {
+	// SETUP
+	mockPS := &mock.PostService{}
+	server := newTestServer(services{posts: mockPS})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/not-a-uuid", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
+
+func Test_FetchPost_Error(t *testing.T) {
+	// SETUP
+	mockPS := &mock.PostService{Error: errors.New("forced")}
+	server := newTestServer(services{posts: mockPS})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/"+uuid.New().String(), nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var envelope struct {
+		Error struct {
+			Status  int    `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&envelope); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if envelope.Error.Status != http.StatusInternalServerError {
+		t.Fatalf("unexpected envelope status: %d", envelope.Error.Status)
+	}
+
+	if strings.Contains(envelope.Error.Message, "forced") {
+		t.Fatal("expected internal error details to be hidden")
+	}
+}
+
+func Test_ListPostsByAccount(t *testing.T) {
+	// SETUP
+	accountID := uuid.New()
+	mockPS := &mock.PostService{
+		ListPostsByAccountFn: func(ctx context.Context, id uuid.UUID) ([]divulge.Post, error) {
+			if id != accountID {
+				t.Fatalf("unexpected account ID: %s", id)
+			}
+
+			return []divulge.Post{{ID: uuid.New()}, {ID: uuid.New()}}, nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/"+accountID.String()+"/posts", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var posts []divulge.Post
+	if err := json.NewDecoder(rec.Body).Decode(&posts); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if len(posts) != 2 {
+		t.Fatalf("unexpected number of posts: %d", len(posts))
+	}
+}
+
+func Test_PublishRedactRemovePost(t *testing.T) {
+	// SETUP
+	mockPS := &mock.PostService{}
+	server := newTestServer(services{posts: mockPS})
+	id := uuid.New().String()
+
+	reqs := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/posts/"+id+"/publish", nil),
+		httptest.NewRequest(http.MethodPost, "/posts/"+id+"/redact", nil),
+		httptest.NewRequest(http.MethodDelete, "/posts/"+id, nil),
+	}
+
+	for _, req := range reqs {
+		rec := httptest.NewRecorder()
+
+		// RUN
+		server.ServeHTTP(rec, req)
+
+		// ASSERT
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("unexpected status for %s %s: %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	if mockPS.PublishPostCount != 1 || mockPS.RedactPostCount != 1 || mockPS.RemovePostCount != 1 {
+		t.Fatal("expected publish, redact and remove to each be called once")
+	}
+}
+
+func Test_Trash(t *testing.T) {
+	// SETUP
+	accountID := uuid.New()
+	trashed := time.Now()
+	mockPS := &mock.PostService{
+		ListTrashByAccountFn: func(ctx context.Context, id uuid.UUID) ([]divulge.Post, error) {
+			return []divulge.Post{{ID: uuid.New(), AccountID: id, DeletedAt: &trashed}}, nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+	id := uuid.New().String()
+
+	list := httptest.NewRequest(http.MethodGet, "/accounts/"+accountID.String()+"/trash", nil)
+	reqs := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/posts/"+id+"/restore", nil),
+		httptest.NewRequest(http.MethodPost, "/posts/"+id+"/purge", nil),
+	}
+	listRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(listRec, list)
+	for _, req := range reqs {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		// ASSERT
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("unexpected status for %s %s: %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	if listRec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", listRec.Code)
+	}
+
+	var posts []divulge.Post
+	if err := json.NewDecoder(listRec.Body).Decode(&posts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(posts) != 1 || posts[0].DeletedAt == nil {
+		t.Fatalf("unexpected trash: %+v", posts)
+	}
+
+	if mockPS.RestorePostCount != 1 || mockPS.PurgePostCount != 1 {
+		t.Fatal("expected restore and purge to each be called once")
+	}
+}
+
+func Test_PostErrorStatuses(t *testing.T) {
+	// SETUP
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{divulge.NotFoundError("post not found", nil), http.StatusNotFound},
+		{divulge.ConflictError("post already exists", nil), http.StatusConflict},
+		{divulge.ValidationError("title is required", nil), http.StatusUnprocessableEntity},
+		{fmt.Errorf("failed to fetch: %w", divulge.ErrNotFound), http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		mockPS := &mock.PostService{Error: c.err}
+		server := newTestServer(services{posts: mockPS})
+
+		req := httptest.NewRequest(http.MethodGet, "/posts/"+uuid.New().String(), nil)
+		rec := httptest.NewRecorder()
+
+		// RUN
+		server.ServeHTTP(rec, req)
+
+		// ASSERT
+		if rec.Code != c.status {
+			t.Fatalf("unexpected status for %v: %d", c.err, rec.Code)
+		}
+	}
+}
+
+func Test_SchedulePost(t *testing.T) {
+	// SETUP
+	id := uuid.New()
+	at := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	var scheduled time.Time
+	mockPS := &mock.PostService{
+		SchedulePostFn: func(ctx context.Context, postID uuid.UUID, publishAt time.Time) error {
+			scheduled = publishAt
+			return nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+
+	body := `{"at": "` + at.Format(time.RFC3339) + `"}`
+	req := httptest.NewRequest(http.MethodPut, "/posts/"+id.String()+"/schedule", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if !scheduled.Equal(at) {
+		t.Fatalf("unexpected scheduled time: %s", scheduled)
+	}
+}
+
+func Test_FetchPostBySlug(t *testing.T) {
+	// SETUP
+	accountID := uuid.New()
+	mockPS := &mock.PostService{
+		FetchPostBySlugFn: func(ctx context.Context, id uuid.UUID, slug string) (divulge.Post, error) {
+			return divulge.Post{ID: uuid.New(), AccountID: id, Slug: "current-slug"}, nil
+		},
+	}
+	server := newTestServer(services{posts: mockPS})
+	base := "/accounts/" + accountID.String() + "/posts/by-slug/"
+
+	current := httptest.NewRequest(http.MethodGet, base+"current-slug", nil)
+	currentRec := httptest.NewRecorder()
+	old := httptest.NewRequest(http.MethodGet, base+"old-slug", nil)
+	oldRec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(currentRec, current)
+	server.ServeHTTP(oldRec, old)
+
+	// ASSERT
+	if currentRec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", currentRec.Code)
+	}
+
+	if oldRec.Code != http.StatusMovedPermanently {
+		t.Fatalf("unexpected status for old slug: %d", oldRec.Code)
+	}
+
+	if location := oldRec.Header().Get("Location"); location != base+"current-slug" {
+		t.Fatalf("unexpected location: %s", location)
+	}
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// fetchPostRevision fetches a revision, treating revisions of other posts as missing.
+func (s *Server) fetchPostRevision(w http.ResponseWriter, r *http.Request) (divulge.Revision, bool) {
+	postID, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return divulge.Revision{}, false
+	}
+
+	revisionID, ok := s.uuidParam(w, r, "revisionID")
+	if !ok {
+		return divulge.Revision{}, false
+	}
+
+	revision, err := s.revisions.FetchRevision(r.Context(), revisionID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return revision, false
+	}
+
+	if revision.PostID != postID {
+		s.writeError(w, http.StatusNotFound, "revision not found")
+		return revision, false
+	}
+
+	return revision, true
+}
+
+func (s *Server) handleListRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	revisions, err := s.revisions.ListRevisionsByPost(r.Context(), postID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if revisions == nil {
+		revisions = []divulge.Revision{}
+	}
+
+	s.writeJSON(w, http.StatusOK, revisions)
+}
+
+func (s *Server) handleFetchRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := s.fetchPostRevision(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, revision)
+}
+
+func (s *Server) handleDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, ok := s.uuidParam(w, r, "postID")
+	if !ok {
+		return
+	}
+
+	fromID, err := uuid.Parse(r.URL.Query().Get("from"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid from")
+		return
+	}
+
+	toID, err := uuid.Parse(r.URL.Query().Get("to"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid to")
+		return
+	}
+
+	// both revisions must belong to the post in the path
+	for _, id := range []uuid.UUID{fromID, toID} {
+		revision, err := s.revisions.FetchRevision(r.Context(), id)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		if revision.PostID != postID {
+			s.writeError(w, http.StatusNotFound, "revision not found")
+			return
+		}
+	}
+
+	lines, err := s.revisions.DiffRevisions(r.Context(), fromID, toID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if lines == nil {
+		lines = []divulge.DiffLine{}
+	}
+
+	s.writeJSON(w, http.StatusOK, lines)
+}
+
+func (s *Server) handleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := s.fetchPostRevision(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := s.revisions.RestoreRevision(r.Context(), revision.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, idResponse{ID: id})
+}
@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func Test_FetchRevision_WrongPost(t *testing.T) {
+	// SETUP
+	mockRH := &mock.RevisionHistory{
+		FetchRevisionFn: func(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+			return divulge.Revision{ID: id, PostID: uuid.New()}, nil
+		},
+	}
+	server := newTestServer(services{revisions: mockRH})
+
+	path := "/posts/" + uuid.New().String() + "/revisions/" + uuid.New().String()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
+
+func Test_DiffRevisions(t *testing.T) {
+	// SETUP
+	postID := uuid.New()
+	mockRH := &mock.RevisionHistory{
+		FetchRevisionFn: func(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+			return divulge.Revision{ID: id, PostID: postID}, nil
+		},
+		DiffRevisionsFn: func(ctx context.Context, fromID, toID uuid.UUID) ([]divulge.DiffLine, error) {
+			return []divulge.DiffLine{{Op: divulge.DiffInsert, Text: "new"}}, nil
+		},
+	}
+	server := newTestServer(services{revisions: mockRH})
+
+	path := "/posts/" + postID.String() + "/revisions/diff?from=" + uuid.New().String() + "&to=" + uuid.New().String()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if mockRH.DiffRevisionsCount != 1 {
+		t.Fatal("expected diff to be called once")
+	}
+}
+
+func Test_RestoreRevision(t *testing.T) {
+	// SETUP
+	postID := uuid.New()
+	mockRH := &mock.RevisionHistory{
+		FetchRevisionFn: func(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+			return divulge.Revision{ID: id, PostID: postID}, nil
+		},
+		RestoreRevisionFn: func(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+			return postID, nil
+		},
+	}
+	server := newTestServer(services{revisions: mockRH})
+
+	path := "/posts/" + postID.String() + "/revisions/" + uuid.New().String() + "/restore"
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if mockRH.RestoreRevisionCount != 1 {
+		t.Fatal("expected restore to be called once")
+	}
+}
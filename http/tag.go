@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/eriktate/divulge"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	tags, err := s.tags.ListTags(r.Context(), accountID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if tags == nil {
+		tags = []divulge.Tag{}
+	}
+
+	s.writeJSON(w, http.StatusOK, tags)
+}
+
+func (s *Server) handleListPostsByTag(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	posts, err := s.tags.ListPostsByTag(r.Context(), accountID, chi.URLParam(r, "tag"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if posts == nil {
+		posts = []divulge.Post{}
+	}
+
+	s.writeJSON(w, http.StatusOK, posts)
+}
+
+// A renameTagRequest gives a tag a new name.
+type renameTagRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) handleRenameTag(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	var req renameTagRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.tags.RenameTag(r.Context(), accountID, chi.URLParam(r, "tag"), req.Name); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// A mergeTagsRequest names the tag that should absorb another.
+type mergeTagsRequest struct {
+	Into string `json:"into"`
+}
+
+func (s *Server) handleMergeTags(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := s.uuidParam(w, r, "accountID")
+	if !ok {
+		return
+	}
+
+	var req mergeTagsRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if err := s.tags.MergeTags(r.Context(), accountID, chi.URLParam(r, "tag"), req.Into); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
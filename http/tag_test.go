@@ -0,0 +1,118 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func Test_ListTags(t *testing.T) {
+	// SETUP
+	accountID := uuid.New()
+	mockTS := &mock.TagService{
+		ListTagsFn: func(ctx context.Context, id uuid.UUID) ([]divulge.Tag, error) {
+			return []divulge.Tag{{AccountID: id, Name: "Go", Slug: "go", PostCount: 3}}, nil
+		},
+	}
+	server := newTestServer(services{tags: mockTS})
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/"+accountID.String()+"/tags", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var tags []divulge.Tag
+	if err := json.NewDecoder(rec.Body).Decode(&tags); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if len(tags) != 1 || tags[0].PostCount != 3 || tags[0].AccountID != accountID {
+		t.Fatalf("unexpected tags: %+v", tags)
+	}
+}
+
+func Test_ListPostsByTag(t *testing.T) {
+	// SETUP
+	var requested string
+	mockTS := &mock.TagService{
+		ListPostsByTagFn: func(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error) {
+			requested = tag
+			return nil, nil
+		},
+	}
+	server := newTestServer(services{tags: mockTS})
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/"+uuid.New().String()+"/tags/go/posts", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if requested != "go" {
+		t.Fatalf("unexpected tag: %s", requested)
+	}
+
+	if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+		t.Fatalf("expected an empty list, got: %s", body)
+	}
+}
+
+func Test_RenameAndMergeTags(t *testing.T) {
+	// SETUP
+	var renamed, mergedFrom, mergedInto string
+	mockTS := &mock.TagService{
+		RenameTagFn: func(ctx context.Context, accountID uuid.UUID, tag, name string) error {
+			renamed = tag + "->" + name
+			return nil
+		},
+		MergeTagsFn: func(ctx context.Context, accountID uuid.UUID, from, into string) error {
+			mergedFrom, mergedInto = from, into
+			return nil
+		},
+	}
+	server := newTestServer(services{tags: mockTS})
+	base := "/accounts/" + uuid.New().String() + "/tags/"
+
+	reqs := []*http.Request{
+		httptest.NewRequest(http.MethodPut, base+"golang", strings.NewReader(`{"name": "Go"}`)),
+		httptest.NewRequest(http.MethodPost, base+"golang/merge", strings.NewReader(`{"into": "go"}`)),
+	}
+
+	for _, req := range reqs {
+		rec := httptest.NewRecorder()
+
+		// RUN
+		server.ServeHTTP(rec, req)
+
+		// ASSERT
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("unexpected status for %s %s: %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	if renamed != "golang->Go" {
+		t.Fatalf("unexpected rename: %s", renamed)
+	}
+
+	if mergedFrom != "golang" || mergedInto != "go" {
+		t.Fatalf("unexpected merge: %s into %s", mergedFrom, mergedInto)
+	}
+}
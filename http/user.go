@@ -0,0 +1,109 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// A createUserRequest is a User along with the password they'll log in with, if they have one.
+type createUserRequest struct {
+	divulge.User
+	Password string `json:"password"`
+}
+
+func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req createUserRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	// the password is checked up front so a bad one doesn't leave a user behind
+	if req.Password != "" {
+		if err := divulge.ValidatePassword(req.Password); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	user := req.User
+	user.ID = uuid.UUID{}
+	id, err := s.users.SaveUser(r.Context(), user)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if req.Password != "" {
+		if err := s.auth.SetPassword(r.Context(), id, req.Password); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusCreated, idResponse{ID: id})
+}
+
+func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	var user divulge.User
+	if !s.decode(w, r, &user) {
+		return
+	}
+
+	user.ID = id
+	if _, err := s.users.SaveUser(r.Context(), user); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, idResponse{ID: id})
+}
+
+func (s *Server) handleFetchUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	user, err := s.users.FetchUser(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.users.ListUsers(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if users == nil {
+		users = []divulge.User{}
+	}
+
+	s.writeJSON(w, http.StatusOK, users)
+}
+
+func (s *Server) handleRemoveUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.uuidParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	if err := s.users.RemoveUser(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/google/uuid"
+)
+
+func Test_CreateUser(t *testing.T) {
+	// SETUP
+	var saved divulge.User
+	mockUS := &mock.UserService{
+		SaveUserFn: func(ctx context.Context, user divulge.User) (uuid.UUID, error) {
+			saved = user
+			return uuid.New(), nil
+		},
+	}
+	server := newTestServer(services{users: mockUS})
+
+	body := `{"name": "Test User", "email": "test@test.com"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if saved.Name != "Test User" || saved.Email != "test@test.com" {
+		t.Fatalf("unexpected saved user: %+v", saved)
+	}
+}
+
+func Test_FetchUser(t *testing.T) {
+	// SETUP
+	id := uuid.New()
+	mockUS := &mock.UserService{
+		FetchUserFn: func(ctx context.Context, userID uuid.UUID) (divulge.User, error) {
+			return divulge.User{ID: userID, Email: "test@test.com"}, nil
+		},
+	}
+	server := newTestServer(services{users: mockUS})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+id.String(), nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	server.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var user divulge.User
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	if user.ID != id || user.Email != "test@test.com" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/sirupsen/logrus"
+)
+
+// A Log Mailer logs messages instead of sending them. It's meant for development, and since it
+// logs whole messages, login links included, it shouldn't be used anywhere else.
+type Log struct {
+	logger *logrus.Logger
+}
+
+// NewLog returns a new Log Mailer.
+func NewLog(logger *logrus.Logger) Log {
+	return Log{logger}
+}
+
+// Send logs msg.
+func (m Log) Send(ctx context.Context, msg divulge.Message) error {
+	if _, err := parseAddress("recipient", msg.To); err != nil {
+		return err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info(msg.Body)
+
+	return nil
+}
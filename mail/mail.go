@@ -0,0 +1,48 @@
+// Package mail implements divulge.Mailer, either by sending messages over SMTP or by logging
+// them for development.
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+// format renders msg as a plain text email from the given address. Recipients are parsed rather
+// than copied into the headers so a crafted address can't inject headers of its own.
+func format(from, to *netmail.Address, msg divulge.Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	if _, err := body.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseAddress parses a single email address, returning a validation error if it isn't one.
+func parseAddress(field, address string) (*netmail.Address, error) {
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil {
+		return nil, divulge.ValidationError(fmt.Sprintf("invalid %s address: %q", field, address), err)
+	}
+
+	return parsed, nil
+}
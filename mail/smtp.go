@@ -0,0 +1,135 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+// DefaultSMTPPort is the submission port, used when Config.Port isn't set.
+const DefaultSMTPPort = 587
+
+// Config configures an SMTP Mailer.
+type Config struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Username and Password are only sent if Username is set. They're never sent in the clear
+	// to anything but localhost, so the server has to support STARTTLS.
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// From is the address every message is sent from, like "Divulge <noreply@example.com>".
+	From string `json:"from"`
+}
+
+// An SMTP Mailer sends messages through an SMTP server, upgrading the connection with STARTTLS
+// whenever the server supports it.
+type SMTP struct {
+	cfg  Config
+	from *netmail.Address
+	now  func() time.Time
+}
+
+// NewSMTP returns a new SMTP Mailer.
+func NewSMTP(cfg Config) (SMTP, error) {
+	if cfg.Host == "" {
+		return SMTP{}, errors.New("smtp host is required")
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = DefaultSMTPPort
+	}
+
+	from, err := netmail.ParseAddress(cfg.From)
+	if err != nil {
+		return SMTP{}, fmt.Errorf("invalid smtp from address: %w", err)
+	}
+
+	return SMTP{
+		cfg:  cfg,
+		from: from,
+		now:  time.Now,
+	}, nil
+}
+
+// Send delivers msg to its recipient. The whole conversation with the server has to finish
+// before ctx is done.
+func (m SMTP) Send(ctx context.Context, msg divulge.Message) error {
+	to, err := parseAddress("recipient", msg.To)
+	if err != nil {
+		return err
+	}
+
+	data, err := format(m.from, to, msg, m.now())
+	if err != nil {
+		return fmt.Errorf("failed to format message: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if err := m.send(client, to.Address, data); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+func (m SMTP) send(client *smtp.Client, to string, data []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
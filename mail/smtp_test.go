@@ -0,0 +1,216 @@
+package mail_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mail"
+)
+
+// A delivery is what a fakeSMTP server received in one session.
+type delivery struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTP is just enough of an SMTP server to accept mail on localhost. It doesn't support
+// STARTTLS, so credentials are only sent because it's local.
+type fakeSMTP struct {
+	listener   net.Listener
+	deliveries chan delivery
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := &fakeSMTP{listener: listener, deliveries: make(chan delivery, 1)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go f.serve(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeSMTP) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost fake smtp")
+
+	var d delivery
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			text.PrintfLine("250-localhost")
+			text.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			d.auth = arg
+			text.PrintfLine("235 authenticated")
+		case "MAIL":
+			d.from = arg
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			d.to = append(d.to, arg)
+			text.PrintfLine("250 ok")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			d.data = string(data)
+			text.PrintfLine("250 queued")
+			f.deliveries <- d
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func Test_SMTP_Send(t *testing.T) {
+	// SETUP
+	server := newFakeSMTP(t)
+	mailer, err := mail.NewSMTP(mail.Config{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "divulge",
+		Password: "secret",
+		From:     "Divulge <noreply@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link := "https://example.com/login?token=" + strings.Repeat("a", 80)
+	msg := divulge.Message{
+		To:      "author@example.com",
+		Subject: "Log in to Divulge ✓",
+		Body:    "Use this link to log in:\n\n" + link + "\n.\n",
+	}
+
+	// RUN
+	if err := mailer.Send(ctx, msg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var d delivery
+	select {
+	case d = <-server.deliveries:
+	case <-ctx.Done():
+		t.Fatal("expected a message to be delivered")
+	}
+
+	// ASSERT
+	auth, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(d.auth, "PLAIN "))
+	if err != nil || string(auth) != "\x00divulge\x00secret" {
+		t.Fatalf("unexpected auth: %q", d.auth)
+	}
+
+	if d.from != "FROM:<noreply@example.com>" || len(d.to) != 1 || d.to[0] != "TO:<author@example.com>" {
+		t.Fatalf("unexpected envelope: %s, %v", d.from, d.to)
+	}
+
+	parsed, err := netmail.ReadMessage(bufio.NewReader(strings.NewReader(d.data)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Fatalf("unexpected subject: %q", parsed.Header.Get("Subject"))
+	}
+
+	if parsed.Header.Get("To") != "<author@example.com>" {
+		t.Fatalf("unexpected recipient: %q", parsed.Header.Get("To"))
+	}
+
+	body, err := ioutil.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !strings.Contains(string(body), link) {
+		t.Fatalf("expected the link to survive encoding, got:\n%s", body)
+	}
+}
+
+func Test_SMTP_InvalidRecipient(t *testing.T) {
+	// SETUP
+	server := newFakeSMTP(t)
+	mailer, err := mail.NewSMTP(mail.Config{Host: "127.0.0.1", Port: server.port(), From: "noreply@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	err = mailer.Send(context.TODO(), divulge.Message{To: "author@example.com\r\nBcc: everyone@example.com", Subject: "hi"})
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	select {
+	case d := <-server.deliveries:
+		t.Fatalf("expected nothing to be delivered, got %+v", d)
+	default:
+	}
+}
+
+func Test_NewSMTP_Invalid(t *testing.T) {
+	for _, cfg := range []mail.Config{
+		{From: "noreply@example.com"},
+		{Host: "localhost", Port: 25},
+		{Host: "localhost", From: "not an address"},
+	} {
+		// RUN
+		_, err := mail.NewSMTP(cfg)
+
+		// ASSERT
+		if err == nil {
+			t.Fatalf("expected %+v to be rejected", cfg)
+		}
+	}
+}
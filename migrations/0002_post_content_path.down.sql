@@ -0,0 +1,3 @@
+ALTER TABLE posts
+	DROP COLUMN IF EXISTS deleted_at,
+	DROP COLUMN IF EXISTS content_path;
@@ -0,0 +1,3 @@
+ALTER TABLE posts
+	ADD COLUMN IF NOT EXISTS content_path VARCHAR(1024),
+	ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP DEFAULT NULL;
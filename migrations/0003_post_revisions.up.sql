@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS post_revisions(
+	id UUID PRIMARY KEY,
+	post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	title VARCHAR(256) NOT NULL,
+	summary VARCHAR(512) NOT NULL DEFAULT '',
+	content_path VARCHAR(1024) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id_idx ON post_revisions(post_id, created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS posts_scheduled_at_idx;
+
+ALTER TABLE posts
+	DROP COLUMN IF EXISTS scheduled_at;
@@ -0,0 +1,4 @@
+ALTER TABLE posts
+	ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS posts_scheduled_at_idx ON posts(scheduled_at) WHERE scheduled_at IS NOT NULL;
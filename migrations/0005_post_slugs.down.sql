@@ -0,0 +1,6 @@
+DROP TABLE post_slug_redirects;
+
+DROP INDEX IF EXISTS posts_account_id_slug_idx;
+
+ALTER TABLE posts
+	DROP COLUMN IF EXISTS slug;
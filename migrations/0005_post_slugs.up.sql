@@ -0,0 +1,20 @@
+ALTER TABLE posts
+	ADD COLUMN IF NOT EXISTS slug VARCHAR(128);
+
+-- existing posts get their ID as a slug, which is guaranteed to be unique
+UPDATE posts
+SET slug = id::text
+WHERE slug IS NULL;
+
+ALTER TABLE posts
+	ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS posts_account_id_slug_idx ON posts(account_id, slug);
+
+CREATE TABLE IF NOT EXISTS post_slug_redirects(
+	account_id UUID NOT NULL REFERENCES accounts(id),
+	slug VARCHAR(128) NOT NULL,
+	post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (account_id, slug)
+);
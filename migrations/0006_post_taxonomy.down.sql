@@ -0,0 +1,8 @@
+ALTER TABLE posts
+	DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE post_tags;
+
+DROP TABLE tags;
+
+DROP TABLE categories;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS categories(
+	id UUID PRIMARY KEY,
+	account_id UUID NOT NULL REFERENCES accounts(id),
+	name VARCHAR(128) NOT NULL,
+	slug VARCHAR(128) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (account_id, slug)
+);
+
+CREATE TABLE IF NOT EXISTS tags(
+	id UUID PRIMARY KEY,
+	account_id UUID NOT NULL REFERENCES accounts(id),
+	name VARCHAR(128) NOT NULL,
+	slug VARCHAR(128) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (account_id, slug)
+);
+
+CREATE TABLE IF NOT EXISTS post_tags(
+	post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_tags_tag_id_idx ON post_tags(tag_id);
+
+ALTER TABLE posts
+	ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE accounts
+	ADD COLUMN IF NOT EXISTS feed_content VARCHAR(16) NOT NULL DEFAULT 'summary'
+	CONSTRAINT accounts_feed_content_check CHECK (feed_content IN ('full', 'summary'));
@@ -0,0 +1,3 @@
+ALTER TABLE posts
+	ALTER COLUMN content_path DROP NOT NULL,
+	ALTER COLUMN content_path DROP DEFAULT;
@@ -0,0 +1,5 @@
+UPDATE posts SET content_path = '' WHERE content_path IS NULL;
+
+ALTER TABLE posts
+	ALTER COLUMN content_path SET DEFAULT '',
+	ALTER COLUMN content_path SET NOT NULL;
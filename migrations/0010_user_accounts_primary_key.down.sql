@@ -0,0 +1,2 @@
+ALTER TABLE user_accounts
+	DROP CONSTRAINT IF EXISTS user_accounts_pkey;
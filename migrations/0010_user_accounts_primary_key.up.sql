@@ -0,0 +1,10 @@
+-- memberships were never unique, so keep one row for each user and account
+DELETE FROM user_accounts a
+USING user_accounts b
+WHERE a.user_id = b.user_id
+	AND a.account_id = b.account_id
+	AND a.ctid > b.ctid;
+
+ALTER TABLE user_accounts
+	DROP CONSTRAINT IF EXISTS user_accounts_pkey,
+	ADD PRIMARY KEY (user_id, account_id);
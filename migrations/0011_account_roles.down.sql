@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS user_accounts_owner_idx;
+
+ALTER TABLE user_accounts
+	DROP COLUMN IF EXISTS role;
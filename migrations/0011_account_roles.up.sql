@@ -0,0 +1,12 @@
+ALTER TABLE user_accounts
+	ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'author'
+	CONSTRAINT user_accounts_role_check CHECK (role IN ('owner', 'admin', 'editor', 'author', 'viewer'));
+
+-- owners are members of their own accounts, and there's only ever one of them
+INSERT INTO user_accounts (user_id, account_id, role)
+SELECT owner_id, id, 'owner'
+FROM accounts
+ON CONFLICT (user_id, account_id) DO UPDATE
+SET role = 'owner';
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_accounts_owner_idx ON user_accounts(account_id) WHERE role = 'owner';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_tokens(
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(256) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP DEFAULT NULL,
+	last_used_at TIMESTAMP DEFAULT NULL,
+	revoked_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens(user_id, created_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS login_links(
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_links_user_id_idx ON login_links(user_id, created_at);
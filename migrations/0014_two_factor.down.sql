@@ -0,0 +1,8 @@
+ALTER TABLE accounts
+	DROP COLUMN IF EXISTS two_factor_role;
+
+ALTER TABLE sessions
+	DROP COLUMN IF EXISTS pending;
+
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS user_totp(
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret VARCHAR(64) NOT NULL,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	confirmed_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes(
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash CHAR(64) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	used_at TIMESTAMP DEFAULT NULL,
+	UNIQUE (user_id, code_hash)
+);
+
+-- pending sessions have checked a password but are still waiting on a second factor
+ALTER TABLE sessions
+	ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE accounts
+	ADD COLUMN IF NOT EXISTS two_factor_role VARCHAR(16) NOT NULL DEFAULT 'none'
+	CONSTRAINT accounts_two_factor_role_check CHECK (two_factor_role IN ('none', 'owner', 'admin', 'editor', 'author', 'viewer'));
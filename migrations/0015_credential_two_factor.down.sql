@@ -0,0 +1,5 @@
+ALTER TABLE api_tokens
+	DROP COLUMN IF EXISTS two_factor;
+
+ALTER TABLE sessions
+	DROP COLUMN IF EXISTS two_factor;
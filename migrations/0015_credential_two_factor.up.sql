@@ -0,0 +1,5 @@
+ALTER TABLE sessions
+	ADD COLUMN IF NOT EXISTS two_factor BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE api_tokens
+	ADD COLUMN IF NOT EXISTS two_factor BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,97 @@
+package migrations_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/eriktate/divulge/migrations"
+)
+
+func Test_Load(t *testing.T) {
+	// RUN
+	known, err := migrations.Load()
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(known) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+
+	for i, migration := range known {
+		if migration.Version != i+1 {
+			t.Fatalf("expected contiguous versions, found %d at position %d", migration.Version, i)
+		}
+
+		if migration.Checksum == "" {
+			t.Fatalf("expected checksum for migration %d", migration.Version)
+		}
+	}
+}
+
+func Test_LoadFS(t *testing.T) {
+	// SETUP
+	fsys := fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("CREATE TABLE b();")},
+		"0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0001_first.up.sql":    {Data: []byte("CREATE TABLE a();")},
+		"0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"migrations.go":        {Data: []byte("package migrations")},
+	}
+
+	// RUN
+	known, err := migrations.LoadFS(fsys)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(known) != 2 {
+		t.Fatalf("unexpected number of migrations: %d", len(known))
+	}
+
+	if known[0].Version != 1 || known[0].Name != "first" || known[1].Version != 2 {
+		t.Fatalf("unexpected migration order: %+v", known)
+	}
+
+	if known[0].Up != "CREATE TABLE a();" || known[0].Down != "DROP TABLE a;" {
+		t.Fatalf("unexpected migration contents: %+v", known[0])
+	}
+
+	if known[0].Checksum == known[1].Checksum {
+		t.Fatal("expected checksums to differ")
+	}
+}
+
+func Test_LoadFS_MissingDown(t *testing.T) {
+	// SETUP
+	fsys := fstest.MapFS{
+		"0001_first.up.sql": {Data: []byte("CREATE TABLE a();")},
+	}
+
+	// RUN
+	_, err := migrations.LoadFS(fsys)
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func Test_LoadFS_BadName(t *testing.T) {
+	// SETUP
+	fsys := fstest.MapFS{
+		"first.sql": {Data: []byte("CREATE TABLE a();")},
+	}
+
+	// RUN
+	_, err := migrations.LoadFS(fsys)
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
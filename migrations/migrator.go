@@ -0,0 +1,291 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lockKey is the advisory lock held while migrating so concurrent runs can't interleave.
+const lockKey = 8675309
+
+const createSchemaMigrationsQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations(
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(256) NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const listAppliedQuery = `
+SELECT version, name, checksum, applied_at
+FROM schema_migrations
+ORDER BY version;
+`
+
+const insertAppliedQuery = `
+INSERT INTO schema_migrations
+	(version, name, checksum)
+VALUES
+	($1, $2, $3);
+`
+
+const deleteAppliedQuery = `
+DELETE FROM schema_migrations
+WHERE version = $1;
+`
+
+// An Applied migration is one recorded in the schema_migrations table.
+type Applied struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// A Status describes a known migration and whether it has been applied.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// A Migrator applies and rolls back migrations against a database.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the given migrations, which must be sorted by version.
+func NewMigrator(db *sqlx.DB, migrations []Migration) Migrator {
+	return Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// Latest returns the highest known migration version.
+func (m Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration and returns the ones it applied.
+func (m Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down rolls back the n most recently applied migrations and returns the ones it rolled back.
+func (m Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.withLock(ctx, func(conn *sqlx.Conn, applied []Applied) error {
+		if n > len(applied) {
+			n = len(applied)
+		}
+
+		for i := len(applied) - 1; i >= len(applied)-n; i-- {
+			migration, err := m.find(applied[i].Version)
+			if err != nil {
+				return err
+			}
+
+			if err := m.rollback(ctx, conn, migration); err != nil {
+				return err
+			}
+
+			rolledBack = append(rolledBack, migration)
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// Goto applies or rolls back migrations until version is the most recently applied one. A
+// version of 0 rolls back everything.
+func (m Migrator) Goto(ctx context.Context, version int) ([]Migration, error) {
+	if version != 0 {
+		if _, err := m.find(version); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []Migration
+	err := m.withLock(ctx, func(conn *sqlx.Conn, applied []Applied) error {
+		isApplied := make(map[int]bool, len(applied))
+		for _, a := range applied {
+			isApplied[a.Version] = true
+		}
+
+		// roll back anything past the target, newest first
+		for i := len(applied) - 1; i >= 0; i-- {
+			if applied[i].Version <= version {
+				break
+			}
+
+			migration, err := m.find(applied[i].Version)
+			if err != nil {
+				return err
+			}
+
+			if err := m.rollback(ctx, conn, migration); err != nil {
+				return err
+			}
+
+			changed = append(changed, migration)
+		}
+
+		// then apply anything missing up to the target, oldest first
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+
+			if isApplied[migration.Version] {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+
+			changed = append(changed, migration)
+		}
+
+		return nil
+	})
+
+	return changed, err
+}
+
+// Status returns every known migration along with when it was applied, if ever.
+func (m Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sqlx.Conn, applied []Applied) error {
+		appliedAt := make(map[int]time.Time, len(applied))
+		for _, a := range applied {
+			appliedAt[a.Version] = a.AppliedAt
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if at, ok := appliedAt[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs fn on a single connection holding the migration lock, passing along the
+// migrations that have already been applied after verifying them against the known ones.
+func (m Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn, applied []Applied) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1);", lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1);", lockKey)
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsQuery); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var applied []Applied
+	if err := conn.SelectContext(ctx, &applied, listAppliedQuery); err != nil {
+		return fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	if err := m.verify(applied); err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// verify makes sure every applied migration is still known and unchanged.
+func (m Migrator) verify(applied []Applied) error {
+	for _, a := range applied {
+		migration, err := m.find(a.Version)
+		if err != nil {
+			return fmt.Errorf("applied migration is unknown: %w", err)
+		}
+
+		if migration.Checksum != a.Checksum {
+			return fmt.Errorf("checksum mismatch for migration %d_%s: file was modified after being applied", a.Version, a.Name)
+		}
+	}
+
+	return nil
+}
+
+func (m Migrator) find(version int) (Migration, error) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, nil
+		}
+	}
+
+	return Migration{}, fmt.Errorf("no migration with version %d", version)
+}
+
+func (m Migrator) apply(ctx context.Context, conn *sqlx.Conn, migration Migration) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, insertAppliedQuery, migration.Version, migration.Name, migration.Checksum); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (m Migrator) rollback(ctx context.Context, conn *sqlx.Conn, migration Migration) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteAppliedQuery, migration.Version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to unrecord migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
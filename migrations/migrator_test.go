@@ -0,0 +1,71 @@
+// +build integration
+
+package migrations_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eriktate/divulge/migrations"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+func Test_Migrator(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := sqlx.Connect("postgres", "host=localhost user=postgres password=password dbname=postgres sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.MustExec("DROP TABLE IF EXISTS schema_migrations, migrator_a, migrator_b;")
+
+	known, err := migrations.LoadFS(fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE migrator_a(id INTEGER);")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE migrator_a;")},
+		"0002_b.up.sql":   {Data: []byte("CREATE TABLE migrator_b(id INTEGER);")},
+		"0002_b.down.sql": {Data: []byte("DROP TABLE migrator_b;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator := migrations.NewMigrator(db, known)
+
+	// RUN
+	applied, upErr := migrator.Up(ctx)
+	reapplied, reupErr := migrator.Up(ctx)
+	rolledBack, downErr := migrator.Down(ctx, 1)
+	statuses, statusErr := migrator.Status(ctx)
+
+	modified := append([]migrations.Migration{}, known...)
+	modified[0].Checksum = "modified"
+	_, verifyErr := migrations.NewMigrator(db, modified).Status(ctx)
+
+	_, gotoErr := migrator.Goto(ctx, 0)
+
+	// ASSERT
+	for _, err := range []error{upErr, reupErr, downErr, statusErr, gotoErr} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if len(applied) != 2 || len(reapplied) != 0 {
+		t.Fatalf("unexpected applied migrations: %d then %d", len(applied), len(reapplied))
+	}
+
+	if len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Fatalf("unexpected rolled back migrations: %+v", rolledBack)
+	}
+
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+
+	if verifyErr == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+}
@@ -0,0 +1,129 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type AccountService struct {
+	SaveAccountFn    func(ctx context.Context, account divulge.Account) (uuid.UUID, error)
+	SaveAccountCount int
+
+	FetchAccountFn    func(ctx context.Context, id uuid.UUID) (divulge.Account, error)
+	FetchAccountCount int
+
+	ListAccountsFn    func(ctx context.Context) ([]divulge.Account, error)
+	ListAccountsCount int
+
+	RemoveAccountFn    func(ctx context.Context, id uuid.UUID) error
+	RemoveAccountCount int
+
+	AddAccountUserFn    func(ctx context.Context, accountID, userID uuid.UUID, role string) error
+	AddAccountUserCount int
+
+	ListAccountUsersFn    func(ctx context.Context, accountID uuid.UUID) ([]divulge.User, error)
+	ListAccountUsersCount int
+
+	RemoveAccountUserFn    func(ctx context.Context, accountID, userID uuid.UUID) error
+	RemoveAccountUserCount int
+
+	FetchMembershipFn    func(ctx context.Context, accountID, userID uuid.UUID) (divulge.Membership, error)
+	FetchMembershipCount int
+
+	ListMembershipsFn    func(ctx context.Context, accountID uuid.UUID) ([]divulge.Membership, error)
+	ListMembershipsCount int
+
+	Error error
+}
+
+func (m *AccountService) SaveAccount(ctx context.Context, account divulge.Account) (uuid.UUID, error) {
+	m.SaveAccountCount++
+
+	if m.SaveAccountFn != nil {
+		return m.SaveAccountFn(ctx, account)
+	}
+
+	return account.ID, m.Error
+}
+
+func (m *AccountService) FetchAccount(ctx context.Context, id uuid.UUID) (divulge.Account, error) {
+	m.FetchAccountCount++
+
+	if m.FetchAccountFn != nil {
+		return m.FetchAccountFn(ctx, id)
+	}
+
+	return divulge.Account{}, m.Error
+}
+
+func (m *AccountService) ListAccounts(ctx context.Context) ([]divulge.Account, error) {
+	m.ListAccountsCount++
+
+	if m.ListAccountsFn != nil {
+		return m.ListAccountsFn(ctx)
+	}
+
+	return nil, m.Error
+}
+
+func (m *AccountService) RemoveAccount(ctx context.Context, id uuid.UUID) error {
+	m.RemoveAccountCount++
+
+	if m.RemoveAccountFn != nil {
+		return m.RemoveAccountFn(ctx, id)
+	}
+
+	return m.Error
+}
+
+func (m *AccountService) AddAccountUser(ctx context.Context, accountID, userID uuid.UUID, role string) error {
+	m.AddAccountUserCount++
+
+	if m.AddAccountUserFn != nil {
+		return m.AddAccountUserFn(ctx, accountID, userID, role)
+	}
+
+	return m.Error
+}
+
+func (m *AccountService) ListAccountUsers(ctx context.Context, accountID uuid.UUID) ([]divulge.User, error) {
+	m.ListAccountUsersCount++
+
+	if m.ListAccountUsersFn != nil {
+		return m.ListAccountUsersFn(ctx, accountID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *AccountService) RemoveAccountUser(ctx context.Context, accountID, userID uuid.UUID) error {
+	m.RemoveAccountUserCount++
+
+	if m.RemoveAccountUserFn != nil {
+		return m.RemoveAccountUserFn(ctx, accountID, userID)
+	}
+
+	return m.Error
+}
+
+func (m *AccountService) FetchMembership(ctx context.Context, accountID, userID uuid.UUID) (divulge.Membership, error) {
+	m.FetchMembershipCount++
+
+	if m.FetchMembershipFn != nil {
+		return m.FetchMembershipFn(ctx, accountID, userID)
+	}
+
+	return divulge.Membership{}, m.Error
+}
+
+func (m *AccountService) ListMemberships(ctx context.Context, accountID uuid.UUID) ([]divulge.Membership, error) {
+	m.ListMembershipsCount++
+
+	if m.ListMembershipsFn != nil {
+		return m.ListMembershipsFn(ctx, accountID)
+	}
+
+	return nil, m.Error
+}
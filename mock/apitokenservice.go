@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type APITokenService struct {
+	CreateAPITokenFn    func(ctx context.Context, token divulge.APIToken) (uuid.UUID, error)
+	CreateAPITokenCount int
+
+	FetchAPITokenByHashFn    func(ctx context.Context, tokenHash string) (divulge.APIToken, error)
+	FetchAPITokenByHashCount int
+
+	ListAPITokensByUserFn    func(ctx context.Context, userID uuid.UUID) ([]divulge.APIToken, error)
+	ListAPITokensByUserCount int
+
+	RevokeAPITokenFn    func(ctx context.Context, userID, id uuid.UUID) error
+	RevokeAPITokenCount int
+
+	RevokeAPITokensByUserFn    func(ctx context.Context, userID uuid.UUID) error
+	RevokeAPITokensByUserCount int
+
+	TouchAPITokenFn    func(ctx context.Context, id uuid.UUID) error
+	TouchAPITokenCount int
+
+	Error error
+}
+
+func (m *APITokenService) CreateAPIToken(ctx context.Context, token divulge.APIToken) (uuid.UUID, error) {
+	m.CreateAPITokenCount++
+
+	if m.CreateAPITokenFn != nil {
+		return m.CreateAPITokenFn(ctx, token)
+	}
+
+	return token.ID, m.Error
+}
+
+func (m *APITokenService) FetchAPITokenByHash(ctx context.Context, tokenHash string) (divulge.APIToken, error) {
+	m.FetchAPITokenByHashCount++
+
+	if m.FetchAPITokenByHashFn != nil {
+		return m.FetchAPITokenByHashFn(ctx, tokenHash)
+	}
+
+	return divulge.APIToken{}, m.Error
+}
+
+func (m *APITokenService) ListAPITokensByUser(ctx context.Context, userID uuid.UUID) ([]divulge.APIToken, error) {
+	m.ListAPITokensByUserCount++
+
+	if m.ListAPITokensByUserFn != nil {
+		return m.ListAPITokensByUserFn(ctx, userID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *APITokenService) RevokeAPIToken(ctx context.Context, userID, id uuid.UUID) error {
+	m.RevokeAPITokenCount++
+
+	if m.RevokeAPITokenFn != nil {
+		return m.RevokeAPITokenFn(ctx, userID, id)
+	}
+
+	return m.Error
+}
+
+func (m *APITokenService) RevokeAPITokensByUser(ctx context.Context, userID uuid.UUID) error {
+	m.RevokeAPITokensByUserCount++
+
+	if m.RevokeAPITokensByUserFn != nil {
+		return m.RevokeAPITokensByUserFn(ctx, userID)
+	}
+
+	return m.Error
+}
+
+func (m *APITokenService) TouchAPIToken(ctx context.Context, id uuid.UUID) error {
+	m.TouchAPITokenCount++
+
+	if m.TouchAPITokenFn != nil {
+		return m.TouchAPITokenFn(ctx, id)
+	}
+
+	return m.Error
+}
@@ -0,0 +1,246 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type Authenticator struct {
+	SetPasswordFn    func(ctx context.Context, userID uuid.UUID, password string) error
+	SetPasswordCount int
+
+	ChangePasswordFn    func(ctx context.Context, userID uuid.UUID, current, password string) error
+	ChangePasswordCount int
+
+	LoginFn    func(ctx context.Context, email, password string) (divulge.Session, error)
+	LoginCount int
+
+	AuthenticateFn    func(ctx context.Context, token string) (divulge.User, divulge.Session, error)
+	AuthenticateCount int
+
+	LogoutFn    func(ctx context.Context, token string) error
+	LogoutCount int
+
+	ListSessionsFn    func(ctx context.Context, userID uuid.UUID) ([]divulge.Session, error)
+	ListSessionsCount int
+
+	RevokeSessionFn    func(ctx context.Context, userID, id uuid.UUID) error
+	RevokeSessionCount int
+
+	RequestLoginLinkFn    func(ctx context.Context, email string) error
+	RequestLoginLinkCount int
+
+	LoginWithLinkFn    func(ctx context.Context, token string) (divulge.Session, error)
+	LoginWithLinkCount int
+
+	VerifyLoginFn    func(ctx context.Context, token, code string) (divulge.Session, error)
+	VerifyLoginCount int
+
+	EnrollTOTPFn    func(ctx context.Context, userID uuid.UUID) (divulge.TOTPEnrollment, error)
+	EnrollTOTPCount int
+
+	ConfirmTOTPFn    func(ctx context.Context, userID uuid.UUID, code string) (divulge.Session, []string, error)
+	ConfirmTOTPCount int
+
+	DisableTOTPFn    func(ctx context.Context, userID uuid.UUID, code string) error
+	DisableTOTPCount int
+
+	RegenerateRecoveryCodesFn    func(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	RegenerateRecoveryCodesCount int
+
+	CreateAPITokenFn    func(ctx context.Context, token divulge.APIToken) (divulge.APIToken, error)
+	CreateAPITokenCount int
+
+	AuthenticateAPITokenFn    func(ctx context.Context, token string) (divulge.User, divulge.APIToken, error)
+	AuthenticateAPITokenCount int
+
+	ListAPITokensFn    func(ctx context.Context, userID uuid.UUID) ([]divulge.APIToken, error)
+	ListAPITokensCount int
+
+	RevokeAPITokenFn    func(ctx context.Context, userID, id uuid.UUID) error
+	RevokeAPITokenCount int
+
+	Error error
+}
+
+func (m *Authenticator) SetPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	m.SetPasswordCount++
+
+	if m.SetPasswordFn != nil {
+		return m.SetPasswordFn(ctx, userID, password)
+	}
+
+	return m.Error
+}
+
+func (m *Authenticator) ChangePassword(ctx context.Context, userID uuid.UUID, current, password string) error {
+	m.ChangePasswordCount++
+
+	if m.ChangePasswordFn != nil {
+		return m.ChangePasswordFn(ctx, userID, current, password)
+	}
+
+	return m.Error
+}
+
+func (m *Authenticator) Login(ctx context.Context, email, password string) (divulge.Session, error) {
+	m.LoginCount++
+
+	if m.LoginFn != nil {
+		return m.LoginFn(ctx, email, password)
+	}
+
+	return divulge.Session{}, m.Error
+}
+
+func (m *Authenticator) Authenticate(ctx context.Context, token string) (divulge.User, divulge.Session, error) {
+	m.AuthenticateCount++
+
+	if m.AuthenticateFn != nil {
+		return m.AuthenticateFn(ctx, token)
+	}
+
+	return divulge.User{}, divulge.Session{}, m.Error
+}
+
+func (m *Authenticator) Logout(ctx context.Context, token string) error {
+	m.LogoutCount++
+
+	if m.LogoutFn != nil {
+		return m.LogoutFn(ctx, token)
+	}
+
+	return m.Error
+}
+
+func (m *Authenticator) ListSessions(ctx context.Context, userID uuid.UUID) ([]divulge.Session, error) {
+	m.ListSessionsCount++
+
+	if m.ListSessionsFn != nil {
+		return m.ListSessionsFn(ctx, userID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *Authenticator) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	m.RevokeSessionCount++
+
+	if m.RevokeSessionFn != nil {
+		return m.RevokeSessionFn(ctx, userID, id)
+	}
+
+	return m.Error
+}
+
+func (m *Authenticator) RequestLoginLink(ctx context.Context, email string) error {
+	m.RequestLoginLinkCount++
+
+	if m.RequestLoginLinkFn != nil {
+		return m.RequestLoginLinkFn(ctx, email)
+	}
+
+	return m.Error
+}
+
+func (m *Authenticator) LoginWithLink(ctx context.Context, token string) (divulge.Session, error) {
+	m.LoginWithLinkCount++
+
+	if m.LoginWithLinkFn != nil {
+		return m.LoginWithLinkFn(ctx, token)
+	}
+
+	return divulge.Session{}, m.Error
+}
+
+func (m *Authenticator) VerifyLogin(ctx context.Context, token, code string) (divulge.Session, error) {
+	m.VerifyLoginCount++
+
+	if m.VerifyLoginFn != nil {
+		return m.VerifyLoginFn(ctx, token, code)
+	}
+
+	return divulge.Session{}, m.Error
+}
+
+func (m *Authenticator) EnrollTOTP(ctx context.Context, userID uuid.UUID) (divulge.TOTPEnrollment, error) {
+	m.EnrollTOTPCount++
+
+	if m.EnrollTOTPFn != nil {
+		return m.EnrollTOTPFn(ctx, userID)
+	}
+
+	return divulge.TOTPEnrollment{}, m.Error
+}
+
+func (m *Authenticator) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) (divulge.Session, []string, error) {
+	m.ConfirmTOTPCount++
+
+	if m.ConfirmTOTPFn != nil {
+		return m.ConfirmTOTPFn(ctx, userID, code)
+	}
+
+	return divulge.Session{}, nil, m.Error
+}
+
+func (m *Authenticator) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	m.DisableTOTPCount++
+
+	if m.DisableTOTPFn != nil {
+		return m.DisableTOTPFn(ctx, userID, code)
+	}
+
+	return m.Error
+}
+
+func (m *Authenticator) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	m.RegenerateRecoveryCodesCount++
+
+	if m.RegenerateRecoveryCodesFn != nil {
+		return m.RegenerateRecoveryCodesFn(ctx, userID, code)
+	}
+
+	return nil, m.Error
+}
+
+func (m *Authenticator) CreateAPIToken(ctx context.Context, token divulge.APIToken) (divulge.APIToken, error) {
+	m.CreateAPITokenCount++
+
+	if m.CreateAPITokenFn != nil {
+		return m.CreateAPITokenFn(ctx, token)
+	}
+
+	return token, m.Error
+}
+
+func (m *Authenticator) AuthenticateAPIToken(ctx context.Context, token string) (divulge.User, divulge.APIToken, error) {
+	m.AuthenticateAPITokenCount++
+
+	if m.AuthenticateAPITokenFn != nil {
+		return m.AuthenticateAPITokenFn(ctx, token)
+	}
+
+	return divulge.User{}, divulge.APIToken{}, m.Error
+}
+
+func (m *Authenticator) ListAPITokens(ctx context.Context, userID uuid.UUID) ([]divulge.APIToken, error) {
+	m.ListAPITokensCount++
+
+	if m.ListAPITokensFn != nil {
+		return m.ListAPITokensFn(ctx, userID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *Authenticator) RevokeAPIToken(ctx context.Context, userID, id uuid.UUID) error {
+	m.RevokeAPITokenCount++
+
+	if m.RevokeAPITokenFn != nil {
+		return m.RevokeAPITokenFn(ctx, userID, id)
+	}
+
+	return m.Error
+}
@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+)
+
+type ContentIndex struct {
+	ListContentRefsFn    func(ctx context.Context) ([]divulge.ContentRef, error)
+	ListContentRefsCount int
+
+	Error error
+}
+
+func (m *ContentIndex) ListContentRefs(ctx context.Context) ([]divulge.ContentRef, error) {
+	m.ListContentRefsCount++
+
+	if m.ListContentRefsFn != nil {
+		return m.ListContentRefsFn(ctx)
+	}
+
+	return nil, m.Error
+}
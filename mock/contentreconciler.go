@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+)
+
+type ContentReconciler struct {
+	ReconcileContentFn    func(ctx context.Context) (divulge.ContentReport, error)
+	ReconcileContentCount int
+
+	Error error
+}
+
+func (m *ContentReconciler) ReconcileContent(ctx context.Context) (divulge.ContentReport, error) {
+	m.ReconcileContentCount++
+
+	if m.ReconcileContentFn != nil {
+		return m.ReconcileContentFn(ctx)
+	}
+
+	return divulge.ContentReport{}, m.Error
+}
@@ -0,0 +1,51 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type CredentialService struct {
+	SaveCredentialsFn    func(ctx context.Context, creds divulge.Credentials) error
+	SaveCredentialsCount int
+
+	FetchCredentialsFn    func(ctx context.Context, userID uuid.UUID) (divulge.Credentials, error)
+	FetchCredentialsCount int
+
+	FetchCredentialsByEmailFn    func(ctx context.Context, email string) (divulge.Credentials, error)
+	FetchCredentialsByEmailCount int
+
+	Error error
+}
+
+func (m *CredentialService) SaveCredentials(ctx context.Context, creds divulge.Credentials) error {
+	m.SaveCredentialsCount++
+
+	if m.SaveCredentialsFn != nil {
+		return m.SaveCredentialsFn(ctx, creds)
+	}
+
+	return m.Error
+}
+
+func (m *CredentialService) FetchCredentials(ctx context.Context, userID uuid.UUID) (divulge.Credentials, error) {
+	m.FetchCredentialsCount++
+
+	if m.FetchCredentialsFn != nil {
+		return m.FetchCredentialsFn(ctx, userID)
+	}
+
+	return divulge.Credentials{}, m.Error
+}
+
+func (m *CredentialService) FetchCredentialsByEmail(ctx context.Context, email string) (divulge.Credentials, error) {
+	m.FetchCredentialsByEmailCount++
+
+	if m.FetchCredentialsByEmailFn != nil {
+		return m.FetchCredentialsByEmailFn(ctx, email)
+	}
+
+	return divulge.Credentials{}, m.Error
+}
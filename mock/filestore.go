@@ -1,17 +1,66 @@
 package mock
 
-import "context"
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/eriktate/divulge"
+)
 
 type FileStore struct {
+	CreateFn    func(ctx context.Context, key string, r io.Reader, size int64) error
+	CreateCount int
+
+	OpenFn    func(ctx context.Context, key string) (io.ReadCloser, error)
+	OpenCount int
+
 	WriteFn    func(ctx context.Context, key string, data []byte) error
 	WriteCount int
 
 	ReadFn    func(ctx context.Context, key string) ([]byte, error)
 	ReadCount int
 
+	DeleteFn    func(ctx context.Context, key string) error
+	DeleteCount int
+
+	ExistsFn    func(ctx context.Context, key string) (bool, error)
+	ExistsCount int
+
+	StatFn    func(ctx context.Context, key string) (divulge.FileInfo, error)
+	StatCount int
+
+	ListFn    func(ctx context.Context, prefix string) ([]divulge.FileInfo, error)
+	ListCount int
+
 	Error error
 }
 
+func (m *FileStore) Create(ctx context.Context, key string, r io.Reader, size int64) error {
+	m.CreateCount++
+
+	if m.CreateFn != nil {
+		return m.CreateFn(ctx, key, r, size)
+	}
+
+	return m.Error
+}
+
+func (m *FileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.OpenCount++
+
+	if m.OpenFn != nil {
+		return m.OpenFn(ctx, key)
+	}
+
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
+
 func (m *FileStore) Write(ctx context.Context, key string, data []byte) error {
 	m.WriteCount++
 
@@ -31,3 +80,43 @@ func (m *FileStore) Read(ctx context.Context, key string) ([]byte, error) {
 
 	return nil, m.Error
 }
+
+func (m *FileStore) Delete(ctx context.Context, key string) error {
+	m.DeleteCount++
+
+	if m.DeleteFn != nil {
+		return m.DeleteFn(ctx, key)
+	}
+
+	return m.Error
+}
+
+func (m *FileStore) Exists(ctx context.Context, key string) (bool, error) {
+	m.ExistsCount++
+
+	if m.ExistsFn != nil {
+		return m.ExistsFn(ctx, key)
+	}
+
+	return false, m.Error
+}
+
+func (m *FileStore) Stat(ctx context.Context, key string) (divulge.FileInfo, error) {
+	m.StatCount++
+
+	if m.StatFn != nil {
+		return m.StatFn(ctx, key)
+	}
+
+	return divulge.FileInfo{}, m.Error
+}
+
+func (m *FileStore) List(ctx context.Context, prefix string) ([]divulge.FileInfo, error) {
+	m.ListCount++
+
+	if m.ListFn != nil {
+		return m.ListFn(ctx, prefix)
+	}
+
+	return nil, m.Error
+}
@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type LoginLinkService struct {
+	CreateLoginLinkFn    func(ctx context.Context, link divulge.LoginLink) (uuid.UUID, error)
+	CreateLoginLinkCount int
+
+	ConsumeLoginLinkFn    func(ctx context.Context, tokenHash string) (divulge.LoginLink, error)
+	ConsumeLoginLinkCount int
+
+	Error error
+}
+
+func (m *LoginLinkService) CreateLoginLink(ctx context.Context, link divulge.LoginLink) (uuid.UUID, error) {
+	m.CreateLoginLinkCount++
+
+	if m.CreateLoginLinkFn != nil {
+		return m.CreateLoginLinkFn(ctx, link)
+	}
+
+	return link.ID, m.Error
+}
+
+func (m *LoginLinkService) ConsumeLoginLink(ctx context.Context, tokenHash string) (divulge.LoginLink, error) {
+	m.ConsumeLoginLinkCount++
+
+	if m.ConsumeLoginLinkFn != nil {
+		return m.ConsumeLoginLinkFn(ctx, tokenHash)
+	}
+
+	return divulge.LoginLink{}, m.Error
+}
@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+)
+
+type Mailer struct {
+	SendFn    func(ctx context.Context, msg divulge.Message) error
+	SendCount int
+
+	Error error
+}
+
+func (m *Mailer) Send(ctx context.Context, msg divulge.Message) error {
+	m.SendCount++
+
+	if m.SendFn != nil {
+		return m.SendFn(ctx, msg)
+	}
+
+	return m.Error
+}
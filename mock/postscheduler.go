@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type PostScheduler struct {
+	PublishDuePostsFn    func(ctx context.Context, limit int) ([]uuid.UUID, error)
+	PublishDuePostsCount int
+
+	Error error
+}
+
+func (m *PostScheduler) PublishDuePosts(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	m.PublishDuePostsCount++
+
+	if m.PublishDuePostsFn != nil {
+		return m.PublishDuePostsFn(ctx, limit)
+	}
+
+	return nil, m.Error
+}
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/eriktate/divulge"
 	"github.com/google/uuid"
@@ -17,15 +18,33 @@ type PostService struct {
 	RedactPostFn    func(ctx context.Context, id uuid.UUID) error
 	RedactPostCount int
 
+	SchedulePostFn    func(ctx context.Context, id uuid.UUID, at time.Time) error
+	SchedulePostCount int
+
+	UnschedulePostFn    func(ctx context.Context, id uuid.UUID) error
+	UnschedulePostCount int
+
 	FetchPostFn    func(ctx context.Context, id uuid.UUID) (divulge.Post, error)
 	FetchPostCount int
 
+	FetchPostBySlugFn    func(ctx context.Context, accountID uuid.UUID, slug string) (divulge.Post, error)
+	FetchPostBySlugCount int
+
 	ListPostsByAccountFn    func(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error)
 	ListPostsByAccountCount int
 
 	RemovePostFn    func(ctx context.Context, id uuid.UUID) error
 	RemovePostCount int
 
+	ListTrashByAccountFn    func(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error)
+	ListTrashByAccountCount int
+
+	RestorePostFn    func(ctx context.Context, id uuid.UUID) error
+	RestorePostCount int
+
+	PurgePostFn    func(ctx context.Context, id uuid.UUID) error
+	PurgePostCount int
+
 	Error error
 }
 
@@ -59,6 +78,26 @@ func (m *PostService) RedactPost(ctx context.Context, id uuid.UUID) error {
 	return m.Error
 }
 
+func (m *PostService) SchedulePost(ctx context.Context, id uuid.UUID, at time.Time) error {
+	m.SchedulePostCount++
+
+	if m.SchedulePostFn != nil {
+		return m.SchedulePostFn(ctx, id, at)
+	}
+
+	return m.Error
+}
+
+func (m *PostService) UnschedulePost(ctx context.Context, id uuid.UUID) error {
+	m.UnschedulePostCount++
+
+	if m.UnschedulePostFn != nil {
+		return m.UnschedulePostFn(ctx, id)
+	}
+
+	return m.Error
+}
+
 func (m *PostService) FetchPost(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
 	m.FetchPostCount++
 
@@ -69,6 +108,16 @@ func (m *PostService) FetchPost(ctx context.Context, id uuid.UUID) (divulge.Post
 	return divulge.Post{}, m.Error
 }
 
+func (m *PostService) FetchPostBySlug(ctx context.Context, accountID uuid.UUID, slug string) (divulge.Post, error) {
+	m.FetchPostBySlugCount++
+
+	if m.FetchPostBySlugFn != nil {
+		return m.FetchPostBySlugFn(ctx, accountID, slug)
+	}
+
+	return divulge.Post{}, m.Error
+}
+
 func (m *PostService) ListPostsByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
 	m.ListPostsByAccountCount++
 
@@ -88,3 +137,33 @@ func (m *PostService) RemovePost(ctx context.Context, id uuid.UUID) error {
 
 	return m.Error
 }
+
+func (m *PostService) ListTrashByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+	m.ListTrashByAccountCount++
+
+	if m.ListTrashByAccountFn != nil {
+		return m.ListTrashByAccountFn(ctx, accountID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *PostService) RestorePost(ctx context.Context, id uuid.UUID) error {
+	m.RestorePostCount++
+
+	if m.RestorePostFn != nil {
+		return m.RestorePostFn(ctx, id)
+	}
+
+	return m.Error
+}
+
+func (m *PostService) PurgePost(ctx context.Context, id uuid.UUID) error {
+	m.PurgePostCount++
+
+	if m.PurgePostFn != nil {
+		return m.PurgePostFn(ctx, id)
+	}
+
+	return m.Error
+}
@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type RevisionHistory struct {
+	FetchRevisionFn    func(ctx context.Context, id uuid.UUID) (divulge.Revision, error)
+	FetchRevisionCount int
+
+	ListRevisionsByPostFn    func(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error)
+	ListRevisionsByPostCount int
+
+	DiffRevisionsFn    func(ctx context.Context, fromID, toID uuid.UUID) ([]divulge.DiffLine, error)
+	DiffRevisionsCount int
+
+	RestoreRevisionFn    func(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+	RestoreRevisionCount int
+
+	Error error
+}
+
+func (m *RevisionHistory) FetchRevision(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+	m.FetchRevisionCount++
+
+	if m.FetchRevisionFn != nil {
+		return m.FetchRevisionFn(ctx, id)
+	}
+
+	return divulge.Revision{}, m.Error
+}
+
+func (m *RevisionHistory) ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error) {
+	m.ListRevisionsByPostCount++
+
+	if m.ListRevisionsByPostFn != nil {
+		return m.ListRevisionsByPostFn(ctx, postID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *RevisionHistory) DiffRevisions(ctx context.Context, fromID, toID uuid.UUID) ([]divulge.DiffLine, error) {
+	m.DiffRevisionsCount++
+
+	if m.DiffRevisionsFn != nil {
+		return m.DiffRevisionsFn(ctx, fromID, toID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *RevisionHistory) RestoreRevision(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	m.RestoreRevisionCount++
+
+	if m.RestoreRevisionFn != nil {
+		return m.RestoreRevisionFn(ctx, id)
+	}
+
+	return id, m.Error
+}
@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type RevisionService struct {
+	CreateRevisionFn    func(ctx context.Context, revision divulge.Revision) (uuid.UUID, error)
+	CreateRevisionCount int
+
+	SavePostRevisionFn    func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error)
+	SavePostRevisionCount int
+
+	FetchRevisionFn    func(ctx context.Context, id uuid.UUID) (divulge.Revision, error)
+	FetchRevisionCount int
+
+	ListRevisionsByPostFn    func(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error)
+	ListRevisionsByPostCount int
+
+	Error error
+}
+
+func (m *RevisionService) CreateRevision(ctx context.Context, revision divulge.Revision) (uuid.UUID, error) {
+	m.CreateRevisionCount++
+
+	if m.CreateRevisionFn != nil {
+		return m.CreateRevisionFn(ctx, revision)
+	}
+
+	return revision.ID, m.Error
+}
+
+func (m *RevisionService) SavePostRevision(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+	m.SavePostRevisionCount++
+
+	if m.SavePostRevisionFn != nil {
+		return m.SavePostRevisionFn(ctx, post, revision)
+	}
+
+	return post.ID, m.Error
+}
+
+func (m *RevisionService) FetchRevision(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+	m.FetchRevisionCount++
+
+	if m.FetchRevisionFn != nil {
+		return m.FetchRevisionFn(ctx, id)
+	}
+
+	return divulge.Revision{}, m.Error
+}
+
+func (m *RevisionService) ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error) {
+	m.ListRevisionsByPostCount++
+
+	if m.ListRevisionsByPostFn != nil {
+		return m.ListRevisionsByPostFn(ctx, postID)
+	}
+
+	return nil, m.Error
+}
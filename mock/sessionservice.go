@@ -0,0 +1,77 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type SessionService struct {
+	CreateSessionFn    func(ctx context.Context, session divulge.Session) (uuid.UUID, error)
+	CreateSessionCount int
+
+	FetchSessionByTokenHashFn    func(ctx context.Context, tokenHash string) (divulge.Session, error)
+	FetchSessionByTokenHashCount int
+
+	ListSessionsByUserFn    func(ctx context.Context, userID uuid.UUID) ([]divulge.Session, error)
+	ListSessionsByUserCount int
+
+	RevokeSessionFn    func(ctx context.Context, userID, id uuid.UUID) error
+	RevokeSessionCount int
+
+	RevokeSessionsByUserFn    func(ctx context.Context, userID uuid.UUID) error
+	RevokeSessionsByUserCount int
+
+	Error error
+}
+
+func (m *SessionService) CreateSession(ctx context.Context, session divulge.Session) (uuid.UUID, error) {
+	m.CreateSessionCount++
+
+	if m.CreateSessionFn != nil {
+		return m.CreateSessionFn(ctx, session)
+	}
+
+	return session.ID, m.Error
+}
+
+func (m *SessionService) FetchSessionByTokenHash(ctx context.Context, tokenHash string) (divulge.Session, error) {
+	m.FetchSessionByTokenHashCount++
+
+	if m.FetchSessionByTokenHashFn != nil {
+		return m.FetchSessionByTokenHashFn(ctx, tokenHash)
+	}
+
+	return divulge.Session{}, m.Error
+}
+
+func (m *SessionService) ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]divulge.Session, error) {
+	m.ListSessionsByUserCount++
+
+	if m.ListSessionsByUserFn != nil {
+		return m.ListSessionsByUserFn(ctx, userID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *SessionService) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	m.RevokeSessionCount++
+
+	if m.RevokeSessionFn != nil {
+		return m.RevokeSessionFn(ctx, userID, id)
+	}
+
+	return m.Error
+}
+
+func (m *SessionService) RevokeSessionsByUser(ctx context.Context, userID uuid.UUID) error {
+	m.RevokeSessionsByUserCount++
+
+	if m.RevokeSessionsByUserFn != nil {
+		return m.RevokeSessionsByUserFn(ctx, userID)
+	}
+
+	return m.Error
+}
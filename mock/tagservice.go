@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type TagService struct {
+	ListTagsFn    func(ctx context.Context, accountID uuid.UUID) ([]divulge.Tag, error)
+	ListTagsCount int
+
+	ListPostsByTagFn    func(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error)
+	ListPostsByTagCount int
+
+	RenameTagFn    func(ctx context.Context, accountID uuid.UUID, tag, name string) error
+	RenameTagCount int
+
+	MergeTagsFn    func(ctx context.Context, accountID uuid.UUID, from, into string) error
+	MergeTagsCount int
+
+	Error error
+}
+
+func (m *TagService) ListTags(ctx context.Context, accountID uuid.UUID) ([]divulge.Tag, error) {
+	m.ListTagsCount++
+
+	if m.ListTagsFn != nil {
+		return m.ListTagsFn(ctx, accountID)
+	}
+
+	return nil, m.Error
+}
+
+func (m *TagService) ListPostsByTag(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error) {
+	m.ListPostsByTagCount++
+
+	if m.ListPostsByTagFn != nil {
+		return m.ListPostsByTagFn(ctx, accountID, tag)
+	}
+
+	return nil, m.Error
+}
+
+func (m *TagService) RenameTag(ctx context.Context, accountID uuid.UUID, tag, name string) error {
+	m.RenameTagCount++
+
+	if m.RenameTagFn != nil {
+		return m.RenameTagFn(ctx, accountID, tag, name)
+	}
+
+	return m.Error
+}
+
+func (m *TagService) MergeTags(ctx context.Context, accountID uuid.UUID, from, into string) error {
+	m.MergeTagsCount++
+
+	if m.MergeTagsFn != nil {
+		return m.MergeTagsFn(ctx, accountID, from, into)
+	}
+
+	return m.Error
+}
@@ -0,0 +1,25 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+type TempSweeper struct {
+	SweepTempFn    func(ctx context.Context, cutoff time.Time) ([]divulge.FileInfo, error)
+	SweepTempCount int
+
+	Error error
+}
+
+func (m *TempSweeper) SweepTemp(ctx context.Context, cutoff time.Time) ([]divulge.FileInfo, error) {
+	m.SweepTempCount++
+
+	if m.SweepTempFn != nil {
+		return m.SweepTempFn(ctx, cutoff)
+	}
+
+	return nil, m.Error
+}
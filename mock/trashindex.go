@@ -0,0 +1,25 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TrashIndex struct {
+	ListTrashedBeforeFn    func(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error)
+	ListTrashedBeforeCount int
+
+	Error error
+}
+
+func (m *TrashIndex) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error) {
+	m.ListTrashedBeforeCount++
+
+	if m.ListTrashedBeforeFn != nil {
+		return m.ListTrashedBeforeFn(ctx, before, limit)
+	}
+
+	return nil, m.Error
+}
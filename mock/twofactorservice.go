@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type TwoFactorService struct {
+	SaveTOTPFn    func(ctx context.Context, totp divulge.TOTP) error
+	SaveTOTPCount int
+
+	FetchTOTPFn    func(ctx context.Context, userID uuid.UUID) (divulge.TOTP, error)
+	FetchTOTPCount int
+
+	UseTOTPStepFn    func(ctx context.Context, userID uuid.UUID, step int64) error
+	UseTOTPStepCount int
+
+	RemoveTOTPFn    func(ctx context.Context, userID uuid.UUID) error
+	RemoveTOTPCount int
+
+	SaveRecoveryCodesFn    func(ctx context.Context, userID uuid.UUID, hashes []string) error
+	SaveRecoveryCodesCount int
+
+	UseRecoveryCodeFn    func(ctx context.Context, userID uuid.UUID, hash string) error
+	UseRecoveryCodeCount int
+
+	Error error
+}
+
+func (m *TwoFactorService) SaveTOTP(ctx context.Context, totp divulge.TOTP) error {
+	m.SaveTOTPCount++
+
+	if m.SaveTOTPFn != nil {
+		return m.SaveTOTPFn(ctx, totp)
+	}
+
+	return m.Error
+}
+
+func (m *TwoFactorService) FetchTOTP(ctx context.Context, userID uuid.UUID) (divulge.TOTP, error) {
+	m.FetchTOTPCount++
+
+	if m.FetchTOTPFn != nil {
+		return m.FetchTOTPFn(ctx, userID)
+	}
+
+	return divulge.TOTP{}, m.Error
+}
+
+func (m *TwoFactorService) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	m.UseTOTPStepCount++
+
+	if m.UseTOTPStepFn != nil {
+		return m.UseTOTPStepFn(ctx, userID, step)
+	}
+
+	return m.Error
+}
+
+func (m *TwoFactorService) RemoveTOTP(ctx context.Context, userID uuid.UUID) error {
+	m.RemoveTOTPCount++
+
+	if m.RemoveTOTPFn != nil {
+		return m.RemoveTOTPFn(ctx, userID)
+	}
+
+	return m.Error
+}
+
+func (m *TwoFactorService) SaveRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	m.SaveRecoveryCodesCount++
+
+	if m.SaveRecoveryCodesFn != nil {
+		return m.SaveRecoveryCodesFn(ctx, userID, hashes)
+	}
+
+	return m.Error
+}
+
+func (m *TwoFactorService) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error {
+	m.UseRecoveryCodeCount++
+
+	if m.UseRecoveryCodeFn != nil {
+		return m.UseRecoveryCodeFn(ctx, userID, hash)
+	}
+
+	return m.Error
+}
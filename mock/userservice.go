@@ -0,0 +1,77 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+type UserService struct {
+	SaveUserFn    func(ctx context.Context, user divulge.User) (uuid.UUID, error)
+	SaveUserCount int
+
+	FetchUserFn    func(ctx context.Context, id uuid.UUID) (divulge.User, error)
+	FetchUserCount int
+
+	FetchUserByEmailFn    func(ctx context.Context, email string) (divulge.User, error)
+	FetchUserByEmailCount int
+
+	ListUsersFn    func(ctx context.Context) ([]divulge.User, error)
+	ListUsersCount int
+
+	RemoveUserFn    func(ctx context.Context, id uuid.UUID) error
+	RemoveUserCount int
+
+	Error error
+}
+
+func (m *UserService) SaveUser(ctx context.Context, user divulge.User) (uuid.UUID, error) {
+	m.SaveUserCount++
+
+	if m.SaveUserFn != nil {
+		return m.SaveUserFn(ctx, user)
+	}
+
+	return user.ID, m.Error
+}
+
+func (m *UserService) FetchUser(ctx context.Context, id uuid.UUID) (divulge.User, error) {
+	m.FetchUserCount++
+
+	if m.FetchUserFn != nil {
+		return m.FetchUserFn(ctx, id)
+	}
+
+	return divulge.User{}, m.Error
+}
+
+func (m *UserService) FetchUserByEmail(ctx context.Context, email string) (divulge.User, error) {
+	m.FetchUserByEmailCount++
+
+	if m.FetchUserByEmailFn != nil {
+		return m.FetchUserByEmailFn(ctx, email)
+	}
+
+	return divulge.User{}, m.Error
+}
+
+func (m *UserService) ListUsers(ctx context.Context) ([]divulge.User, error) {
+	m.ListUsersCount++
+
+	if m.ListUsersFn != nil {
+		return m.ListUsersFn(ctx)
+	}
+
+	return nil, m.Error
+}
+
+func (m *UserService) RemoveUser(ctx context.Context, id uuid.UUID) error {
+	m.RemoveUserCount++
+
+	if m.RemoveUserFn != nil {
+		return m.RemoveUserFn(ctx, id)
+	}
+
+	return m.Error
+}
@@ -11,29 +11,43 @@ import (
 
 const insertAccountQuery = `
 INSERT INTO accounts
-	(id, name, owner_id)
+	(id, name, owner_id, feed_content, two_factor_role)
 VALUES
-	(:id, :name, :owner_id);
+	(
+		:id,
+		:name,
+		:owner_id,
+		COALESCE(NULLIF(:feed_content, ''), 'summary'),
+		COALESCE(NULLIF(:two_factor_role, ''), 'none')
+	);
 `
 
 const updateAccountQuery = `
 UPDATE accounts
 SET
 	name = :name,
-	owner_id = :owner_id
-WHERE id = :id;
+	owner_id = :owner_id,
+	feed_content = COALESCE(NULLIF(:feed_content, ''), feed_content),
+	two_factor_role = COALESCE(NULLIF(:two_factor_role, ''), two_factor_role),
+	updated_at = CURRENT_TIMESTAMP
+WHERE
+	id = :id
+	AND deleted_at IS NULL;
 `
 
 const fetchAccountQuery = `
 SELECT *
 FROM accounts
 WHERE
-	id = $1;
+	id = $1
+	AND deleted_at IS NULL;
 `
 
 const listAccountsQuery = `
 SELECT *
-FROM accounts;
+FROM accounts
+WHERE
+	deleted_at IS NULL;
 `
 
 const removeAccountQuery = `
@@ -45,6 +59,84 @@ WHERE
 	AND deleted_at IS NULL;
 `
 
+// addAccountUserQuery never touches the owner's membership, which follows the account's owner_id.
+const addAccountUserQuery = `
+INSERT INTO user_accounts
+	(user_id, account_id, role)
+VALUES
+	($1, $2, COALESCE(NULLIF($3, ''), 'author'))
+ON CONFLICT (user_id, account_id) DO UPDATE
+SET
+	role = EXCLUDED.role
+WHERE
+	user_accounts.role <> 'owner';
+`
+
+// demoteOwnerQuery and promoteOwnerQuery move the owner role to an account's current owner. The
+// previous owner stays on as an admin.
+const demoteOwnerQuery = `
+UPDATE user_accounts
+SET
+	role = 'admin'
+WHERE
+	account_id = $1
+	AND user_id <> $2
+	AND role = 'owner';
+`
+
+const promoteOwnerQuery = `
+INSERT INTO user_accounts
+	(user_id, account_id, role)
+VALUES
+	($2, $1, 'owner')
+ON CONFLICT (user_id, account_id) DO UPDATE
+SET
+	role = 'owner';
+`
+
+const fetchMembershipQuery = `
+SELECT ua.user_id, ua.account_id, ua.role
+FROM user_accounts ua
+JOIN accounts a ON a.id = ua.account_id
+JOIN users u ON u.id = ua.user_id
+WHERE
+	ua.account_id = $1
+	AND ua.user_id = $2
+	AND a.deleted_at IS NULL
+	AND u.deleted_at IS NULL;
+`
+
+const listMembershipsQuery = `
+SELECT ua.user_id, ua.account_id, ua.role
+FROM user_accounts ua
+JOIN users u ON u.id = ua.user_id
+WHERE
+	ua.account_id = $1
+	AND u.deleted_at IS NULL
+ORDER BY u.name, u.id;
+`
+
+const listAccountUsersQuery = selectUsersQuery + `
+WHERE
+	u.deleted_at IS NULL
+	AND u.id IN (
+		SELECT user_id
+		FROM user_accounts
+		WHERE account_id = $1
+	)
+GROUP BY u.id;
+`
+
+// removeAccountUserQuery leaves owners alone, they can only stop being members by transferring
+// ownership.
+const removeAccountUserQuery = `
+DELETE FROM user_accounts
+WHERE
+	user_id = $1
+	AND account_id = $2
+	AND role <> 'owner';
+`
+
 func (db DB) SaveAccount(ctx context.Context, account divulge.Account) (uuid.UUID, error) {
 	// are we inserting?
 	query := updateAccountQuery
@@ -58,9 +150,26 @@ func (db DB) SaveAccount(ctx context.Context, account divulge.Account) (uuid.UUI
 		return account.ID, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := sqlx.NamedExecContext(ctx, tx, query, &account); err != nil {
+	res, err := sqlx.NamedExecContext(ctx, tx, query, &account)
+	if err != nil {
 		tx.Rollback()
-		return account.ID, fmt.Errorf("failed to execute query: %w", err)
+		return account.ID, classify("account", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("account", res); err != nil {
+		tx.Rollback()
+		return account.ID, err
+	}
+
+	// the old owner has to be demoted first since an account can only have one
+	if _, err := tx.ExecContext(ctx, demoteOwnerQuery, account.ID, account.OwnerID); err != nil {
+		tx.Rollback()
+		return account.ID, fmt.Errorf("failed to demote previous owner: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, promoteOwnerQuery, account.ID, account.OwnerID); err != nil {
+		tx.Rollback()
+		return account.ID, classify("membership", fmt.Errorf("failed to promote owner: %w", err))
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -73,7 +182,7 @@ func (db DB) SaveAccount(ctx context.Context, account divulge.Account) (uuid.UUI
 func (db DB) FetchAccount(ctx context.Context, id uuid.UUID) (divulge.Account, error) {
 	var account divulge.Account
 	if err := db.db.GetContext(ctx, &account, fetchAccountQuery, id); err != nil {
-		return account, fmt.Errorf("failed to select: %w", err)
+		return account, classify("account", fmt.Errorf("failed to select: %w", err))
 	}
 
 	return account, nil
@@ -94,9 +203,15 @@ func (db DB) RemoveAccount(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := tx.ExecContext(ctx, removeAccountQuery, id); err != nil {
+	res, err := tx.ExecContext(ctx, removeAccountQuery, id)
+	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to execute query: %w", err)
+		return classify("account", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("account", res); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -105,3 +220,47 @@ func (db DB) RemoveAccount(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+func (db DB) AddAccountUser(ctx context.Context, accountID, userID uuid.UUID, role string) error {
+	if _, err := db.db.ExecContext(ctx, addAccountUserQuery, userID, accountID, role); err != nil {
+		return classify("membership", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return nil
+}
+
+func (db DB) ListAccountUsers(ctx context.Context, accountID uuid.UUID) ([]divulge.User, error) {
+	var records []userRecord
+	if err := db.db.SelectContext(ctx, &records, listAccountUsersQuery, accountID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return toUsers(records)
+}
+
+func (db DB) RemoveAccountUser(ctx context.Context, accountID, userID uuid.UUID) error {
+	res, err := db.db.ExecContext(ctx, removeAccountUserQuery, userID, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return requireRows("membership", res)
+}
+
+func (db DB) FetchMembership(ctx context.Context, accountID, userID uuid.UUID) (divulge.Membership, error) {
+	var membership divulge.Membership
+	if err := db.db.GetContext(ctx, &membership, fetchMembershipQuery, accountID, userID); err != nil {
+		return membership, classify("membership", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return membership, nil
+}
+
+func (db DB) ListMemberships(ctx context.Context, accountID uuid.UUID) ([]divulge.Membership, error) {
+	var memberships []divulge.Membership
+	if err := db.db.SelectContext(ctx, &memberships, listMembershipsQuery, accountID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return memberships, nil
+}
@@ -4,6 +4,7 @@ package pg_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -77,7 +78,16 @@ func Test_Accounts(t *testing.T) {
 		t.Fatalf("unexpected error removing account2: %s", err)
 	}
 
+	removed := account1
+	removed.ID = id1
+	removed.Name = "Renamed"
+	_, updateRemovedErr := db.SaveAccount(ctx, removed)
+
 	// ASSERT
+	if !errors.Is(updateRemovedErr, divulge.ErrNotFound) {
+		t.Fatalf("expected removed accounts not to be updated, got %v", updateRemovedErr)
+	}
+
 	if divulge.IsEmpty(id1) {
 		t.Fatal("unexpected empty id1")
 	}
@@ -98,4 +108,151 @@ func Test_Accounts(t *testing.T) {
 	if fetchedAccount1.OwnerID != accountUserID {
 		t.Fatal("expected fetchedAccount1.ID to equal accountUserID")
 	}
+
+	if fetchedAccount1.TwoFactorRole != divulge.TwoFactorNone {
+		t.Fatalf("expected accounts not to require two-factor authentication, got %q", fetchedAccount1.TwoFactorRole)
+	}
+}
+
+func Test_AccountUsers(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ownerID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Owner",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memberID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Member",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := db.SaveAccount(ctx, divulge.Account{Name: "Members", OwnerID: ownerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := db.AddAccountUser(ctx, accountID, memberID, ""); err != nil {
+		t.Fatalf("unexpected error adding member: %s", err)
+	}
+
+	// adding twice changes the member's role
+	if err := db.AddAccountUser(ctx, accountID, memberID, divulge.RoleEditor); err != nil {
+		t.Fatalf("unexpected error re-adding member: %s", err)
+	}
+
+	// the owner's role can't be changed this way
+	if err := db.AddAccountUser(ctx, accountID, ownerID, divulge.RoleViewer); err != nil {
+		t.Fatalf("unexpected error re-adding owner: %s", err)
+	}
+
+	members, err := db.ListAccountUsers(ctx, accountID)
+	if err != nil {
+		t.Fatalf("unexpected error listing members: %s", err)
+	}
+
+	memberships, err := db.ListMemberships(ctx, accountID)
+	if err != nil {
+		t.Fatalf("unexpected error listing memberships: %s", err)
+	}
+
+	membership, err := db.FetchMembership(ctx, accountID, memberID)
+	if err != nil {
+		t.Fatalf("unexpected error fetching membership: %s", err)
+	}
+
+	if err := db.RemoveAccountUser(ctx, accountID, memberID); err != nil {
+		t.Fatalf("unexpected error removing member: %s", err)
+	}
+
+	remaining, err := db.ListAccountUsers(ctx, accountID)
+	if err != nil {
+		t.Fatalf("unexpected error listing remaining members: %s", err)
+	}
+
+	// ASSERT
+	if len(members) != 2 {
+		t.Fatalf("unexpected members: %+v", members)
+	}
+
+	roles := make(map[uuid.UUID]string)
+	for _, m := range memberships {
+		roles[m.UserID] = m.Role
+	}
+
+	if roles[ownerID] != divulge.RoleOwner || roles[memberID] != divulge.RoleEditor {
+		t.Fatalf("unexpected memberships: %+v", memberships)
+	}
+
+	if membership.Role != divulge.RoleEditor {
+		t.Fatalf("unexpected membership: %+v", membership)
+	}
+
+	if len(remaining) != 1 || remaining[0].ID != ownerID {
+		t.Fatalf("expected only the owner to remain, got %+v", remaining)
+	}
+}
+
+func Test_TransferAccount(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ownerID, err := db.SaveUser(ctx, divulge.User{Name: "Owner", Email: fmt.Sprintf("%s@test.com", uuid.New().String())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nextID, err := db.SaveUser(ctx, divulge.User{Name: "Next Owner", Email: fmt.Sprintf("%s@test.com", uuid.New().String())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := divulge.Account{Name: "Transferred", OwnerID: ownerID}
+	account.ID, err = db.SaveAccount(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	account.OwnerID = nextID
+	if _, err := db.SaveAccount(ctx, account); err != nil {
+		t.Fatalf("unexpected error transferring account: %s", err)
+	}
+
+	previous, err := db.FetchMembership(ctx, account.ID, ownerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	next, err := db.FetchMembership(ctx, account.ID, nextID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	addOwnerErr := db.AddAccountUser(ctx, account.ID, ownerID, divulge.RoleOwner)
+
+	// ASSERT
+	if previous.Role != divulge.RoleAdmin || next.Role != divulge.RoleOwner {
+		t.Fatalf("unexpected roles after transfer: %s, %s", previous.Role, next.Role)
+	}
+
+	if !errors.Is(addOwnerErr, divulge.ErrConflict) {
+		t.Fatalf("expected a second owner to conflict, got %v", addOwnerErr)
+	}
 }
@@ -0,0 +1,149 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// insertAPITokenQuery converts the expiry into the session's time zone so it compares correctly
+// against CURRENT_TIMESTAMP.
+const insertAPITokenQuery = `
+INSERT INTO api_tokens
+	(id, user_id, name, prefix, token_hash, scopes, two_factor, expires_at)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8::timestamptz::timestamp);
+`
+
+const fetchAPITokenByHashQuery = `
+SELECT *
+FROM api_tokens
+WHERE
+	token_hash = $1
+	AND revoked_at IS NULL
+	AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);
+`
+
+const listAPITokensByUserQuery = `
+SELECT *
+FROM api_tokens
+WHERE
+	user_id = $1
+	AND revoked_at IS NULL
+ORDER BY created_at DESC;
+`
+
+const revokeAPITokenQuery = `
+UPDATE api_tokens
+SET
+	revoked_at = CURRENT_TIMESTAMP
+WHERE
+	user_id = $1
+	AND id = $2
+	AND revoked_at IS NULL;
+`
+
+const revokeAPITokensByUserQuery = `
+UPDATE api_tokens
+SET
+	revoked_at = CURRENT_TIMESTAMP
+WHERE
+	user_id = $1
+	AND revoked_at IS NULL;
+`
+
+// touchAPITokenQuery only records use once a minute so busy tokens don't write on every request.
+const touchAPITokenQuery = `
+UPDATE api_tokens
+SET
+	last_used_at = CURRENT_TIMESTAMP
+WHERE
+	id = $1
+	AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');
+`
+
+// An apiTokenRecord is a divulge.APIToken as it's selected from the database.
+type apiTokenRecord struct {
+	divulge.APIToken
+	ScopeList pq.StringArray `db:"scopes"`
+}
+
+func (r apiTokenRecord) toAPIToken() divulge.APIToken {
+	token := r.APIToken
+	token.Scopes = []string(r.ScopeList)
+	if token.Scopes == nil {
+		token.Scopes = []string{}
+	}
+
+	return token
+}
+
+func (db DB) CreateAPIToken(ctx context.Context, token divulge.APIToken) (uuid.UUID, error) {
+	if divulge.IsEmpty(token.ID) {
+		token.ID = uuid.New()
+	}
+
+	scopes := pq.StringArray(token.Scopes)
+	if scopes == nil {
+		scopes = pq.StringArray{}
+	}
+
+	if _, err := db.db.ExecContext(ctx, insertAPITokenQuery, token.ID, token.UserID, token.Name, token.Prefix, token.TokenHash, scopes, token.TwoFactor, token.ExpiresAt); err != nil {
+		return token.ID, classify("api token", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return token.ID, nil
+}
+
+func (db DB) FetchAPITokenByHash(ctx context.Context, tokenHash string) (divulge.APIToken, error) {
+	var record apiTokenRecord
+	if err := db.db.GetContext(ctx, &record, fetchAPITokenByHashQuery, tokenHash); err != nil {
+		return record.APIToken, classify("api token", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return record.toAPIToken(), nil
+}
+
+func (db DB) ListAPITokensByUser(ctx context.Context, userID uuid.UUID) ([]divulge.APIToken, error) {
+	var records []apiTokenRecord
+	if err := db.db.SelectContext(ctx, &records, listAPITokensByUserQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	tokens := make([]divulge.APIToken, len(records))
+	for i, record := range records {
+		tokens[i] = record.toAPIToken()
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes one of a user's tokens.
+func (db DB) RevokeAPIToken(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := db.db.ExecContext(ctx, revokeAPITokenQuery, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return requireRows("api token", res)
+}
+
+// RevokeAPITokensByUser revokes every one of a user's tokens.
+func (db DB) RevokeAPITokensByUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := db.db.ExecContext(ctx, revokeAPITokensByUserQuery, userID); err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (db DB) TouchAPIToken(ctx context.Context, id uuid.UUID) error {
+	if _, err := db.db.ExecContext(ctx, touchAPITokenQuery, id); err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
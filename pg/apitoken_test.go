@@ -0,0 +1,114 @@
+// +build integration
+
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/pg"
+	"github.com/google/uuid"
+)
+
+func Test_APITokens(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := db.SaveUser(ctx, divulge.User{Name: "Token User", Email: fmt.Sprintf("%s@test.com", uuid.New().String())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	active := divulge.APIToken{
+		UserID:    userID,
+		Name:      "deploys",
+		Prefix:    "dvg_abcdefgh",
+		TokenHash: fmt.Sprintf("%064s", uuid.New().String()[:8]),
+		Scopes:    []string{divulge.ScopePostsRead, divulge.ScopePostsWrite},
+		TwoFactor: true,
+	}
+	expired := divulge.APIToken{
+		UserID:    userID,
+		Name:      "old",
+		Prefix:    "dvg_ijklmnop",
+		TokenHash: fmt.Sprintf("%064s", uuid.New().String()[:8]),
+		Scopes:    []string{divulge.ScopePostsRead},
+		ExpiresAt: &past,
+	}
+
+	// RUN
+	activeID, err := db.CreateAPIToken(ctx, active)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := db.CreateAPIToken(ctx, expired); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := db.TouchAPIToken(ctx, activeID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fetched, fetchErr := db.FetchAPITokenByHash(ctx, active.TokenHash)
+	_, expiredErr := db.FetchAPITokenByHash(ctx, expired.TokenHash)
+	listed, err := db.ListAPITokensByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := db.RevokeAPIToken(ctx, userID, activeID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, revokedErr := db.FetchAPITokenByHash(ctx, active.TokenHash)
+	againErr := db.RevokeAPIToken(ctx, userID, activeID)
+	if err := db.RevokeAPITokensByUser(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	remaining, err := db.ListAPITokensByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if fetchErr != nil || fetched.ID != activeID || fetched.Prefix != active.Prefix {
+		t.Fatalf("unexpected active token: %+v, %v", fetched, fetchErr)
+	}
+
+	if !fetched.TwoFactor {
+		t.Fatal("expected the token to be marked as verified with two-factor authentication")
+	}
+
+	if len(fetched.Scopes) != 2 || fetched.Scopes[1] != divulge.ScopePostsWrite {
+		t.Fatalf("unexpected scopes: %v", fetched.Scopes)
+	}
+
+	if fetched.LastUsedAt == nil {
+		t.Fatal("expected the token's last use to be recorded")
+	}
+
+	if len(listed) != 2 {
+		t.Fatalf("expected expired tokens to still be listed, got %+v", listed)
+	}
+
+	if len(remaining) != 0 {
+		t.Fatalf("expected every token to be revoked, got %+v", remaining)
+	}
+
+	for _, err := range []error{expiredErr, revokedErr, againErr} {
+		if !errors.Is(err, divulge.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	}
+}
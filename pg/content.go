@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+)
+
+const listContentRefsQuery = `
+SELECT
+	id AS post_id,
+	NULL::uuid AS revision_id,
+	content_path
+FROM posts
+UNION ALL
+SELECT
+	post_id,
+	id AS revision_id,
+	content_path
+FROM post_revisions;
+`
+
+// ListContentRefs lists the content referenced by every post and revision.
+func (db DB) ListContentRefs(ctx context.Context) ([]divulge.ContentRef, error) {
+	var refs []divulge.ContentRef
+	if err := db.db.SelectContext(ctx, &refs, listContentRefsQuery); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return refs, nil
+}
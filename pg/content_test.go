@@ -0,0 +1,79 @@
+// +build integration
+
+package pg_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/pg"
+	"github.com/google/uuid"
+)
+
+func Test_ListContentRefs(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Content Author",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := db.SaveAccount(ctx, divulge.Account{Name: "Content Account", OwnerID: authorID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postKey := "posts/" + uuid.New().String()
+	postID, err := db.SavePost(ctx, divulge.Post{
+		AccountID:   accountID,
+		AuthorID:    authorID,
+		Title:       "Referenced Content",
+		ContentPath: postKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisionKey := "revisions/" + uuid.New().String()
+	revisionID, err := db.CreateRevision(ctx, divulge.Revision{PostID: postID, Title: "Referenced Content", ContentPath: revisionKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// updating without a content path keeps the current one
+	if _, err := db.SavePost(ctx, divulge.Post{ID: postID, Title: "Still Referenced"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	refs, err := db.ListContentRefs(ctx)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var foundPost, foundRevision bool
+	for _, ref := range refs {
+		switch ref.Key {
+		case postKey:
+			foundPost = ref.PostID == postID && ref.RevisionID == nil
+		case revisionKey:
+			foundRevision = ref.PostID == postID && ref.RevisionID != nil && *ref.RevisionID == revisionID
+		}
+	}
+
+	if !foundPost || !foundRevision {
+		t.Fatalf("expected references to the post and revision content: %+v", refs)
+	}
+}
@@ -0,0 +1,113 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eriktate/divulge"
+	"github.com/lib/pq"
+)
+
+// postgres error codes we know how to classify.
+const (
+	codeNotNullViolation    = "23502"
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+	codeInvalidText         = "22P02"
+	codeStringTooLong       = "22001"
+)
+
+// constraintMessages holds friendlier messages for specific constraint violations.
+var constraintMessages = map[string]string{
+	"users_email_key":                "email is already in use",
+	"accounts_owner_id_fkey":         "owner does not exist",
+	"user_accounts_user_id_fkey":     "user does not exist",
+	"user_accounts_account_id_fkey":  "account does not exist",
+	"posts_author_id_fkey":           "author does not exist",
+	"posts_account_id_fkey":          "account does not exist",
+	"post_revisions_post_id_fkey":    "post does not exist",
+	"posts_account_id_slug_idx":      "slug is already in use",
+	"tags_account_id_slug_key":       "a tag with that name already exists",
+	"categories_account_id_fkey":     "account does not exist",
+	"tags_account_id_fkey":           "account does not exist",
+	"accounts_feed_content_check":    "feedContent must be either full or summary",
+	"user_credentials_user_id_fkey":  "user does not exist",
+	"sessions_user_id_fkey":          "user does not exist",
+	"user_accounts_role_check":       "role must be one of owner, admin, editor, author or viewer",
+	"user_accounts_owner_idx":        "account already has an owner",
+	"api_tokens_user_id_fkey":        "user does not exist",
+	"login_links_user_id_fkey":       "user does not exist",
+	"user_totp_user_id_fkey":         "user does not exist",
+	"recovery_codes_user_id_fkey":    "user does not exist",
+	"accounts_two_factor_role_check": "twoFactorRole must be one of none, owner, admin, editor, author or viewer",
+}
+
+// classify maps database errors onto divulge's sentinel errors. Errors it doesn't recognize are
+// returned untouched.
+func classify(entity string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return divulge.NotFoundError(fmt.Sprintf("%s not found", entity), err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	message, ok := constraintMessages[pqErr.Constraint]
+	switch string(pqErr.Code) {
+	case codeUniqueViolation:
+		if !ok {
+			message = fmt.Sprintf("%s already exists", entity)
+		}
+
+		return divulge.ConflictError(message, err)
+	case codeForeignKeyViolation:
+		// deleting something that's still referenced is a conflict, referencing something that
+		// doesn't exist is a validation failure
+		if strings.HasPrefix(pqErr.Message, "update or delete") {
+			return divulge.ConflictError(fmt.Sprintf("%s is still referenced", entity), err)
+		}
+
+		if !ok {
+			message = fmt.Sprintf("%s references something that does not exist", entity)
+		}
+
+		return divulge.ValidationError(message, err)
+	case codeNotNullViolation, codeCheckViolation, codeInvalidText:
+		if !ok {
+			message = fmt.Sprintf("invalid %s", entity)
+			if pqErr.Column != "" {
+				message = fmt.Sprintf("invalid %s: %s", entity, pqErr.Column)
+			}
+		}
+
+		return divulge.ValidationError(message, err)
+	case codeStringTooLong:
+		// postgres doesn't say which column was too long
+		return divulge.ValidationError(fmt.Sprintf("invalid %s: a value is too long", entity), err)
+	}
+
+	return err
+}
+
+// requireRows returns an ErrNotFound if a statement didn't affect any rows.
+func requireRows(entity string, res sql.Result) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to count affected rows: %w", err)
+	}
+
+	if count == 0 {
+		return divulge.NotFoundError(fmt.Sprintf("%s not found", entity), nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/lib/pq"
+)
+
+func Test_Classify(t *testing.T) {
+	// SETUP
+	cases := []struct {
+		name    string
+		err     error
+		kind    error
+		message string
+	}{
+		{
+			name:    "no rows",
+			err:     fmt.Errorf("failed to select: %w", sql.ErrNoRows),
+			kind:    divulge.ErrNotFound,
+			message: "post not found",
+		},
+		{
+			name:    "duplicate email",
+			err:     &pq.Error{Code: codeUniqueViolation, Constraint: "users_email_key"},
+			kind:    divulge.ErrConflict,
+			message: "email is already in use",
+		},
+		{
+			name:    "missing reference",
+			err:     &pq.Error{Code: codeForeignKeyViolation, Constraint: "posts_author_id_fkey", Message: "insert or update on table \"posts\" violates foreign key constraint"},
+			kind:    divulge.ErrValidation,
+			message: "author does not exist",
+		},
+		{
+			name:    "still referenced",
+			err:     &pq.Error{Code: codeForeignKeyViolation, Constraint: "posts_author_id_fkey", Message: "update or delete on table \"users\" violates foreign key constraint"},
+			kind:    divulge.ErrConflict,
+			message: "post is still referenced",
+		},
+		{
+			name:    "value too long",
+			err:     &pq.Error{Code: codeStringTooLong, Message: "value too long for type character varying(256)"},
+			kind:    divulge.ErrValidation,
+			message: "invalid post: a value is too long",
+		},
+	}
+
+	for _, c := range cases {
+		// RUN
+		err := classify("post", c.err)
+
+		// ASSERT
+		if !errors.Is(err, c.kind) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.kind, err)
+		}
+
+		if !errors.Is(err, c.err) {
+			t.Fatalf("%s: expected original error to be preserved", c.name)
+		}
+
+		message, _ := divulge.ErrorMessage(err)
+		if message != c.message {
+			t.Fatalf("%s: unexpected message: %s", c.name, message)
+		}
+	}
+}
+
+func Test_Classify_Unknown(t *testing.T) {
+	// SETUP
+	original := errors.New("connection refused")
+
+	// RUN
+	err := classify("post", original)
+
+	// ASSERT
+	if err != original {
+		t.Fatalf("expected unknown errors to pass through, got %v", err)
+	}
+}
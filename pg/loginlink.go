@@ -0,0 +1,54 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// insertLoginLinkQuery converts the expiry into the session's time zone so it compares correctly
+// against CURRENT_TIMESTAMP.
+const insertLoginLinkQuery = `
+INSERT INTO login_links
+	(id, user_id, token_hash, expires_at)
+VALUES
+	($1, $2, $3, $4::timestamptz::timestamp);
+`
+
+// consumeLoginLinkQuery marks a link as used and returns it in one statement, so two requests
+// racing to use the same link can't both succeed.
+const consumeLoginLinkQuery = `
+UPDATE login_links
+SET
+	used_at = CURRENT_TIMESTAMP
+WHERE
+	token_hash = $1
+	AND used_at IS NULL
+	AND expires_at > CURRENT_TIMESTAMP
+RETURNING *;
+`
+
+func (db DB) CreateLoginLink(ctx context.Context, link divulge.LoginLink) (uuid.UUID, error) {
+	if divulge.IsEmpty(link.ID) {
+		link.ID = uuid.New()
+	}
+
+	if _, err := db.db.ExecContext(ctx, insertLoginLinkQuery, link.ID, link.UserID, link.TokenHash, link.ExpiresAt); err != nil {
+		return link.ID, classify("login link", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return link.ID, nil
+}
+
+// ConsumeLoginLink marks a LoginLink as used. Links that don't exist, have expired or have
+// already been used are not found.
+func (db DB) ConsumeLoginLink(ctx context.Context, tokenHash string) (divulge.LoginLink, error) {
+	var link divulge.LoginLink
+	if err := db.db.GetContext(ctx, &link, consumeLoginLinkQuery, tokenHash); err != nil {
+		return link, classify("login link", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return link, nil
+}
@@ -0,0 +1,73 @@
+// +build integration
+
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/pg"
+	"github.com/google/uuid"
+)
+
+func Test_LoginLinks(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := fmt.Sprintf("%s@test.com", uuid.New().String())
+	userID, err := db.SaveUser(ctx, divulge.User{Name: "Link User", Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := divulge.LoginLink{UserID: userID, TokenHash: fmt.Sprintf("%064s", uuid.New().String()[:8]), ExpiresAt: time.Now().Add(time.Minute)}
+	expired := divulge.LoginLink{UserID: userID, TokenHash: fmt.Sprintf("%064s", uuid.New().String()[:8]), ExpiresAt: time.Now().Add(-time.Minute)}
+
+	// RUN
+	user, err := db.FetchUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	activeID, err := db.CreateLoginLink(ctx, active)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := db.CreateLoginLink(ctx, expired); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	consumed, consumeErr := db.ConsumeLoginLink(ctx, active.TokenHash)
+	_, againErr := db.ConsumeLoginLink(ctx, active.TokenHash)
+	_, expiredErr := db.ConsumeLoginLink(ctx, expired.TokenHash)
+
+	if err := db.RemoveUser(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, removedErr := db.FetchUserByEmail(ctx, email)
+
+	// ASSERT
+	if user.ID != userID {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+
+	if consumeErr != nil || consumed.ID != activeID || consumed.UserID != userID || consumed.UsedAt == nil {
+		t.Fatalf("unexpected consumed link: %+v, %v", consumed, consumeErr)
+	}
+
+	for _, err := range []error{againErr, expiredErr, removedErr} {
+		if !errors.Is(err, divulge.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	}
+}
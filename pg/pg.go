@@ -2,6 +2,7 @@ package pg
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -12,14 +13,71 @@ type DB struct {
 	db *sqlx.DB
 }
 
+// A Config describes how to connect to postgres.
+type Config struct {
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	Name         string `json:"name"`
+	User         string `json:"user"`
+	Password     string `json:"password"`
+	SSLMode      string `json:"sslMode"`
+	MaxOpenConns int    `json:"maxOpenConns"`
+	MaxIdleConns int    `json:"maxIdleConns"`
+}
+
+// DSN returns the connection string described by the Config. Empty fields are left out so
+// libpq defaults apply.
+func (c Config) DSN() string {
+	var parts []string
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+
+		// quote values so passwords with spaces or quotes survive
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `'`, `\'`)
+		parts = append(parts, fmt.Sprintf("%s='%s'", key, value))
+	}
+
+	add("host", c.Host)
+	if c.Port != 0 {
+		add("port", fmt.Sprint(c.Port))
+	}
+	add("dbname", c.Name)
+	add("user", c.User)
+	add("password", c.Password)
+	add("sslmode", c.SSLMode)
+
+	return strings.Join(parts, " ")
+}
+
 // New creates a new pg.DB capable of working with divulge data.
 func New(host, user, password string) (DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=divulge sslmode=disable", host, user, password)
-	db, err := sqlx.Connect("postgres", dsn)
+	return Connect(Config{
+		Host:     host,
+		Name:     "divulge",
+		User:     user,
+		Password: password,
+		SSLMode:  "disable",
+	})
+}
+
+// Connect creates a new pg.DB using the given Config.
+func Connect(cfg Config) (DB, error) {
+	db, err := sqlx.Connect("postgres", cfg.DSN())
 	if err != nil {
 		return DB{}, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+
 	return DB{
 		db: db,
 	}, nil
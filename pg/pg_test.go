@@ -0,0 +1,28 @@
+package pg_test
+
+import (
+	"testing"
+
+	"github.com/eriktate/divulge/pg"
+)
+
+func Test_ConfigDSN(t *testing.T) {
+	// SETUP
+	cfg := pg.Config{
+		Host:     "localhost",
+		Port:     5433,
+		Name:     "divulge",
+		User:     "postgres",
+		Password: "it's secret",
+		SSLMode:  "require",
+	}
+
+	// RUN
+	dsn := cfg.DSN()
+
+	// ASSERT
+	expected := `host='localhost' port='5433' dbname='divulge' user='postgres' password='it\'s secret' sslmode='require'`
+	if dsn != expected {
+		t.Fatalf("unexpected dsn: %s", dsn)
+	}
+}
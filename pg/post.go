@@ -3,24 +3,30 @@ package pg
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/eriktate/divulge"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 const insertPostQuery = `
 INSERT INTO posts
-	(id, author_id, account_id, title, summary)
+	(id, author_id, account_id, category_id, title, slug, summary, content_path)
 VALUES
-	(:id, :author_id, :account_id, :title, :summary);
+	(:id, :author_id, :account_id, :category_id, :title, :slug, :summary, :content_path);
 `
 
 const updatePostQuery = `
 UPDATE posts
 SET
+	category_id = :category_id,
 	title = :title,
-	summary = :summary
+	slug = :slug,
+	summary = :summary,
+	content_path = COALESCE(NULLIF(:content_path, ''), content_path),
+	updated_at = CURRENT_TIMESTAMP
 WHERE
 	id = :id
 	AND deleted_at IS NULL;
@@ -29,9 +35,11 @@ WHERE
 const publishPostQuery = `
 UPDATE posts
 SET
-	published_at = CURRENT_TIMESTAMP
+	published_at = CURRENT_TIMESTAMP,
+	scheduled_at = NULL
 WHERE
-	id = $1;
+	id = $1
+	AND deleted_at IS NULL;
 `
 
 const redactPostQuery = `
@@ -39,43 +47,164 @@ UPDATE posts
 SET
 	published_at = NULL
 WHERE
-	id = $1;
+	id = $1
+	AND deleted_at IS NULL;
 `
 
-const fetchPostQuery = `
-SELECT *
-FROM posts
+// schedulePostQuery converts the scheduled time into the session's time zone so it compares
+// correctly against CURRENT_TIMESTAMP.
+const schedulePostQuery = `
+UPDATE posts
+SET
+	scheduled_at = $2::timestamptz::timestamp
+WHERE
+	id = $1
+	AND deleted_at IS NULL;
+`
+
+const unschedulePostQuery = `
+UPDATE posts
+SET
+	scheduled_at = NULL
+WHERE
+	id = $1
+	AND deleted_at IS NULL;
+`
+
+// publishDuePostsQuery publishes a batch of posts whose scheduled time has passed. Rows locked by
+// another instance are skipped rather than waited on, so concurrent schedulers never block each
+// other or publish the same post twice.
+const publishDuePostsQuery = `
+UPDATE posts
+SET
+	published_at = scheduled_at,
+	scheduled_at = NULL
+WHERE id IN (
+	SELECT id
+	FROM posts
+	WHERE
+		scheduled_at <= CURRENT_TIMESTAMP
+		AND deleted_at IS NULL
+	ORDER BY scheduled_at
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id;
+`
+
+// selectPostsQuery selects posts along with the name of their category and the names of their
+// tags. It must be completed with a WHERE clause and a GROUP BY on p.id and c.id.
+const selectPostsQuery = `
+SELECT
+	p.*,
+	COALESCE(c.name, '') AS category_name,
+	COALESCE(
+		array_agg(t.name ORDER BY t.name) FILTER (WHERE t.id IS NOT NULL),
+		'{}'
+	) AS tag_names
+FROM posts p
+LEFT JOIN categories c ON c.id = p.category_id
+LEFT JOIN post_tags pt ON pt.post_id = p.id
+LEFT JOIN tags t ON t.id = pt.tag_id
+`
+
+// fetchPostQuery includes trashed posts so they can be restored and purged.
+const fetchPostQuery = selectPostsQuery + `
 WHERE
-	id = $1;
+	p.id = $1
+GROUP BY p.id, c.id;
 `
 
-const listPostsByAccountQuery = `
-SELECT *
+const listPostsByAccountQuery = selectPostsQuery + `
+WHERE
+	p.account_id = $1
+	AND p.deleted_at IS NULL
+GROUP BY p.id, c.id;
+`
+
+const listTrashByAccountQuery = selectPostsQuery + `
+WHERE
+	p.account_id = $1
+	AND p.deleted_at IS NOT NULL
+GROUP BY p.id, c.id
+ORDER BY p.deleted_at DESC;
+`
+
+const listTrashedBeforeQuery = `
+SELECT id
 FROM posts
 WHERE
-	account_id = $1;
+	deleted_at < $1::timestamptz::timestamp
+ORDER BY deleted_at
+LIMIT $2;
 `
 
 const removePostQuery = `
+UPDATE posts
+SET
+	deleted_at = CURRENT_TIMESTAMP
+WHERE
+	id = $1
+	AND deleted_at IS NULL;
+`
+
+const restorePostQuery = `
+UPDATE posts
+SET
+	deleted_at = NULL
+WHERE
+	id = $1
+	AND deleted_at IS NOT NULL;
+`
+
+const purgePostQuery = `
 DELETE FROM posts
-WHERE id = $1;
+WHERE
+	id = $1
+	AND deleted_at IS NOT NULL;
 `
 
-func (db DB) SavePost(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
-	query := updatePostQuery
-	if divulge.IsEmpty(post.ID) {
-		post.ID = uuid.New()
-		query = insertPostQuery
+// A postRecord is a divulge.Post as it's stored in the database.
+type postRecord struct {
+	divulge.Post
+	CategoryID   *uuid.UUID     `db:"category_id"`
+	CategoryName string         `db:"category_name"`
+	TagNames     pq.StringArray `db:"tag_names"`
+}
+
+func (r postRecord) toPost() divulge.Post {
+	post := r.Post
+	post.Category = r.CategoryName
+	post.Tags = []string(r.TagNames)
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+
+	return post
+}
+
+func toPosts(records []postRecord) []divulge.Post {
+	posts := make([]divulge.Post, len(records))
+	for i, record := range records {
+		posts[i] = record.toPost()
 	}
 
+	return posts
+}
+
+// SavePost inserts or updates a post. The post's category is set to post.Category, creating it
+// if needed, and an empty Category clears it. Tags are synced to match post.Tags, except when
+// updating with nil Tags which leaves them untouched. Updating with an empty ContentPath keeps
+// the current one.
+func (db DB) SavePost(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
 	tx, err := db.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return post.ID, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := sqlx.NamedExecContext(ctx, tx, query, &post); err != nil {
+	if post.ID, err = savePost(ctx, tx, post); err != nil {
 		tx.Rollback()
-		return post.ID, fmt.Errorf("failed to execute query: %w", err)
+		return post.ID, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -85,15 +214,71 @@ func (db DB) SavePost(ctx context.Context, post divulge.Post) (uuid.UUID, error)
 	return post.ID, nil
 }
 
+func savePost(ctx context.Context, tx *sqlx.Tx, post divulge.Post) (uuid.UUID, error) {
+	query := updatePostQuery
+	inserting := divulge.IsEmpty(post.ID)
+	if inserting {
+		post.ID = uuid.New()
+		query = insertPostQuery
+	}
+
+	var current postSlug
+	if !inserting {
+		if err := tx.GetContext(ctx, &current, lockPostSlugQuery, post.ID); err != nil {
+			return post.ID, classify("post", fmt.Errorf("failed to lock post: %w", err))
+		}
+
+		// slugs are unique per account, and updates can't move a post between accounts
+		post.AccountID = current.AccountID
+	}
+
+	var err error
+	if post.Slug, err = uniqueSlug(ctx, tx, post); err != nil {
+		return post.ID, err
+	}
+
+	record := postRecord{Post: post}
+	if record.CategoryID, err = ensureCategory(ctx, tx, post.AccountID, post.Category); err != nil {
+		return post.ID, err
+	}
+
+	res, err := sqlx.NamedExecContext(ctx, tx, query, &record)
+	if err != nil {
+		return post.ID, classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("post", res); err != nil {
+		return post.ID, err
+	}
+
+	if err := redirectSlug(ctx, tx, post, current.Slug); err != nil {
+		return post.ID, err
+	}
+
+	if inserting || post.Tags != nil {
+		if err := syncPostTags(ctx, tx, post); err != nil {
+			return post.ID, err
+		}
+	}
+
+	return post.ID, nil
+}
+
 func (db DB) PublishPost(ctx context.Context, id uuid.UUID) error {
 	tx, err := db.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := tx.ExecContext(ctx, publishPostQuery, id); err != nil {
+	res, err := tx.ExecContext(ctx, publishPostQuery, id)
+	if err != nil {
+		tx.Rollback()
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("post", res); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to execute query: %w", err)
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -109,9 +294,15 @@ func (db DB) RedactPost(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := tx.ExecContext(ctx, redactPostQuery, id); err != nil {
+	res, err := tx.ExecContext(ctx, redactPostQuery, id)
+	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to execute query: %w", err)
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("post", res); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -121,28 +312,102 @@ func (db DB) RedactPost(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (db DB) SchedulePost(ctx context.Context, id uuid.UUID, at time.Time) error {
+	res, err := db.db.ExecContext(ctx, schedulePostQuery, id, at)
+	if err != nil {
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return requireRows("post", res)
+}
+
+func (db DB) UnschedulePost(ctx context.Context, id uuid.UUID) error {
+	res, err := db.db.ExecContext(ctx, unschedulePostQuery, id)
+	if err != nil {
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return requireRows("post", res)
+}
+
+// PublishDuePosts publishes up to limit posts whose scheduled time has passed and returns their
+// IDs. It's safe to call from multiple instances at once.
+func (db DB) PublishDuePosts(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := db.db.SelectContext(ctx, &ids, publishDuePostsQuery, limit); err != nil {
+		return nil, fmt.Errorf("failed to publish due posts: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (db DB) FetchPost(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
-	var post divulge.Post
-	if err := db.db.GetContext(ctx, &post, fetchUserQuery, id); err != nil {
-		return post, fmt.Errorf("failed to select: %w", err)
+	var record postRecord
+	if err := db.db.GetContext(ctx, &record, fetchPostQuery, id); err != nil {
+		return record.Post, classify("post", fmt.Errorf("failed to select: %w", err))
 	}
 
-	return post, nil
+	return record.toPost(), nil
 }
 
 func (db DB) ListPostsByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
-	var posts []divulge.Post
-	if err := db.db.SelectContext(ctx, &posts, listPostsByAccountQuery, accountID); err != nil {
-		return posts, fmt.Errorf("failed to select: %w", err)
+	var records []postRecord
+	if err := db.db.SelectContext(ctx, &records, listPostsByAccountQuery, accountID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
 	}
 
-	return posts, nil
+	return toPosts(records), nil
 }
 
+// RemovePost moves a post to the trash. Trashed posts keep their slug, tags and revisions so
+// they can be restored.
 func (db DB) RemovePost(ctx context.Context, id uuid.UUID) error {
-	if _, err := db.db.ExecContext(ctx, removePostQuery, id); err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+	res, err := db.db.ExecContext(ctx, removePostQuery, id)
+	if err != nil {
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
 	}
 
-	return nil
+	return requireRows("post", res)
+}
+
+// ListTrashByAccount lists an account's trashed posts, most recently trashed first.
+func (db DB) ListTrashByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+	var records []postRecord
+	if err := db.db.SelectContext(ctx, &records, listTrashByAccountQuery, accountID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return toPosts(records), nil
+}
+
+// RestorePost takes a post back out of the trash.
+func (db DB) RestorePost(ctx context.Context, id uuid.UUID) error {
+	res, err := db.db.ExecContext(ctx, restorePostQuery, id)
+	if err != nil {
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return requireRows("post", res)
+}
+
+// PurgePost permanently deletes a trashed post along with its revisions. Posts must be trashed
+// before they can be purged.
+func (db DB) PurgePost(ctx context.Context, id uuid.UUID) error {
+	res, err := db.db.ExecContext(ctx, purgePostQuery, id)
+	if err != nil {
+		return classify("post", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return requireRows("post", res)
+}
+
+// ListTrashedBefore returns the IDs of up to limit posts trashed before the given time, oldest
+// first.
+func (db DB) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := db.db.SelectContext(ctx, &ids, listTrashedBeforeQuery, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return ids, nil
 }
@@ -0,0 +1,365 @@
+// +build integration
+
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/pg"
+	"github.com/google/uuid"
+)
+
+func Test_PostSlugs(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Slug Author",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := db.SaveAccount(ctx, divulge.Account{Name: "Slug Account", OwnerID: authorID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := divulge.Post{
+		AccountID: accountID,
+		AuthorID:  authorID,
+		Title:     "Crème Brûlée Recipes",
+	}
+
+	// RUN
+	id1, err := db.SavePost(ctx, post)
+	if err != nil {
+		t.Fatalf("unexpected error creating post 1: %s", err)
+	}
+
+	id2, err := db.SavePost(ctx, post)
+	if err != nil {
+		t.Fatalf("unexpected error creating post 2: %s", err)
+	}
+
+	first, err := db.FetchPost(ctx, id1)
+	if err != nil {
+		t.Fatalf("unexpected error fetching post 1: %s", err)
+	}
+
+	second, err := db.FetchPost(ctx, id2)
+	if err != nil {
+		t.Fatalf("unexpected error fetching post 2: %s", err)
+	}
+
+	first.Title = "Better Recipes"
+	first.Slug = ""
+	if _, err := db.SavePost(ctx, first); err != nil {
+		t.Fatalf("unexpected error renaming post 1: %s", err)
+	}
+
+	current, err := db.FetchPostBySlug(ctx, accountID, "better-recipes")
+	if err != nil {
+		t.Fatalf("unexpected error fetching by new slug: %s", err)
+	}
+
+	redirected, err := db.FetchPostBySlug(ctx, accountID, "creme-brulee-recipes")
+	if err != nil {
+		t.Fatalf("unexpected error fetching by old slug: %s", err)
+	}
+
+	_, missingErr := db.FetchPostBySlug(ctx, accountID, "never-used")
+
+	// ASSERT
+	if first.Slug != "creme-brulee-recipes" {
+		t.Fatalf("unexpected slug for post 1: %s", first.Slug)
+	}
+
+	if second.Slug != "creme-brulee-recipes-2" {
+		t.Fatalf("unexpected slug for post 2: %s", second.Slug)
+	}
+
+	if current.ID != id1 || current.Slug != "better-recipes" {
+		t.Fatalf("unexpected post for new slug: %+v", current)
+	}
+
+	if redirected.ID != id1 || redirected.Slug != "better-recipes" {
+		t.Fatalf("expected old slug to redirect to post 1: %+v", redirected)
+	}
+
+	if !errors.Is(missingErr, divulge.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", missingErr)
+	}
+}
+
+func Test_PostTags(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Tag Author",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := db.SaveAccount(ctx, divulge.Account{Name: "Tag Account", OwnerID: authorID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	id1, err := db.SavePost(ctx, divulge.Post{
+		AccountID: accountID,
+		AuthorID:  authorID,
+		Title:     "Tagged One",
+		Category:  "Recipes",
+		Tags:      []string{"Go", "Golang"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating post 1: %s", err)
+	}
+
+	id2, err := db.SavePost(ctx, divulge.Post{
+		AccountID: accountID,
+		AuthorID:  authorID,
+		Title:     "Tagged Two",
+		Tags:      []string{"Golang"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating post 2: %s", err)
+	}
+
+	if err := db.MergeTags(ctx, accountID, "golang", "go"); err != nil {
+		t.Fatalf("unexpected error merging tags: %s", err)
+	}
+
+	if err := db.RenameTag(ctx, accountID, "go", "Go Lang"); err != nil {
+		t.Fatalf("unexpected error renaming tag: %s", err)
+	}
+
+	tags, err := db.ListTags(ctx, accountID)
+	if err != nil {
+		t.Fatalf("unexpected error listing tags: %s", err)
+	}
+
+	tagged, err := db.ListPostsByTag(ctx, accountID, "go-lang")
+	if err != nil {
+		t.Fatalf("unexpected error listing posts by tag: %s", err)
+	}
+
+	untouched, err := db.FetchPost(ctx, id1)
+	if err != nil {
+		t.Fatalf("unexpected error fetching post 1: %s", err)
+	}
+
+	untouched.Tags = nil
+	untouched.Category = ""
+	if _, err := db.SavePost(ctx, untouched); err != nil {
+		t.Fatalf("unexpected error updating post 1: %s", err)
+	}
+
+	updated, err := db.FetchPost(ctx, id1)
+	if err != nil {
+		t.Fatalf("unexpected error fetching post 1: %s", err)
+	}
+
+	// ASSERT
+	if len(tags) != 1 || tags[0].Slug != "go-lang" || tags[0].PostCount != 2 {
+		t.Fatalf("unexpected tags after merge: %+v", tags)
+	}
+
+	if len(tagged) != 2 || tagged[0].ID != id2 {
+		t.Fatalf("unexpected posts for tag: %+v", tagged)
+	}
+
+	if len(updated.Tags) != 1 || updated.Tags[0] != "Go Lang" {
+		t.Fatalf("expected nil tags to leave tags untouched: %q", updated.Tags)
+	}
+
+	if updated.Category != "" {
+		t.Fatalf("expected category to be cleared: %q", updated.Category)
+	}
+}
+
+func Test_PostTrash(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Trash Author",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := db.SaveAccount(ctx, divulge.Account{Name: "Trash Account", OwnerID: authorID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.SavePost(ctx, divulge.Post{
+		AccountID: accountID,
+		AuthorID:  authorID,
+		Title:     "Trashed Post",
+		Tags:      []string{"Trash"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := db.PurgePost(ctx, id); !errors.Is(err, divulge.ErrNotFound) {
+		t.Fatalf("expected live posts not to be purged, got: %v", err)
+	}
+
+	if err := db.RemovePost(ctx, id); err != nil {
+		t.Fatalf("unexpected error trashing post: %s", err)
+	}
+
+	live, err := db.ListPostsByAccount(ctx, accountID)
+	if err != nil {
+		t.Fatalf("unexpected error listing posts: %s", err)
+	}
+
+	trash, err := db.ListTrashByAccount(ctx, accountID)
+	if err != nil {
+		t.Fatalf("unexpected error listing trash: %s", err)
+	}
+
+	expired, err := db.ListTrashedBefore(ctx, time.Now().Add(time.Minute), 100)
+	if err != nil {
+		t.Fatalf("unexpected error listing expired posts: %s", err)
+	}
+
+	publishErr := db.PublishPost(ctx, id)
+
+	if err := db.RestorePost(ctx, id); err != nil {
+		t.Fatalf("unexpected error restoring post: %s", err)
+	}
+
+	restored, err := db.FetchPost(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error fetching restored post: %s", err)
+	}
+
+	if err := db.RemovePost(ctx, id); err != nil {
+		t.Fatalf("unexpected error trashing post again: %s", err)
+	}
+
+	purgeErr := db.PurgePost(ctx, id)
+	_, fetchErr := db.FetchPost(ctx, id)
+
+	// ASSERT
+	if len(live) != 0 {
+		t.Fatalf("expected trashed posts to be hidden: %+v", live)
+	}
+
+	if len(trash) != 1 || trash[0].ID != id || trash[0].DeletedAt == nil {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+
+	var found bool
+	for _, expiredID := range expired {
+		found = found || expiredID == id
+	}
+
+	if !found {
+		t.Fatal("expected the trashed post to be listed as expired")
+	}
+
+	if !errors.Is(publishErr, divulge.ErrNotFound) {
+		t.Fatalf("expected trashed posts not to be published, got: %v", publishErr)
+	}
+
+	if restored.DeletedAt != nil || len(restored.Tags) != 1 {
+		t.Fatalf("expected the post to be restored intact: %+v", restored)
+	}
+
+	if purgeErr != nil {
+		t.Fatalf("unexpected error purging post: %s", purgeErr)
+	}
+
+	if !errors.Is(fetchErr, divulge.ErrNotFound) {
+		t.Fatalf("expected the purged post to be gone, got: %v", fetchErr)
+	}
+}
+
+func Test_SavePostRevision(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Revision Author",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := db.SaveAccount(ctx, divulge.Account{Name: "Revision Account", OwnerID: authorID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := divulge.Post{AccountID: accountID, AuthorID: authorID, Title: "First Title", ContentPath: "posts/first"}
+	revision := divulge.Revision{ID: uuid.New(), Title: post.Title, ContentPath: post.ContentPath}
+
+	// RUN
+	id, err := db.SavePostRevision(ctx, post, revision)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	post.ID = id
+	post.Title = "Second Title"
+	post.ContentPath = "posts/second"
+	_, duplicateErr := db.SavePostRevision(ctx, post, revision)
+
+	fetched, err := db.FetchPost(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	revisions, err := db.ListRevisionsByPost(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if len(revisions) != 1 || revisions[0].ID != revision.ID || revisions[0].PostID != id {
+		t.Fatalf("expected the post's revision to be saved with it, got %+v", revisions)
+	}
+
+	if duplicateErr == nil {
+		t.Fatal("expected a duplicate revision to fail")
+	}
+
+	if fetched.Title != "First Title" || fetched.ContentPath != "posts/first" {
+		t.Fatalf("expected the post update to be rolled back with its revision, got %+v", fetched)
+	}
+}
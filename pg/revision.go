@@ -0,0 +1,94 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const insertRevisionQuery = `
+INSERT INTO post_revisions
+	(id, post_id, title, summary, content_path)
+VALUES
+	(:id, :post_id, :title, :summary, :content_path);
+`
+
+const fetchRevisionQuery = `
+SELECT *
+FROM post_revisions
+WHERE
+	id = $1;
+`
+
+const listRevisionsByPostQuery = `
+SELECT *
+FROM post_revisions
+WHERE
+	post_id = $1
+ORDER BY created_at DESC;
+`
+
+// CreateRevision inserts a new revision. An ID is generated unless the revision already has one.
+func (db DB) CreateRevision(ctx context.Context, revision divulge.Revision) (uuid.UUID, error) {
+	if divulge.IsEmpty(revision.ID) {
+		revision.ID = uuid.New()
+	}
+
+	if _, err := sqlx.NamedExecContext(ctx, db.db, insertRevisionQuery, &revision); err != nil {
+		return revision.ID, classify("revision", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return revision.ID, nil
+}
+
+// SavePostRevision saves a post like SavePost and inserts a revision of it in the same
+// transaction, so a post is never saved without the revision recording it. The revision's PostID
+// is set to the saved post's.
+func (db DB) SavePostRevision(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+	if divulge.IsEmpty(revision.ID) {
+		revision.ID = uuid.New()
+	}
+
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return post.ID, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if post.ID, err = savePost(ctx, tx, post); err != nil {
+		tx.Rollback()
+		return post.ID, err
+	}
+
+	revision.PostID = post.ID
+	if _, err := sqlx.NamedExecContext(ctx, tx, insertRevisionQuery, &revision); err != nil {
+		tx.Rollback()
+		return post.ID, classify("revision", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return post.ID, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return post.ID, nil
+}
+
+func (db DB) FetchRevision(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+	var revision divulge.Revision
+	if err := db.db.GetContext(ctx, &revision, fetchRevisionQuery, id); err != nil {
+		return revision, classify("revision", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return revision, nil
+}
+
+func (db DB) ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error) {
+	var revisions []divulge.Revision
+	if err := db.db.SelectContext(ctx, &revisions, listRevisionsByPostQuery, postID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,163 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const saveCredentialsQuery = `
+INSERT INTO user_credentials
+	(user_id, password_hash)
+VALUES
+	(:user_id, :password_hash)
+ON CONFLICT (user_id) DO UPDATE
+SET
+	password_hash = EXCLUDED.password_hash,
+	updated_at = CURRENT_TIMESTAMP;
+`
+
+const fetchCredentialsQuery = `
+SELECT c.*
+FROM user_credentials c
+JOIN users u ON u.id = c.user_id
+WHERE
+	c.user_id = $1
+	AND u.deleted_at IS NULL;
+`
+
+const fetchCredentialsByEmailQuery = `
+SELECT c.*
+FROM user_credentials c
+JOIN users u ON u.id = c.user_id
+WHERE
+	u.email = $1
+	AND u.deleted_at IS NULL;
+`
+
+// insertSessionQuery converts the expiry into the session's time zone so it compares correctly
+// against CURRENT_TIMESTAMP.
+const insertSessionQuery = `
+INSERT INTO sessions
+	(id, user_id, token_hash, pending, two_factor, expires_at)
+VALUES
+	($1, $2, $3, $4, $5, $6::timestamptz::timestamp);
+`
+
+const fetchSessionByTokenHashQuery = `
+SELECT *
+FROM sessions
+WHERE
+	token_hash = $1
+	AND revoked_at IS NULL
+	AND expires_at > CURRENT_TIMESTAMP;
+`
+
+const listSessionsByUserQuery = `
+SELECT *
+FROM sessions
+WHERE
+	user_id = $1
+	AND revoked_at IS NULL
+	AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC;
+`
+
+const revokeSessionQuery = `
+UPDATE sessions
+SET
+	revoked_at = CURRENT_TIMESTAMP
+WHERE
+	user_id = $1
+	AND id = $2
+	AND revoked_at IS NULL
+	AND expires_at > CURRENT_TIMESTAMP;
+`
+
+const revokeSessionsByUserQuery = `
+UPDATE sessions
+SET
+	revoked_at = CURRENT_TIMESTAMP
+WHERE
+	user_id = $1
+	AND revoked_at IS NULL;
+`
+
+// SaveCredentials creates or replaces a user's credentials.
+func (db DB) SaveCredentials(ctx context.Context, creds divulge.Credentials) error {
+	if _, err := sqlx.NamedExecContext(ctx, db.db, saveCredentialsQuery, &creds); err != nil {
+		return classify("credentials", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return nil
+}
+
+func (db DB) FetchCredentials(ctx context.Context, userID uuid.UUID) (divulge.Credentials, error) {
+	var creds divulge.Credentials
+	if err := db.db.GetContext(ctx, &creds, fetchCredentialsQuery, userID); err != nil {
+		return creds, classify("credentials", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return creds, nil
+}
+
+func (db DB) FetchCredentialsByEmail(ctx context.Context, email string) (divulge.Credentials, error) {
+	var creds divulge.Credentials
+	if err := db.db.GetContext(ctx, &creds, fetchCredentialsByEmailQuery, email); err != nil {
+		return creds, classify("credentials", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return creds, nil
+}
+
+func (db DB) CreateSession(ctx context.Context, session divulge.Session) (uuid.UUID, error) {
+	if divulge.IsEmpty(session.ID) {
+		session.ID = uuid.New()
+	}
+
+	if _, err := db.db.ExecContext(ctx, insertSessionQuery, session.ID, session.UserID, session.TokenHash, session.Pending, session.TwoFactor, session.ExpiresAt); err != nil {
+		return session.ID, classify("session", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return session.ID, nil
+}
+
+func (db DB) FetchSessionByTokenHash(ctx context.Context, tokenHash string) (divulge.Session, error) {
+	var session divulge.Session
+	if err := db.db.GetContext(ctx, &session, fetchSessionByTokenHashQuery, tokenHash); err != nil {
+		return session, classify("session", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return session, nil
+}
+
+func (db DB) ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]divulge.Session, error) {
+	var sessions []divulge.Session
+	if err := db.db.SelectContext(ctx, &sessions, listSessionsByUserQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes one of a user's active sessions.
+func (db DB) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := db.db.ExecContext(ctx, revokeSessionQuery, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return requireRows("session", res)
+}
+
+// RevokeSessionsByUser revokes every session a user has.
+func (db DB) RevokeSessionsByUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := db.db.ExecContext(ctx, revokeSessionsByUserQuery, userID); err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
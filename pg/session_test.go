@@ -0,0 +1,95 @@
+// +build integration
+
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/pg"
+	"github.com/google/uuid"
+)
+
+func Test_Sessions(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := fmt.Sprintf("%s@test.com", uuid.New().String())
+	userID, err := db.SaveUser(ctx, divulge.User{Name: "Session User", Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := divulge.Session{UserID: userID, TokenHash: fmt.Sprintf("%064s", uuid.New().String()[:8]), ExpiresAt: time.Now().Add(time.Hour)}
+	expired := divulge.Session{UserID: userID, TokenHash: fmt.Sprintf("%064s", uuid.New().String()[:8]), ExpiresAt: time.Now().Add(-time.Minute)}
+
+	// RUN
+	if err := db.SaveCredentials(ctx, divulge.Credentials{UserID: userID, PasswordHash: "first"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := db.SaveCredentials(ctx, divulge.Credentials{UserID: userID, PasswordHash: "second"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	creds, err := db.FetchCredentialsByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	activeID, err := db.CreateSession(ctx, active)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := db.CreateSession(ctx, expired); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fetched, fetchErr := db.FetchSessionByTokenHash(ctx, active.TokenHash)
+	_, expiredErr := db.FetchSessionByTokenHash(ctx, expired.TokenHash)
+	listed, err := db.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := db.RevokeSession(ctx, userID, activeID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, revokedErr := db.FetchSessionByTokenHash(ctx, active.TokenHash)
+	againErr := db.RevokeSession(ctx, userID, activeID)
+
+	if err := db.RemoveUser(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, removedErr := db.FetchCredentialsByEmail(ctx, email)
+
+	// ASSERT
+	if creds.UserID != userID || creds.PasswordHash != "second" {
+		t.Fatalf("expected saving credentials to replace them, got %+v", creds)
+	}
+
+	if fetchErr != nil || fetched.ID != activeID {
+		t.Fatalf("unexpected active session: %+v, %v", fetched, fetchErr)
+	}
+
+	if len(listed) != 1 || listed[0].ID != activeID {
+		t.Fatalf("expected only the active session to be listed, got %+v", listed)
+	}
+
+	for _, err := range []error{expiredErr, revokedErr, againErr, removedErr} {
+		if !errors.Is(err, divulge.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	}
+}
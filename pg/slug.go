@@ -0,0 +1,129 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/slug"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// fallbackSlug is used when a post's title has nothing slug-worthy in it.
+const fallbackSlug = "post"
+
+const lockPostSlugQuery = `
+SELECT account_id, slug
+FROM posts
+WHERE
+	id = $1
+FOR UPDATE;
+`
+
+const listTakenSlugsQuery = `
+SELECT slug
+FROM posts
+WHERE
+	account_id = $1
+	AND id <> $2
+	AND (slug = $3 OR slug LIKE $3 || '-%');
+`
+
+const upsertSlugRedirectQuery = `
+INSERT INTO post_slug_redirects
+	(account_id, slug, post_id)
+VALUES
+	($1, $2, $3)
+ON CONFLICT (account_id, slug) DO UPDATE
+SET
+	post_id = EXCLUDED.post_id,
+	created_at = CURRENT_TIMESTAMP;
+`
+
+const removeSlugRedirectQuery = `
+DELETE FROM post_slug_redirects
+WHERE
+	account_id = $1
+	AND slug = $2;
+`
+
+// fetchPostBySlugQuery prefers a post currently using the slug over one that used to.
+const fetchPostBySlugQuery = selectPostsQuery + `
+LEFT JOIN post_slug_redirects r ON
+	r.post_id = p.id
+	AND r.account_id = $1
+	AND r.slug = $2
+WHERE
+	p.account_id = $1
+	AND p.deleted_at IS NULL
+	AND (p.slug = $2 OR r.slug IS NOT NULL)
+GROUP BY p.id, c.id
+ORDER BY (p.slug = $2) DESC
+LIMIT 1;
+`
+
+// A postSlug is the part of a post needed to manage its slug.
+type postSlug struct {
+	AccountID uuid.UUID `db:"account_id"`
+	Slug      string    `db:"slug"`
+}
+
+// uniqueSlug returns a slug for the post that no other post in its account is using. The slug
+// comes from post.Slug if one was given, otherwise from post.Title. Collisions are resolved by
+// appending -2, -3 and so on.
+func uniqueSlug(ctx context.Context, tx *sqlx.Tx, post divulge.Post) (string, error) {
+	base := slug.Make(post.Slug)
+	if base == "" {
+		base = slug.Make(post.Title)
+	}
+
+	if base == "" {
+		base = fallbackSlug
+	}
+
+	var taken []string
+	if err := tx.SelectContext(ctx, &taken, listTakenSlugsQuery, post.AccountID, post.ID, base); err != nil {
+		return "", fmt.Errorf("failed to select slugs: %w", err)
+	}
+
+	inUse := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		inUse[s] = true
+	}
+
+	candidate := base
+	for n := 2; inUse[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	return candidate, nil
+}
+
+// redirectSlug makes sure links to the post's previous slug keep working after it changes.
+func redirectSlug(ctx context.Context, tx *sqlx.Tx, post divulge.Post, previous string) error {
+	if previous != "" && previous != post.Slug {
+		if _, err := tx.ExecContext(ctx, upsertSlugRedirectQuery, post.AccountID, previous, post.ID); err != nil {
+			return fmt.Errorf("failed to create slug redirect: %w", err)
+		}
+	}
+
+	// the new slug belongs to a live post now, so any redirect using it is stale
+	if _, err := tx.ExecContext(ctx, removeSlugRedirectQuery, post.AccountID, post.Slug); err != nil {
+		return fmt.Errorf("failed to remove stale slug redirect: %w", err)
+	}
+
+	return nil
+}
+
+// FetchPostBySlug fetches the post in an account using the given slug. If no post currently uses
+// it, posts that used to are checked so old links keep working. Callers can compare the
+// returned post's Slug to detect a redirect.
+func (db DB) FetchPostBySlug(ctx context.Context, accountID uuid.UUID, slug string) (divulge.Post, error) {
+	var record postRecord
+	if err := db.db.GetContext(ctx, &record, fetchPostBySlugQuery, accountID, slug); err != nil {
+		return record.Post, classify("post", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return record.toPost(), nil
+}
@@ -0,0 +1,257 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/slug"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// upsertCategoryQuery returns the ID of the account's category with the given slug, creating it
+// if needed. The no-op update makes RETURNING work for categories that already exist.
+const upsertCategoryQuery = `
+INSERT INTO categories
+	(id, account_id, name, slug)
+VALUES
+	($1, $2, $3, $4)
+ON CONFLICT (account_id, slug) DO UPDATE
+SET
+	slug = EXCLUDED.slug
+RETURNING id;
+`
+
+// upsertTagQuery works just like upsertCategoryQuery.
+const upsertTagQuery = `
+INSERT INTO tags
+	(id, account_id, name, slug)
+VALUES
+	($1, $2, $3, $4)
+ON CONFLICT (account_id, slug) DO UPDATE
+SET
+	slug = EXCLUDED.slug
+RETURNING id;
+`
+
+const detachPostTagsQuery = `
+DELETE FROM post_tags
+WHERE
+	post_id = $1
+	AND NOT (tag_id = ANY($2::uuid[]));
+`
+
+const attachPostTagQuery = `
+INSERT INTO post_tags
+	(post_id, tag_id)
+VALUES
+	($1, $2)
+ON CONFLICT DO NOTHING;
+`
+
+const listTagsQuery = `
+SELECT
+	t.*,
+	COUNT(p.id) AS post_count
+FROM tags t
+LEFT JOIN post_tags pt ON pt.tag_id = t.id
+LEFT JOIN posts p ON
+	p.id = pt.post_id
+	AND p.deleted_at IS NULL
+WHERE
+	t.account_id = $1
+GROUP BY t.id
+ORDER BY t.name;
+`
+
+const listPostsByTagQuery = selectPostsQuery + `
+WHERE
+	p.account_id = $1
+	AND p.deleted_at IS NULL
+	AND p.id IN (
+		SELECT pt.post_id
+		FROM post_tags pt
+		JOIN tags tt ON tt.id = pt.tag_id
+		WHERE
+			tt.account_id = $1
+			AND tt.slug = $2
+	)
+GROUP BY p.id, c.id
+ORDER BY p.created_at DESC;
+`
+
+const renameTagQuery = `
+UPDATE tags
+SET
+	name = $3,
+	slug = $4
+WHERE
+	account_id = $1
+	AND slug = $2;
+`
+
+// lockTagsQuery locks two tags in a fixed order, so merges running in opposite directions
+// can't deadlock.
+const lockTagsQuery = `
+SELECT id, slug
+FROM tags
+WHERE
+	account_id = $1
+	AND slug IN ($2, $3)
+ORDER BY id
+FOR UPDATE;
+`
+
+const mergePostTagsQuery = `
+INSERT INTO post_tags
+	(post_id, tag_id)
+SELECT post_id, $2
+FROM post_tags
+WHERE
+	tag_id = $1
+ON CONFLICT DO NOTHING;
+`
+
+const removeTagQuery = `
+DELETE FROM tags
+WHERE
+	id = $1;
+`
+
+// ensureCategory returns the ID of the named category in the account, creating it if needed. An
+// empty name means no category.
+func ensureCategory(ctx context.Context, tx *sqlx.Tx, accountID uuid.UUID, name string) (*uuid.UUID, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	categorySlug := slug.Make(name)
+	if categorySlug == "" {
+		return nil, divulge.ValidationError(fmt.Sprintf("category %q needs at least one letter or number", name), nil)
+	}
+
+	var id uuid.UUID
+	if err := tx.GetContext(ctx, &id, upsertCategoryQuery, uuid.New(), accountID, name, categorySlug); err != nil {
+		return nil, classify("category", fmt.Errorf("failed to save category: %w", err))
+	}
+
+	return &id, nil
+}
+
+// syncPostTags creates any of the post's tags that don't exist yet and then attaches and
+// detaches tags so the post has exactly the ones given.
+func syncPostTags(ctx context.Context, tx *sqlx.Tx, post divulge.Post) error {
+	ids := make([]string, 0, len(post.Tags))
+	for _, name := range post.Tags {
+		tagSlug := slug.Make(name)
+		if tagSlug == "" {
+			return divulge.ValidationError(fmt.Sprintf("tag %q needs at least one letter or number", name), nil)
+		}
+
+		var id uuid.UUID
+		if err := tx.GetContext(ctx, &id, upsertTagQuery, uuid.New(), post.AccountID, name, tagSlug); err != nil {
+			return classify("tag", fmt.Errorf("failed to save tag: %w", err))
+		}
+
+		ids = append(ids, id.String())
+	}
+
+	if _, err := tx.ExecContext(ctx, detachPostTagsQuery, post.ID, pq.StringArray(ids)); err != nil {
+		return fmt.Errorf("failed to detach tags: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, attachPostTagQuery, post.ID, id); err != nil {
+			return classify("tag", fmt.Errorf("failed to attach tag: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// ListTags lists every tag in an account along with how many posts use it.
+func (db DB) ListTags(ctx context.Context, accountID uuid.UUID) ([]divulge.Tag, error) {
+	var tags []divulge.Tag
+	if err := db.db.SelectContext(ctx, &tags, listTagsQuery, accountID); err != nil {
+		return tags, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return tags, nil
+}
+
+// ListPostsByTag lists the posts in an account with the given tag slug, newest first.
+func (db DB) ListPostsByTag(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error) {
+	var records []postRecord
+	if err := db.db.SelectContext(ctx, &records, listPostsByTagQuery, accountID, tag); err != nil {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	return toPosts(records), nil
+}
+
+// RenameTag renames the tag with the given slug. Its slug changes to match the new name, which
+// fails with a conflict if another tag already uses it.
+func (db DB) RenameTag(ctx context.Context, accountID uuid.UUID, tag, name string) error {
+	newSlug := slug.Make(name)
+	if newSlug == "" {
+		return divulge.ValidationError(fmt.Sprintf("tag %q needs at least one letter or number", name), nil)
+	}
+
+	res, err := db.db.ExecContext(ctx, renameTagQuery, accountID, tag, name, newSlug)
+	if err != nil {
+		return classify("tag", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	return requireRows("tag", res)
+}
+
+// MergeTags moves every post tagged with from over to into and then removes from.
+func (db DB) MergeTags(ctx context.Context, accountID uuid.UUID, from, into string) error {
+	if from == into {
+		return divulge.ValidationError("can't merge a tag into itself", nil)
+	}
+
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	var locked []divulge.Tag
+	if err := tx.SelectContext(ctx, &locked, lockTagsQuery, accountID, from, into); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to lock tags: %w", err)
+	}
+
+	var fromID, intoID uuid.UUID
+	for _, tag := range locked {
+		switch tag.Slug {
+		case from:
+			fromID = tag.ID
+		case into:
+			intoID = tag.ID
+		}
+	}
+
+	if divulge.IsEmpty(fromID) || divulge.IsEmpty(intoID) {
+		tx.Rollback()
+		return divulge.NotFoundError("tag not found", nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, mergePostTagsQuery, fromID, intoID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, removeTagQuery, fromID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove tag: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
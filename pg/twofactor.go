@@ -0,0 +1,175 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// saveTOTPQuery replaces a TOTP that's still waiting to be confirmed, but leaves a confirmed one
+// alone so it can't be swapped out from under its user.
+const saveTOTPQuery = `
+INSERT INTO user_totp
+	(user_id, secret)
+VALUES
+	($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET
+	secret = EXCLUDED.secret,
+	last_used_step = 0,
+	created_at = CURRENT_TIMESTAMP
+WHERE
+	user_totp.confirmed_at IS NULL;
+`
+
+const fetchTOTPQuery = `
+SELECT *
+FROM user_totp
+WHERE
+	user_id = $1;
+`
+
+// useTOTPStepQuery only moves forward, so each code can only be used once.
+const useTOTPStepQuery = `
+UPDATE user_totp
+SET
+	last_used_step = $2,
+	confirmed_at = COALESCE(confirmed_at, CURRENT_TIMESTAMP)
+WHERE
+	user_id = $1
+	AND last_used_step < $2;
+`
+
+const removeTOTPQuery = `
+DELETE FROM user_totp
+WHERE
+	user_id = $1;
+`
+
+const removeRecoveryCodesQuery = `
+DELETE FROM recovery_codes
+WHERE
+	user_id = $1;
+`
+
+const insertRecoveryCodeQuery = `
+INSERT INTO recovery_codes
+	(id, user_id, code_hash)
+VALUES
+	($1, $2, $3);
+`
+
+const useRecoveryCodeQuery = `
+UPDATE recovery_codes
+SET
+	used_at = CURRENT_TIMESTAMP
+WHERE
+	user_id = $1
+	AND code_hash = $2
+	AND used_at IS NULL;
+`
+
+func (db DB) SaveTOTP(ctx context.Context, totp divulge.TOTP) error {
+	res, err := db.db.ExecContext(ctx, saveTOTPQuery, totp.UserID, totp.Secret)
+	if err != nil {
+		return classify("totp", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to count affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return divulge.ConflictError("two-factor authentication is already enabled", nil)
+	}
+
+	return nil
+}
+
+func (db DB) FetchTOTP(ctx context.Context, userID uuid.UUID) (divulge.TOTP, error) {
+	var totp divulge.TOTP
+	if err := db.db.GetContext(ctx, &totp, fetchTOTPQuery, userID); err != nil {
+		return totp, classify("totp", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return totp, nil
+}
+
+// UseTOTPStep records the step of a code that was just accepted, confirming the TOTP if it
+// wasn't already.
+func (db DB) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	res, err := db.db.ExecContext(ctx, useTOTPStepQuery, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return requireRows("totp step", res)
+}
+
+// RemoveTOTP turns off two-factor authentication for a user, removing their recovery codes too.
+func (db DB) RemoveTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, removeRecoveryCodesQuery, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, removeTOTPQuery, userID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	if err := requireRows("totp", res); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SaveRecoveryCodes replaces all of a user's recovery codes.
+func (db DB) SaveRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, removeRecoveryCodesQuery, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, insertRecoveryCodeQuery, uuid.New(), userID, hash); err != nil {
+			tx.Rollback()
+			return classify("recovery code", fmt.Errorf("failed to execute query: %w", err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db DB) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error {
+	res, err := db.db.ExecContext(ctx, useRecoveryCodeQuery, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return requireRows("recovery code", res)
+}
@@ -0,0 +1,100 @@
+// +build integration
+
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/pg"
+	"github.com/google/uuid"
+)
+
+func Test_TwoFactor(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := fmt.Sprintf("%s@test.com", uuid.New().String())
+	userID, err := db.SaveUser(ctx, divulge.User{Name: "Two Factor User", Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := []string{fmt.Sprintf("%064d", 1), fmt.Sprintf("%064d", 2)}
+
+	// RUN
+	if err := db.SaveTOTP(ctx, divulge.TOTP{UserID: userID, Secret: "FIRST"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := db.SaveTOTP(ctx, divulge.TOTP{UserID: userID, Secret: "SECOND"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	unconfirmed, err := db.FetchUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stepErr := db.UseTOTPStep(ctx, userID, 100)
+	replayErr := db.UseTOTPStep(ctx, userID, 100)
+	replaceErr := db.SaveTOTP(ctx, divulge.TOTP{UserID: userID, Secret: "THIRD"})
+
+	totp, err := db.FetchTOTP(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	confirmed, err := db.FetchUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := db.SaveRecoveryCodes(ctx, userID, codes); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	recoveryErr := db.UseRecoveryCode(ctx, userID, codes[0])
+	reusedErr := db.UseRecoveryCode(ctx, userID, codes[0])
+
+	if err := db.RemoveTOTP(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, removedErr := db.FetchTOTP(ctx, userID)
+	removedCodeErr := db.UseRecoveryCode(ctx, userID, codes[1])
+
+	if err := db.RemoveUser(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if unconfirmed.TwoFactor {
+		t.Fatal("expected an unconfirmed totp not to enable two-factor authentication")
+	}
+
+	if stepErr != nil || recoveryErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", stepErr, recoveryErr)
+	}
+
+	if totp.Secret != "SECOND" || totp.LastUsedStep != 100 || totp.ConfirmedAt == nil || !confirmed.TwoFactor {
+		t.Fatalf("unexpected totp: %+v, %+v", totp, confirmed)
+	}
+
+	if !errors.Is(replaceErr, divulge.ErrConflict) {
+		t.Fatalf("expected a confirmed totp not to be replaced, got %v", replaceErr)
+	}
+
+	for _, err := range []error{replayErr, reusedErr, removedErr, removedCodeErr} {
+		if !errors.Is(err, divulge.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/eriktate/divulge"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 const insertUserQuery = `
@@ -20,7 +21,8 @@ SET
 	name = :name,
 	email = :email
 WHERE
-	id = :id;
+	id = :id
+	AND deleted_at IS NULL;
 `
 
 const removeUserQuery = `
@@ -28,24 +30,112 @@ UPDATE users
 SET
 	deleted_at = CURRENT_TIMESTAMP
 WHERE
-	id = $1;
+	id = $1
+	AND deleted_at IS NULL;
+`
+
+// selectUsersQuery selects users along with the IDs of every account they're a member of and
+// whether they've confirmed a TOTP. It must be completed with a WHERE clause and a GROUP BY on
+// u.id.
+const selectUsersQuery = `
+SELECT
+	u.*,
+	COALESCE(
+		array_agg(ua.account_id) FILTER (WHERE ua.account_id IS NOT NULL),
+		'{}'
+	) AS accounts,
+	EXISTS (
+		SELECT 1
+		FROM user_totp t
+		WHERE
+			t.user_id = u.id
+			AND t.confirmed_at IS NOT NULL
+	) AS two_factor
+FROM users u
+LEFT JOIN user_accounts ua ON ua.user_id = u.id
 `
 
-const fetchUserQuery = `
-SELECT *
-FROM users
+const fetchUserQuery = selectUsersQuery + `
 WHERE
-	id = $1
-	AND deleted_at IS NULL;
+	u.id = $1
+	AND u.deleted_at IS NULL
+GROUP BY u.id;
+`
+
+const fetchUserByEmailQuery = selectUsersQuery + `
+WHERE
+	u.email = $1
+	AND u.deleted_at IS NULL
+GROUP BY u.id;
+`
+
+const listUsersQuery = selectUsersQuery + `
+WHERE
+	u.deleted_at IS NULL
+GROUP BY u.id;
 `
 
-const listUsersQuery = `
-SELECT *
-FROM users
+const lockUserAccountsQuery = `
+SELECT account_id
+FROM user_accounts
 WHERE
-	deleted_at IS NULL;
+	user_id = $1
+FOR UPDATE;
+`
+
+const insertUserAccountQuery = `
+INSERT INTO user_accounts
+	(user_id, account_id)
+VALUES
+	($1, $2);
 `
 
+const deleteUserAccountQuery = `
+DELETE FROM user_accounts
+WHERE
+	user_id = $1
+	AND account_id = $2
+	AND role <> 'owner';
+`
+
+// A userRecord is a divulge.User as it's selected from the database.
+type userRecord struct {
+	divulge.User
+	AccountIDs pq.StringArray `db:"accounts"`
+}
+
+func (r userRecord) toUser() (divulge.User, error) {
+	user := r.User
+	user.Accounts = make([]uuid.UUID, len(r.AccountIDs))
+	for i, raw := range r.AccountIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return user, fmt.Errorf("failed to parse account id: %w", err)
+		}
+
+		user.Accounts[i] = id
+	}
+
+	return user, nil
+}
+
+func toUsers(records []userRecord) ([]divulge.User, error) {
+	users := make([]divulge.User, len(records))
+	for i, record := range records {
+		user, err := record.toUser()
+		if err != nil {
+			return nil, err
+		}
+
+		users[i] = user
+	}
+
+	return users, nil
+}
+
+// SaveUser inserts or updates a user. The user's account memberships are synced to match
+// user.Accounts, except when updating with a nil Accounts which leaves memberships untouched.
+// Memberships of accounts the user owns are never removed.
 func (db DB) SaveUser(ctx context.Context, user divulge.User) (uuid.UUID, error) {
 	// are we inserting?
 	query := updateUserQuery
@@ -59,9 +149,22 @@ func (db DB) SaveUser(ctx context.Context, user divulge.User) (uuid.UUID, error)
 		return user.ID, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := sqlx.NamedExecContext(ctx, tx, query, &user); err != nil {
+	res, err := sqlx.NamedExecContext(ctx, tx, query, &user)
+	if err != nil {
+		tx.Rollback()
+		return user.ID, classify("user", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("user", res); err != nil {
 		tx.Rollback()
-		return user.ID, fmt.Errorf("failed to execute query: %w", err)
+		return user.ID, err
+	}
+
+	if query == insertUserQuery || user.Accounts != nil {
+		if err := syncUserAccounts(ctx, tx, user.ID, user.Accounts); err != nil {
+			tx.Rollback()
+			return user.ID, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -71,22 +174,73 @@ func (db DB) SaveUser(ctx context.Context, user divulge.User) (uuid.UUID, error)
 	return user.ID, nil
 }
 
+// syncUserAccounts adds and removes user_accounts rows so that the user is a member of exactly
+// the accounts given.
+func syncUserAccounts(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, accounts []uuid.UUID) error {
+	var current []uuid.UUID
+	if err := tx.SelectContext(ctx, &current, lockUserAccountsQuery, userID); err != nil {
+		return fmt.Errorf("failed to select memberships: %w", err)
+	}
+
+	existing := make(map[uuid.UUID]bool, len(current))
+	for _, id := range current {
+		existing[id] = true
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(accounts))
+	for _, id := range accounts {
+		if wanted[id] {
+			continue
+		}
+
+		wanted[id] = true
+		if existing[id] {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, insertUserAccountQuery, userID, id); err != nil {
+			return classify("membership", fmt.Errorf("failed to add membership: %w", err))
+		}
+	}
+
+	for _, id := range current {
+		if wanted[id] {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteUserAccountQuery, userID, id); err != nil {
+			return fmt.Errorf("failed to remove membership: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (db DB) FetchUser(ctx context.Context, id uuid.UUID) (divulge.User, error) {
-	var user divulge.User
-	if err := db.db.GetContext(ctx, &user, fetchUserQuery, id); err != nil {
-		return user, fmt.Errorf("failed to select: %w", err)
+	var record userRecord
+	if err := db.db.GetContext(ctx, &record, fetchUserQuery, id); err != nil {
+		return record.User, classify("user", fmt.Errorf("failed to select: %w", err))
 	}
 
-	return user, nil
+	return record.toUser()
+}
+
+func (db DB) FetchUserByEmail(ctx context.Context, email string) (divulge.User, error) {
+	var record userRecord
+	if err := db.db.GetContext(ctx, &record, fetchUserByEmailQuery, email); err != nil {
+		return record.User, classify("user", fmt.Errorf("failed to select: %w", err))
+	}
+
+	return record.toUser()
 }
 
 func (db DB) ListUsers(ctx context.Context) ([]divulge.User, error) {
-	var users []divulge.User
-	if err := db.db.SelectContext(ctx, &users, listUsersQuery); err != nil {
+	var records []userRecord
+	if err := db.db.SelectContext(ctx, &records, listUsersQuery); err != nil {
 		return nil, fmt.Errorf("failed to select: %w", err)
 	}
 
-	return users, nil
+	return toUsers(records)
 }
 
 func (db DB) RemoveUser(ctx context.Context, id uuid.UUID) error {
@@ -95,9 +249,15 @@ func (db DB) RemoveUser(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if _, err := tx.ExecContext(ctx, removeUserQuery, id); err != nil {
+	res, err := tx.ExecContext(ctx, removeUserQuery, id)
+	if err != nil {
+		tx.Rollback()
+		return classify("user", fmt.Errorf("failed to execute query: %w", err))
+	}
+
+	if err := requireRows("user", res); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to execute query: %w", err)
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -4,6 +4,7 @@ package pg_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -67,7 +68,16 @@ func Test_Users(t *testing.T) {
 		t.Fatalf("unexpected error removing user2: %s", err)
 	}
 
+	removed := user1
+	removed.ID = id1
+	removed.Name = "Renamed"
+	_, updateRemovedErr := db.SaveUser(ctx, removed)
+
 	// ASSERT
+	if !errors.Is(updateRemovedErr, divulge.ErrNotFound) {
+		t.Fatalf("expected removed users not to be updated, got %v", updateRemovedErr)
+	}
+
 	if divulge.IsEmpty(id1) {
 		t.Fatal("unexpected empty id1")
 	}
@@ -89,3 +99,81 @@ func Test_Users(t *testing.T) {
 		t.Fatal("expected fetchedUser1.ID to equal user1.ID")
 	}
 }
+
+func Test_UserAccounts(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	db, err := pg.New("localhost", "postgres", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ownerID, err := db.SaveUser(ctx, divulge.User{
+		Name:  "Owner",
+		Email: fmt.Sprintf("%s@test.com", uuid.New().String()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account1, err := db.SaveAccount(ctx, divulge.Account{Name: "Account One", OwnerID: ownerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account2, err := db.SaveAccount(ctx, divulge.Account{Name: "Account Two", OwnerID: ownerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := divulge.User{
+		Name:     "Member",
+		Email:    fmt.Sprintf("%s@test.com", uuid.New().String()),
+		Accounts: []uuid.UUID{account1},
+	}
+
+	// RUN
+	id, err := db.SaveUser(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %s", err)
+	}
+
+	created, err := db.FetchUser(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error fetching created user: %s", err)
+	}
+
+	user.ID = id
+	user.Accounts = []uuid.UUID{account2}
+	if _, err := db.SaveUser(ctx, user); err != nil {
+		t.Fatalf("unexpected error updating user: %s", err)
+	}
+
+	updated, err := db.FetchUser(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error fetching updated user: %s", err)
+	}
+
+	user.Accounts = nil
+	if _, err := db.SaveUser(ctx, user); err != nil {
+		t.Fatalf("unexpected error updating user without accounts: %s", err)
+	}
+
+	untouched, err := db.FetchUser(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error fetching untouched user: %s", err)
+	}
+
+	// ASSERT
+	if len(created.Accounts) != 1 || created.Accounts[0] != account1 {
+		t.Fatalf("unexpected accounts after create: %v", created.Accounts)
+	}
+
+	if len(updated.Accounts) != 1 || updated.Accounts[0] != account2 {
+		t.Fatalf("unexpected accounts after update: %v", updated.Accounts)
+	}
+
+	if len(untouched.Accounts) != 1 || untouched.Accounts[0] != account2 {
+		t.Fatalf("expected nil accounts to leave memberships untouched: %v", untouched.Accounts)
+	}
+}
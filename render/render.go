@@ -0,0 +1,209 @@
+// Package render turns post content written in markdown into sanitized HTML.
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/slug"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// DefaultCacheSize is how many rendered documents a Renderer keeps by default.
+const DefaultCacheSize = 512
+
+// A Document is rendered post content.
+type Document struct {
+	HTML string
+	TOC  []divulge.Heading
+}
+
+// A Renderer renders CommonMark with GFM tables, strikethrough, autolinks, task lists and
+// footnotes. Every heading gets an anchor and an entry in the table of contents. Output is
+// sanitized, so raw HTML in content is allowed through only where it's safe.
+//
+// Rendered documents are cached by a hash of their content, so a Renderer is safe to call on
+// every fetch. It's safe for concurrent use.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	cache map[[sha256.Size]byte]*list.Element
+}
+
+type cached struct {
+	key [sha256.Size]byte
+	doc Document
+}
+
+// New returns a Renderer that caches up to size documents. A size of 0 or less disables caching.
+func New(size int) *Renderer {
+	return &Renderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM, extension.Footnote),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			// raw HTML is passed through here and cleaned up by the sanitizer afterwards
+			goldmark.WithRendererOptions(html.WithUnsafe()),
+		),
+		policy: policy(),
+		size:   size,
+		order:  list.New(),
+		cache:  make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Render renders markdown content into a Document.
+func (r *Renderer) Render(content string) (Document, error) {
+	key := sha256.Sum256([]byte(content))
+	if doc, ok := r.lookup(key); ok {
+		return doc, nil
+	}
+
+	source := []byte(content)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	root := r.md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	toc := anchorHeadings(root, source)
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, source, root); err != nil {
+		return Document{}, fmt.Errorf("failed to render content: %w", err)
+	}
+
+	doc := Document{
+		HTML: r.policy.Sanitize(buf.String()),
+		TOC:  toc,
+	}
+
+	r.store(key, doc)
+	return doc, nil
+}
+
+func (r *Renderer) lookup(key [sha256.Size]byte) (Document, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.cache[key]
+	if !ok {
+		return Document{}, false
+	}
+
+	r.order.MoveToFront(elem)
+	return elem.Value.(cached).doc, true
+}
+
+// store caches a document, evicting the least recently used one if the cache is full.
+func (r *Renderer) store(key [sha256.Size]byte, doc Document) {
+	if r.size <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.cache[key]; ok {
+		return
+	}
+
+	r.cache[key] = r.order.PushFront(cached{key: key, doc: doc})
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.cache, oldest.Value.(cached).key)
+	}
+}
+
+// anchorHeadings appends a self link to every heading and returns them as a table of contents.
+func anchorHeadings(root ast.Node, source []byte) []divulge.Heading {
+	var toc []divulge.Heading
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		id, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+
+		anchor := string(id.([]byte))
+		toc = append(toc, divulge.Heading{
+			Level: heading.Level,
+			ID:    anchor,
+			Text:  string(heading.Text(source)),
+		})
+
+		link := ast.NewLink()
+		link.Destination = []byte("#" + anchor)
+		link.SetAttributeString("class", []byte("anchor"))
+		link.AppendChild(link, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, link)
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	return toc
+}
+
+// headingIDs generates heading anchors the same way post slugs are made, so headings in any
+// script get readable anchors. Repeated headings are numbered to keep anchors unique.
+type headingIDs struct {
+	seen map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{seen: make(map[string]bool)}
+}
+
+// Generate implements the parser.IDs interface.
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	base := slug.Make(string(value))
+	if base == "" {
+		base = "heading"
+	}
+
+	id := base
+	for n := 1; ids.seen[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	ids.seen[id] = true
+	return []byte(id)
+}
+
+// Put implements the parser.IDs interface.
+func (ids *headingIDs) Put(value []byte) {
+	ids.seen[string(value)] = true
+}
+
+var (
+	anchorID     = regexp.MustCompile(`^[\p{L}\p{M}\p{N}_:-]+$`)
+	knownClasses = regexp.MustCompile(`^(anchor|footnote-ref|footnote-backref|footnotes)$`)
+	knownRoles   = regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)
+)
+
+// policy allows everything users would expect from a blog post, plus the attributes the
+// renderer itself relies on for anchors, footnotes and task lists.
+func policy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(anchorID).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("class").Matching(knownClasses).OnElements("a", "div", "sup")
+	p.AllowAttrs("role").Matching(knownRoles).OnElements("a", "div", "sup")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
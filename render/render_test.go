@@ -0,0 +1,123 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eriktate/divulge/render"
+)
+
+func Test_Render(t *testing.T) {
+	// SETUP
+	renderer := render.New(render.DefaultCacheSize)
+	content := strings.Join([]string{
+		"# Crème Brûlée",
+		"",
+		"Custard[^1] with ~~sugar~~ caramel.",
+		"",
+		"| step | time |",
+		"|------|------|",
+		"| bake | 40m  |",
+		"",
+		"## Steps",
+		"",
+		"- [x] heat",
+		"- [ ] chill",
+		"",
+		"## Steps",
+		"",
+		"[^1]: Mostly eggs.",
+	}, "\n")
+
+	// RUN
+	doc, err := renderer.Render(content)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		`<h1 id="creme-brulee">Crème Brûlée<a href="#creme-brulee" class="anchor"`,
+		`<del>sugar</del>`,
+		`<td>bake</td>`,
+		`<input checked="" disabled="" type="checkbox">`,
+		`<h2 id="steps-1">`,
+		`<sup id="fnref:1"><a href="#fn:1" class="footnote-ref"`,
+		`<li id="fn:1">`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(doc.HTML, e) {
+			t.Fatalf("expected HTML to contain %s, got:\n%s", e, doc.HTML)
+		}
+	}
+
+	if len(doc.TOC) != 3 {
+		t.Fatalf("unexpected table of contents: %+v", doc.TOC)
+	}
+
+	if doc.TOC[0].Level != 1 || doc.TOC[0].ID != "creme-brulee" || doc.TOC[0].Text != "Crème Brûlée" {
+		t.Fatalf("unexpected first heading: %+v", doc.TOC[0])
+	}
+
+	if doc.TOC[1].ID != "steps" || doc.TOC[2].ID != "steps-1" {
+		t.Fatalf("expected repeated headings to get unique anchors: %+v", doc.TOC)
+	}
+}
+
+func Test_Render_Sanitizes(t *testing.T) {
+	// SETUP
+	renderer := render.New(0)
+	content := `Hello <script>alert("hi")</script><b onclick="steal()">there</b> [link](javascript:alert(1))`
+
+	// RUN
+	doc, err := renderer.Render(content)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, unsafe := range []string{"<script", "onclick", "javascript:"} {
+		if strings.Contains(doc.HTML, unsafe) {
+			t.Fatalf("expected %s to be removed, got: %s", unsafe, doc.HTML)
+		}
+	}
+
+	if !strings.Contains(doc.HTML, "<b>there</b>") {
+		t.Fatalf("expected safe HTML to be kept, got: %s", doc.HTML)
+	}
+}
+
+func Test_Render_Cache(t *testing.T) {
+	// SETUP
+	renderer := render.New(1)
+
+	// RUN
+	first, err := renderer.Render("# One")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	again, err := renderer.Render("# One")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// evicts the first document
+	if _, err := renderer.Render("# Two"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evicted, err := renderer.Render("# One")
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if again.HTML != first.HTML || evicted.HTML != first.HTML {
+		t.Fatal("expected the same content to always render the same way")
+	}
+}
@@ -0,0 +1,85 @@
+// +build integration
+
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/s3"
+	"github.com/google/uuid"
+)
+
+// Test_MinIO expects a MinIO server on localhost:9000 with the default credentials and a
+// bucket named divulge.
+func Test_MinIO(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fs, err := s3.New(s3.Config{
+		Endpoint:        "http://localhost:9000",
+		Bucket:          "divulge",
+		Prefix:          uuid.New().String(),
+		AccessKeyID:     "minioadmin",
+		SecretAccessKey: "minioadmin",
+		PathStyle:       true,
+		PartSize:        s3.MinPartSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := []byte("# Hello, MinIO")
+	large := bytes.Repeat([]byte("divulge "), s3.MinPartSize/4)
+
+	// RUN
+	if err := fs.Write(ctx, "small.md", small); err != nil {
+		t.Fatalf("unexpected error writing small object: %s", err)
+	}
+
+	if err := fs.Write(ctx, "nested/large.md", large); err != nil {
+		t.Fatalf("unexpected error writing large object: %s", err)
+	}
+
+	readSmall, err := fs.Read(ctx, "small.md")
+	if err != nil {
+		t.Fatalf("unexpected error reading small object: %s", err)
+	}
+
+	readLarge, err := fs.Read(ctx, "nested/large.md")
+	if err != nil {
+		t.Fatalf("unexpected error reading large object: %s", err)
+	}
+
+	_, missingErr := fs.Read(ctx, "missing.md")
+
+	files, listErr := fs.List(ctx, "nested/")
+	if listErr != nil {
+		t.Fatalf("unexpected error listing objects: %s", listErr)
+	}
+
+	if err := fs.Delete(ctx, "small.md"); err != nil {
+		t.Fatalf("unexpected error deleting object: %s", err)
+	}
+
+	exists, existsErr := fs.Exists(ctx, "small.md")
+
+	// ASSERT
+	if !bytes.Equal(readSmall, small) || !bytes.Equal(readLarge, large) {
+		t.Fatal("expected objects to round trip")
+	}
+
+	if !errors.Is(missingErr, divulge.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", missingErr)
+	}
+
+	if len(files) != 1 || files[0].Key != "nested/large.md" || files[0].Size != int64(len(large)) {
+		t.Fatalf("unexpected listing: %+v", files)
+	}
+
+	if exists || existsErr != nil {
+		t.Fatalf("expected the deleted object to be gone: %v", existsErr)
+	}
+}
@@ -0,0 +1,508 @@
+// Package s3 implements divulge.FileStore against any S3-compatible object storage API.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+// Server-side encryption modes.
+const (
+	SSENone = ""
+	SSES3   = "AES256"
+	SSEKMS  = "aws:kms"
+)
+
+// MinPartSize is the smallest part S3 accepts in a multipart upload, other than the last one.
+const MinPartSize = 5 << 20
+
+// DefaultPartSize is used when Config.PartSize isn't set. Content larger than the part size is
+// uploaded in parts, and at most one part is buffered in memory at a time.
+const DefaultPartSize = 16 << 20
+
+// DefaultTimeout is used when Config.Timeout isn't set.
+const DefaultTimeout = time.Minute
+
+// Config configures a FileStore.
+type Config struct {
+	// Endpoint is the base URL of the S3 API, like https://s3.us-east-1.amazonaws.com or
+	// http://localhost:9000 for MinIO.
+	Endpoint string `json:"endpoint"`
+	Region   string `json:"region"`
+	Bucket   string `json:"bucket"`
+	// Prefix is prepended to every key, letting several installs share a bucket.
+	Prefix          string `json:"prefix"`
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	// PathStyle addresses the bucket in the path instead of the host name, which most
+	// self-hosted S3-compatible services need.
+	PathStyle bool `json:"pathStyle"`
+	// SSE is the server-side encryption mode to request for every object.
+	SSE      string `json:"sse"`
+	KMSKeyID string `json:"kmsKeyId"`
+	PartSize int64  `json:"partSize"`
+	// Timeout bounds every request to the S3 API, including reading the response. Uploads are
+	// sent a part at a time, so it only has to allow for a single part.
+	Timeout time.Duration `json:"-"`
+}
+
+// A FileStore is an S3 implementation of divulge.FileStore.
+type FileStore struct {
+	cfg      Config
+	endpoint *url.URL
+	signer   signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+// New returns a new FileStore.
+func New(cfg Config) (FileStore, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return FileStore{}, fmt.Errorf("invalid s3 endpoint: %q", cfg.Endpoint)
+	}
+
+	if cfg.Bucket == "" {
+		return FileStore{}, errors.New("s3 bucket is required")
+	}
+
+	switch cfg.SSE {
+	case SSENone, SSES3, SSEKMS:
+	default:
+		return FileStore{}, fmt.Errorf("unknown s3 server-side encryption: %q", cfg.SSE)
+	}
+
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+
+	if cfg.PartSize < MinPartSize {
+		return FileStore{}, fmt.Errorf("s3 part size must be at least %d bytes", MinPartSize)
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	if cfg.Timeout < 0 {
+		return FileStore{}, errors.New("s3 timeout can't be negative")
+	}
+
+	return FileStore{
+		cfg:      cfg,
+		endpoint: endpoint,
+		signer: signer{
+			accessKey: cfg.AccessKeyID,
+			secretKey: cfg.SecretAccessKey,
+			region:    cfg.Region,
+		},
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}, nil
+}
+
+// Create an object from the content of r. Content is buffered a part at a time, so anything
+// larger than the configured part size is uploaded in parts without ever being held in memory
+// all at once.
+func (fs FileStore) Create(ctx context.Context, key string, r io.Reader, size int64) error {
+	part, eof, err := fs.readPart(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+
+	if !eof {
+		return fs.createMultipart(ctx, key, part, r, size)
+	}
+
+	if size >= 0 && int64(len(part)) != size {
+		return fmt.Errorf("failed to write object: expected %d bytes, got %d", size, len(part))
+	}
+
+	res, err := fs.do(ctx, http.MethodPut, key, nil, part, fs.encryptionHeaders())
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	defer res.Body.Close()
+
+	return nil
+}
+
+// Open an object for reading. The caller must close it.
+func (fs FileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := fs.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, divulge.NotFoundError("content not found", err)
+		}
+
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	return res.Body, nil
+}
+
+// Write an object.
+func (fs FileStore) Write(ctx context.Context, key string, data []byte) error {
+	return fs.Create(ctx, key, bytes.NewReader(data), int64(len(data)))
+}
+
+// Read an object.
+func (fs FileStore) Read(ctx context.Context, key string) ([]byte, error) {
+	body, err := fs.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	return data, nil
+}
+
+// readPart reads up to a part's worth of r. eof reports whether r is known to be drained, which
+// is only certain once a read comes up short. remaining is how much of r is expected to be left,
+// or -1, and only sizes the buffer.
+func (fs FileStore) readPart(r io.Reader, remaining int64) (part []byte, eof bool, err error) {
+	var buf bytes.Buffer
+	if remaining >= 0 && remaining < fs.cfg.PartSize {
+		buf.Grow(int(remaining))
+	}
+
+	n, err := io.CopyN(&buf, r, fs.cfg.PartSize)
+	if err == io.EOF {
+		return buf.Bytes(), true, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return buf.Bytes(), n < fs.cfg.PartSize, nil
+}
+
+// Delete an object. S3 doesn't treat deleting a missing object as an error, and neither do we.
+func (fs FileStore) Delete(ctx context.Context, key string) error {
+	res, err := fs.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	defer res.Body.Close()
+
+	return nil
+}
+
+// Exists checks whether an object exists.
+func (fs FileStore) Exists(ctx context.Context, key string) (bool, error) {
+	if _, err := fs.Stat(ctx, key); err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Stat describes an object. The checksum is its ETag.
+func (fs FileStore) Stat(ctx context.Context, key string) (divulge.FileInfo, error) {
+	res, err := fs.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return divulge.FileInfo{}, divulge.NotFoundError("content not found", err)
+		}
+
+		return divulge.FileInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	defer res.Body.Close()
+
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return divulge.FileInfo{
+		Key:      key,
+		Size:     res.ContentLength,
+		ModTime:  modTime,
+		Checksum: strings.Trim(res.Header.Get("ETag"), `"`),
+	}, nil
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// List describes every object whose key starts with prefix, following continuation tokens until
+// the listing is complete.
+func (fs FileStore) List(ctx context.Context, prefix string) ([]divulge.FileInfo, error) {
+	files := []divulge.FileInfo{}
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {fs.cfg.Prefix + prefix},
+	}
+
+	for {
+		res, err := fs.send(ctx, http.MethodGet, fs.bucketURL(query), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			files = append(files, divulge.FileInfo{
+				Key:      strings.TrimPrefix(object.Key, fs.cfg.Prefix),
+				Size:     object.Size,
+				ModTime:  object.LastModified,
+				Checksum: strings.Trim(object.ETag, `"`),
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return files, nil
+		}
+
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// createMultipart uploads first and then the rest of r in parts. If anything goes wrong the
+// upload is aborted so the parts don't linger in the bucket.
+func (fs FileStore) createMultipart(ctx context.Context, key string, first []byte, r io.Reader, size int64) error {
+	res, err := fs.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, fs.encryptionHeaders())
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	var initiated initiateMultipartUploadResult
+	err = xml.NewDecoder(res.Body).Decode(&initiated)
+	res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode multipart upload: %w", err)
+	}
+
+	if err := fs.uploadParts(ctx, key, initiated.UploadID, first, r, size); err != nil {
+		abort := url.Values{"uploadId": {initiated.UploadID}}
+		if res, abortErr := fs.do(context.Background(), http.MethodDelete, key, abort, nil, nil); abortErr == nil {
+			res.Body.Close()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (fs FileStore) uploadParts(ctx context.Context, key, uploadID string, part []byte, r io.Reader, size int64) error {
+	var complete completeMultipartUpload
+	var written int64
+	for eof := false; len(part) > 0; {
+		number := len(complete.Parts) + 1
+		query := url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {uploadID},
+		}
+
+		res, err := fs.do(ctx, http.MethodPut, key, query, part, nil)
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+		res.Body.Close()
+
+		complete.Parts = append(complete.Parts, completedPart{
+			PartNumber: number,
+			ETag:       res.Header.Get("ETag"),
+		})
+
+		written += int64(len(part))
+		if eof {
+			break
+		}
+
+		remaining := int64(-1)
+		if size >= 0 {
+			remaining = size - written
+		}
+
+		part, eof, err = fs.readPart(r, remaining)
+		if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+
+	if size >= 0 && written != size {
+		return fmt.Errorf("failed to write object: expected %d bytes, got %d", size, written)
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return fmt.Errorf("failed to encode multipart upload: %w", err)
+	}
+
+	res, err := fs.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, nil)
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	defer res.Body.Close()
+
+	// S3 can report a failed completion with a 200 and an error document
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	if bytes.Contains(respBody, []byte("<Error>")) {
+		return fmt.Errorf("failed to complete multipart upload: %w", parseError(res.StatusCode, respBody))
+	}
+
+	return nil
+}
+
+func (fs FileStore) encryptionHeaders() http.Header {
+	headers := http.Header{}
+	if fs.cfg.SSE == SSENone {
+		return headers
+	}
+
+	headers.Set("X-Amz-Server-Side-Encryption", fs.cfg.SSE)
+	if fs.cfg.SSE == SSEKMS && fs.cfg.KMSKeyID != "" {
+		headers.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", fs.cfg.KMSKeyID)
+	}
+
+	return headers
+}
+
+// bucketURL returns the URL of the bucket itself.
+func (fs FileStore) bucketURL(query url.Values) *url.URL {
+	return fs.url("", query)
+}
+
+// objectURL returns the URL of the object with the given key.
+func (fs FileStore) objectURL(key string, query url.Values) *url.URL {
+	return fs.url(strings.TrimPrefix(fs.cfg.Prefix+key, "/"), query)
+}
+
+func (fs FileStore) url(objectKey string, query url.Values) *url.URL {
+	u := *fs.endpoint
+	objectPath := strings.TrimSuffix(u.Path, "/")
+	if fs.cfg.PathStyle {
+		objectPath += "/" + fs.cfg.Bucket
+	} else {
+		u.Host = fs.cfg.Bucket + "." + u.Host
+	}
+
+	u.Path = objectPath + "/" + objectKey
+	u.RawPath = canonicalURI(&u)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do sends a signed request for an object.
+func (fs FileStore) do(ctx context.Context, method, key string, query url.Values, body []byte, headers http.Header) (*http.Response, error) {
+	return fs.send(ctx, method, fs.objectURL(key, query), body, headers)
+}
+
+// send sends a signed request, turning any non-2xx response into an *Error.
+func (fs FileStore) send(ctx context.Context, method string, u *url.URL, body []byte, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+
+	req.ContentLength = int64(len(body))
+	req.Header.Set("X-Amz-Content-Sha256", hashHex(body))
+	fs.signer.sign(req, fs.now())
+
+	res, err := fs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		data, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64<<10))
+		return nil, parseError(res.StatusCode, data)
+	}
+
+	return res, nil
+}
+
+// An Error is an error response from the S3 API.
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func parseError(status int, body []byte) error {
+	apiErr := &Error{StatusCode: status}
+	xml.Unmarshal(body, apiErr)
+	return apiErr
+}
@@ -0,0 +1,470 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/s3"
+)
+
+// fakeS3 is an in-memory, path-style S3 API supporting just what the FileStore uses.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	headers  map[string]http.Header
+	requests []string
+	failPart int
+	pageSize int
+}
+
+func newFakeS3() (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{
+		objects: make(map[string][]byte),
+		uploads: make(map[string]map[int][]byte),
+		headers: make(map[string]http.Header),
+	}
+
+	return fake, httptest.NewServer(fake)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	query := r.URL.Query()
+	key := r.URL.Path
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "missing signature")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[id] = make(map[int][]byte)
+		f.headers[key] = r.Header.Clone()
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		var number int
+		fmt.Sscan(query.Get("partNumber"), &number)
+		if number == f.failPart {
+			writeS3Error(w, http.StatusInternalServerError, "InternalError", "forced")
+			return
+		}
+
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		xml.Unmarshal(body, &complete)
+
+		parts := f.uploads[query.Get("uploadId")]
+		var numbers []int
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+
+		var object []byte
+		for _, number := range numbers {
+			object = append(object, parts[number]...)
+		}
+
+		f.objects[key] = object
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		f.headers[key] = r.Header.Clone()
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Length", fmt.Sprint(len(object)))
+		w.Header().Set("Last-Modified", "Thu, 04 Mar 2021 05:06:07 GMT")
+		w.Header().Set("ETag", etag(object))
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		f.list(w, key, query)
+	case r.Method == http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+
+		w.Write(object)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// list serves ListObjectsV2 for the bucket at bucketPath, using the last key of a page as the
+// continuation token.
+func (f *fakeS3) list(w http.ResponseWriter, bucketPath string, query url.Values) {
+	var keys []string
+	for key := range f.objects {
+		objectKey := strings.TrimPrefix(key, bucketPath)
+		if strings.HasPrefix(objectKey, query.Get("prefix")) && objectKey > query.Get("continuation-token") {
+			keys = append(keys, objectKey)
+		}
+	}
+	sort.Strings(keys)
+
+	truncated := f.pageSize > 0 && len(keys) > f.pageSize
+	if truncated {
+		keys = keys[:f.pageSize]
+	}
+
+	fmt.Fprint(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`)
+	fmt.Fprintf(w, "<IsTruncated>%t</IsTruncated>", truncated)
+	if truncated {
+		fmt.Fprintf(w, "<NextContinuationToken>%s</NextContinuationToken>", keys[len(keys)-1])
+	}
+
+	for _, key := range keys {
+		object := f.objects[bucketPath+key]
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>2021-03-04T05:06:07.000Z</LastModified><ETag>%s</ETag><Size>%d</Size></Contents>",
+			key, etag(object), len(object))
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+func etag(object []byte) string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(object))
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+func newFileStore(t *testing.T, url string, cfg s3.Config) s3.FileStore {
+	cfg.Endpoint = url
+	cfg.Bucket = "content"
+	cfg.PathStyle = true
+	cfg.AccessKeyID = "access"
+	cfg.SecretAccessKey = "secret"
+
+	fs, err := s3.New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating file store: %s", err)
+	}
+
+	return fs
+}
+
+func Test_WriteRead(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fake, server := newFakeS3()
+	defer server.Close()
+	fs := newFileStore(t, server.URL, s3.Config{Prefix: "blogs", SSE: s3.SSEKMS, KMSKeyID: "key-1"})
+
+	// RUN
+	writeErr := fs.Write(ctx, "posts/a post.md", []byte("# Hello"))
+	data, readErr := fs.Read(ctx, "posts/a post.md")
+
+	// ASSERT
+	if writeErr != nil {
+		t.Fatalf("unexpected error writing: %s", writeErr)
+	}
+
+	if readErr != nil {
+		t.Fatalf("unexpected error reading: %s", readErr)
+	}
+
+	if string(data) != "# Hello" {
+		t.Fatalf("unexpected content: %s", data)
+	}
+
+	headers, ok := fake.headers["/content/blogs/posts/a post.md"]
+	if !ok {
+		t.Fatalf("expected the object to be stored under the bucket and prefix: %v", fake.requests)
+	}
+
+	if headers.Get("X-Amz-Server-Side-Encryption") != s3.SSEKMS || headers.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "key-1" {
+		t.Fatalf("expected server-side encryption headers: %v", headers)
+	}
+}
+
+func Test_Read_NotFound(t *testing.T) {
+	// SETUP
+	_, server := newFakeS3()
+	defer server.Close()
+	fs := newFileStore(t, server.URL, s3.Config{})
+
+	// RUN
+	_, err := fs.Read(context.TODO(), "missing.md")
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+}
+
+func Test_Read_Timeout(t *testing.T) {
+	// SETUP
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+	fs := newFileStore(t, server.URL, s3.Config{Timeout: 50 * time.Millisecond})
+
+	// RUN
+	start := time.Now()
+	_, err := fs.Read(context.TODO(), "slow.md")
+
+	// ASSERT
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Fatalf("expected a stalled request to time out, got %v after %s", err, time.Since(start))
+	}
+}
+
+func Test_Write_Multipart(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fake, server := newFakeS3()
+	defer server.Close()
+	fs := newFileStore(t, server.URL, s3.Config{PartSize: s3.MinPartSize, SSE: s3.SSES3})
+	data := bytes.Repeat([]byte("0123456789"), s3.MinPartSize/10*2+7)
+
+	// RUN
+	err := fs.Write(ctx, "large.md", data)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !bytes.Equal(fake.objects["/content/large.md"], data) {
+		t.Fatal("expected the parts to be reassembled into the original content")
+	}
+
+	var parts int
+	for _, req := range fake.requests {
+		if strings.HasPrefix(req, http.MethodPut) && strings.Contains(req, "partNumber=") {
+			parts++
+		}
+	}
+
+	if parts != 3 {
+		t.Fatalf("unexpected number of parts: %d", parts)
+	}
+
+	if fake.headers["/content/large.md"].Get("X-Amz-Server-Side-Encryption") != s3.SSES3 {
+		t.Fatal("expected encryption to be requested when starting the upload")
+	}
+}
+
+func Test_Write_MultipartAbort(t *testing.T) {
+	// SETUP
+	fake, server := newFakeS3()
+	defer server.Close()
+	fake.failPart = 2
+	fs := newFileStore(t, server.URL, s3.Config{PartSize: s3.MinPartSize})
+	data := bytes.Repeat([]byte("x"), s3.MinPartSize*2+1)
+
+	// RUN
+	err := fs.Write(context.TODO(), "large.md", data)
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(fake.uploads) != 0 {
+		t.Fatal("expected the failed upload to be aborted")
+	}
+
+	if _, ok := fake.objects["/content/large.md"]; ok {
+		t.Fatal("expected no object to be written")
+	}
+}
+
+func Test_CreateOpen_Streaming(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fake, server := newFakeS3()
+	defer server.Close()
+	fs := newFileStore(t, server.URL, s3.Config{PartSize: s3.MinPartSize})
+	data := bytes.Repeat([]byte("x"), s3.MinPartSize*2+5)
+
+	// RUN
+	// hiding the bytes.Reader means the FileStore can't find the length on its own
+	createErr := fs.Create(ctx, "large.md", struct{ io.Reader }{bytes.NewReader(data)}, -1)
+	body, openErr := fs.Open(ctx, "large.md")
+
+	// ASSERT
+	if createErr != nil || openErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", createErr, openErr)
+	}
+	defer body.Close()
+
+	read, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !bytes.Equal(read, data) || !bytes.Equal(fake.objects["/content/large.md"], data) {
+		t.Fatal("expected streamed content to round trip")
+	}
+
+	if len(fake.uploads) != 0 {
+		t.Fatal("expected the upload to be completed")
+	}
+}
+
+func Test_Create_SizeMismatch(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fake, server := newFakeS3()
+	defer server.Close()
+	fs := newFileStore(t, server.URL, s3.Config{PartSize: s3.MinPartSize})
+	large := bytes.Repeat([]byte("x"), s3.MinPartSize+1)
+
+	// RUN
+	smallErr := fs.Create(ctx, "small.md", strings.NewReader("short"), 10)
+	largeErr := fs.Create(ctx, "large.md", bytes.NewReader(large), int64(len(large))+1)
+
+	// ASSERT
+	if smallErr == nil || largeErr == nil {
+		t.Fatalf("expected errors: %v, %v", smallErr, largeErr)
+	}
+
+	if len(fake.objects) != 0 || len(fake.uploads) != 0 {
+		t.Fatal("expected nothing to be stored")
+	}
+}
+
+func Test_StatDelete(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	_, server := newFakeS3()
+	defer server.Close()
+	fs := newFileStore(t, server.URL, s3.Config{Prefix: "blogs"})
+	if err := fs.Write(ctx, "post.md", []byte("# Hello")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	info, statErr := fs.Stat(ctx, "post.md")
+	existsBefore, _ := fs.Exists(ctx, "post.md")
+	deleteErr := fs.Delete(ctx, "post.md")
+	existsAfter, existsErr := fs.Exists(ctx, "post.md")
+	_, missingErr := fs.Stat(ctx, "post.md")
+	deleteAgainErr := fs.Delete(ctx, "post.md")
+
+	// ASSERT
+	if statErr != nil || deleteErr != nil || existsErr != nil || deleteAgainErr != nil {
+		t.Fatalf("unexpected errors: %v, %v, %v, %v", statErr, deleteErr, existsErr, deleteAgainErr)
+	}
+
+	expected := divulge.FileInfo{
+		Key:      "post.md",
+		Size:     7,
+		ModTime:  time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		Checksum: strings.Trim(etag([]byte("# Hello")), `"`),
+	}
+	if info != expected {
+		t.Fatalf("unexpected file info: %+v", info)
+	}
+
+	if !existsBefore || existsAfter {
+		t.Fatalf("unexpected existence: %t, %t", existsBefore, existsAfter)
+	}
+
+	if !errors.Is(missingErr, divulge.ErrNotFound) {
+		t.Fatalf("expected not found, got: %v", missingErr)
+	}
+}
+
+func Test_List(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	fake, server := newFakeS3()
+	defer server.Close()
+	fake.pageSize = 2
+	fs := newFileStore(t, server.URL, s3.Config{Prefix: "blogs"})
+	for _, key := range []string{"posts/c.md", "posts/a.md", "revisions/a", "posts/b.md"} {
+		if err := fs.Write(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// RUN
+	files, err := fs.List(ctx, "posts/")
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var keys []string
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+
+	if strings.Join(keys, ",") != "posts/a.md,posts/b.md,posts/c.md" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	if files[0].Size != int64(len("posts/a.md")) || files[0].ModTime.IsZero() || files[0].Checksum == "" {
+		t.Fatalf("unexpected file info: %+v", files[0])
+	}
+}
+
+func Test_New_Invalid(t *testing.T) {
+	// SETUP
+	cfgs := []s3.Config{
+		{Endpoint: "not a url", Bucket: "content"},
+		{Endpoint: "http://localhost:9000"},
+		{Endpoint: "http://localhost:9000", Bucket: "content", SSE: "rot13"},
+		{Endpoint: "http://localhost:9000", Bucket: "content", PartSize: 1024},
+		{Endpoint: "http://localhost:9000", Bucket: "content", Timeout: -time.Second},
+	}
+
+	for _, cfg := range cfgs {
+		// RUN
+		_, err := s3.New(cfg)
+
+		// ASSERT
+		if err == nil {
+			t.Fatalf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// A Job is a unit of background work run on an interval.
+type Job func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// A Scheduler runs Jobs in the background on fixed intervals.
+type Scheduler struct {
+	logger  *logrus.Logger
+	entries []entry
+}
+
+// New returns a new Scheduler with no Jobs.
+func New(logger *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+	}
+}
+
+// Every registers a Job to be run once per interval. Jobs must be registered before calling Run.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	s.entries = append(s.entries, entry{
+		name:     name,
+		interval: interval,
+		job:      job,
+	})
+}
+
+// Run runs every registered Job until ctx is cancelled, then waits for in flight Jobs to
+// finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range s.entries {
+		wg.Add(1)
+		go func(e entry) {
+			defer wg.Done()
+			s.loop(ctx, e)
+		}(e)
+	}
+
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.job(ctx); err != nil && ctx.Err() == nil {
+			s.logger.WithError(err).WithField("job", e.name).Error("scheduled job failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue returns a Job that publishes every post whose scheduled time has passed, working
+// through them in batches of batchSize.
+func PublishDue(ps divulge.PostScheduler, batchSize int, logger *logrus.Logger) Job {
+	return func(ctx context.Context) error {
+		for {
+			ids, err := ps.PublishDuePosts(ctx, batchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				logger.WithField("post", id).Info("published scheduled post")
+			}
+
+			if len(ids) < batchSize {
+				return nil
+			}
+		}
+	}
+}
+
+// ReconcileContent returns a Job that cleans up orphaned content and logs every Post and
+// Revision whose content is missing.
+func ReconcileContent(r divulge.ContentReconciler, logger *logrus.Logger) Job {
+	return func(ctx context.Context) error {
+		report, err := r.ReconcileContent(ctx)
+		for _, file := range report.Deleted {
+			logger.WithField("key", file.Key).Info("deleted orphaned content")
+		}
+
+		for _, ref := range report.Missing {
+			entry := logger.WithFields(logrus.Fields{
+				"key":  ref.Key,
+				"post": ref.PostID,
+			})
+			if ref.RevisionID != nil {
+				entry = entry.WithField("revision", *ref.RevisionID)
+			}
+
+			entry.Warn("content is missing")
+		}
+
+		return err
+	}
+}
+
+// PurgeTrash returns a Job that permanently purges every post that's been in the trash for
+// longer than retention, working through them in batches of batchSize. Purging goes through
+// posts so content is deleted along with the post. Posts that fail to purge are logged and
+// skipped until the next run, and posts purged by someone else in the meantime are left alone.
+func PurgeTrash(trash divulge.TrashIndex, posts divulge.PostService, retention time.Duration, batchSize int, logger *logrus.Logger) Job {
+	return func(ctx context.Context) error {
+		before := time.Now().Add(-retention)
+		// skipped posts are still in the trash, so each batch asks for enough to get past them
+		skipped := make(map[uuid.UUID]bool)
+		for {
+			limit := batchSize + len(skipped)
+			ids, err := trash.ListTrashedBefore(ctx, before, limit)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				if skipped[id] {
+					continue
+				}
+
+				if err := posts.PurgePost(ctx, id); err != nil {
+					skipped[id] = true
+					if !errors.Is(err, divulge.ErrNotFound) {
+						logger.WithError(err).WithField("post", id).Error("failed to purge trashed post")
+					}
+
+					continue
+				}
+
+				logger.WithField("post", id).Info("purged trashed post")
+			}
+
+			if len(ids) < limit {
+				return nil
+			}
+		}
+	}
+}
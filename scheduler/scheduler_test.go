@@ -0,0 +1,217 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/eriktate/divulge/scheduler"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	return logger
+}
+
+func Test_Scheduler(t *testing.T) {
+	// SETUP
+	ctx, cancel := context.WithCancel(context.Background())
+	sched := scheduler.New(newLogger())
+
+	var runs int32
+	sched.Every("test", time.Millisecond, func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) == 3 {
+			cancel()
+		}
+
+		return errors.New("failures shouldn't stop the job")
+	})
+
+	// RUN
+	done := make(chan struct{})
+	go func() {
+		sched.Run(ctx)
+		close(done)
+	}()
+
+	// ASSERT
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler didn't stop after cancellation")
+	}
+
+	if atomic.LoadInt32(&runs) < 3 {
+		t.Fatalf("expected at least 3 runs, got %d", runs)
+	}
+}
+
+func Test_PublishDue(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	batches := [][]uuid.UUID{
+		{uuid.New(), uuid.New()},
+		{uuid.New()},
+	}
+	mockSched := &mock.PostScheduler{
+		PublishDuePostsFn: func(ctx context.Context, limit int) ([]uuid.UUID, error) {
+			batch := batches[0]
+			batches = batches[1:]
+			return batch, nil
+		},
+	}
+	job := scheduler.PublishDue(mockSched, 2, newLogger())
+
+	// RUN
+	err := job(ctx)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mockSched.PublishDuePostsCount != 2 {
+		t.Fatalf("expected to keep publishing until a partial batch, got %d calls", mockSched.PublishDuePostsCount)
+	}
+}
+
+func Test_PublishDue_Error(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockSched := &mock.PostScheduler{Error: errors.New("forced")}
+	job := scheduler.PublishDue(mockSched, 10, newLogger())
+
+	// RUN
+	err := job(ctx)
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func Test_ReconcileContent(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	revisionID := uuid.New()
+	mockReconciler := &mock.ContentReconciler{
+		ReconcileContentFn: func(ctx context.Context) (divulge.ContentReport, error) {
+			return divulge.ContentReport{
+				Deleted: []divulge.FileInfo{{Key: "posts/orphan"}},
+				Missing: []divulge.ContentRef{{Key: "posts/gone", PostID: uuid.New(), RevisionID: &revisionID}},
+			}, errors.New("forced")
+		},
+	}
+	job := scheduler.ReconcileContent(mockReconciler, newLogger())
+
+	// RUN
+	err := job(ctx)
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected the reconciliation error to be returned")
+	}
+
+	if mockReconciler.ReconcileContentCount != 1 {
+		t.Fatalf("unexpected number of reconciliations: %d", mockReconciler.ReconcileContentCount)
+	}
+}
+
+func Test_PurgeTrash(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	batches := [][]uuid.UUID{
+		{uuid.New(), uuid.New()},
+		{uuid.New()},
+	}
+	var cutoff time.Time
+	mockTrash := &mock.TrashIndex{
+		ListTrashedBeforeFn: func(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error) {
+			cutoff = before
+			batch := batches[0]
+			batches = batches[1:]
+			return batch, nil
+		},
+	}
+	mockPS := &mock.PostService{}
+	job := scheduler.PurgeTrash(mockTrash, mockPS, 24*time.Hour, 2, newLogger())
+
+	// RUN
+	err := job(ctx)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mockPS.PurgePostCount != 3 {
+		t.Fatalf("expected every expired post to be purged, got %d", mockPS.PurgePostCount)
+	}
+
+	if age := time.Since(cutoff); age < 24*time.Hour || age > 25*time.Hour {
+		t.Fatalf("unexpected cutoff: %s", cutoff)
+	}
+}
+
+func Test_PurgeTrash_Error(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	failing := uuid.New()
+	purgedElsewhere := uuid.New()
+	trashed := []uuid.UUID{failing, purgedElsewhere, uuid.New(), uuid.New()}
+	mockTrash := &mock.TrashIndex{
+		ListTrashedBeforeFn: func(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error) {
+			if limit > len(trashed) {
+				limit = len(trashed)
+			}
+
+			return trashed[:limit], nil
+		},
+	}
+	var purged []uuid.UUID
+	mockPS := &mock.PostService{
+		PurgePostFn: func(ctx context.Context, id uuid.UUID) error {
+			switch id {
+			case failing:
+				return errors.New("forced")
+			case purgedElsewhere:
+				return divulge.NotFoundError("post not found", nil)
+			}
+
+			for i, trashedID := range trashed {
+				if trashedID == id {
+					trashed = append(trashed[:i:i], trashed[i+1:]...)
+					break
+				}
+			}
+
+			purged = append(purged, id)
+			return nil
+		},
+	}
+	job := scheduler.PurgeTrash(mockTrash, mockPS, time.Hour, 2, newLogger())
+
+	// RUN
+	err := job(ctx)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(purged) != 2 || len(trashed) != 2 {
+		t.Fatalf("expected failures not to block the posts behind them, purged %v", purged)
+	}
+
+	if mockPS.PurgePostCount != 4 {
+		t.Fatalf("expected each post to be tried once, got %d purges", mockPS.PurgePostCount)
+	}
+}
@@ -0,0 +1,695 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/totp"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Defaults for an AuthConfig.
+const (
+	DefaultSessionTTL   = 14 * 24 * time.Hour
+	DefaultLoginLinkTTL = 15 * time.Minute
+	DefaultTOTPIssuer   = "Divulge"
+)
+
+// pendingSessionTTL is how long users have to provide their second factor after their first.
+const pendingSessionTTL = 5 * time.Minute
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time.
+const RecoveryCodeCount = 10
+
+// recoveryCodeBytes is how much randomness goes into a recovery code, which is enough for each
+// to be stored as a plain SHA-256 hash.
+const recoveryCodeBytes = 10
+
+// tokenBytes is how much randomness goes into session, login link and API tokens.
+const tokenBytes = 32
+
+// APITokenPrefix starts every APIToken, which makes them easy to spot, e.g. by secret scanners.
+// The first few characters after it are kept as the token's Prefix.
+const APITokenPrefix = "dvg_"
+
+const apiTokenPrefixLength = len(APITokenPrefix) + 8
+
+// errBadLogin is returned for every failed login so callers can't tell unknown emails from
+// wrong passwords.
+var errBadLogin = divulge.UnauthorizedError("invalid email or password", nil)
+
+// errBadCode is returned for every second factor that isn't accepted, whether it's wrong or has
+// already been used.
+var errBadCode = divulge.UnauthorizedError("invalid code", nil)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// dummyHash is compared against when logging in as an unknown user so that takes as long as a
+// wrong password does. It's generated the first time it's needed.
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// AuthConfig configures an Authenticator.
+type AuthConfig struct {
+	SessionTTL time.Duration
+	// LoginLinkURL is where emailed LoginLinks point, with the token added as the token query
+	// parameter. Following a link shouldn't be enough to log in, since mail scanners follow links
+	// too, so the page there should POST the token back to the API.
+	LoginLinkURL string
+	LoginLinkTTL time.Duration
+	// TOTPIssuer is the name authenticator apps show next to their codes.
+	TOTPIssuer string
+}
+
+// An Authenticator implements the divulge.Authenticator interface with bcrypt password hashes,
+// opaque session and API tokens, and TOTP second factors. Tokens and recovery codes are only
+// ever stored as SHA-256 hashes.
+type Authenticator struct {
+	users     divulge.UserService
+	creds     divulge.CredentialService
+	sessions  divulge.SessionService
+	tokens    divulge.APITokenService
+	links     divulge.LoginLinkService
+	twoFactor divulge.TwoFactorService
+	mailer    divulge.Mailer
+	cfg       AuthConfig
+}
+
+// NewAuthenticator returns a new Authenticator. LoginLinks are sent through mailer.
+func NewAuthenticator(users divulge.UserService, creds divulge.CredentialService, sessions divulge.SessionService, tokens divulge.APITokenService, links divulge.LoginLinkService, twoFactor divulge.TwoFactorService, mailer divulge.Mailer, cfg AuthConfig) Authenticator {
+	return Authenticator{
+		users:     users,
+		creds:     creds,
+		sessions:  sessions,
+		tokens:    tokens,
+		links:     links,
+		twoFactor: twoFactor,
+		mailer:    mailer,
+		cfg:       cfg,
+	}
+}
+
+// SetPassword replaces a user's password. Every Session the user has is revoked, so anyone
+// holding an old one has to log in again. APITokens are left alone.
+func (a Authenticator) SetPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	if err := divulge.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := a.creds.SaveCredentials(ctx, divulge.Credentials{UserID: userID, PasswordHash: string(hash)}); err != nil {
+		return err
+	}
+
+	if err := a.sessions.RevokeSessionsByUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// ChangePassword sets a new password after checking the current one. Users without a password
+// yet don't need to provide one.
+func (a Authenticator) ChangePassword(ctx context.Context, userID uuid.UUID, current, password string) error {
+	creds, err := a.creds.FetchCredentials(ctx, userID)
+	if err != nil && !errors.Is(err, divulge.ErrNotFound) {
+		return err
+	}
+
+	if err == nil && bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(current)) != nil {
+		return divulge.UnauthorizedError("current password is incorrect", nil)
+	}
+
+	return a.SetPassword(ctx, userID, password)
+}
+
+// Login checks a user's password and starts a new Session, which is Pending if they use
+// two-factor authentication. The returned Session is the only place its Token is available.
+func (a Authenticator) Login(ctx context.Context, email, password string) (divulge.Session, error) {
+	creds, err := a.creds.FetchCredentialsByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+			return divulge.Session{}, errBadLogin
+		}
+
+		return divulge.Session{}, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(password)) != nil {
+		return divulge.Session{}, errBadLogin
+	}
+
+	return a.login(ctx, creds.UserID)
+}
+
+// login starts a Session for a user that's proven who they are with their first factor. Users
+// with two-factor authentication get a short lived Pending Session until they provide their
+// second.
+func (a Authenticator) login(ctx context.Context, userID uuid.UUID) (divulge.Session, error) {
+	secret, err := a.twoFactor.FetchTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, divulge.ErrNotFound) {
+		return divulge.Session{}, err
+	}
+
+	pending := err == nil && secret.ConfirmedAt != nil
+	return a.startSession(ctx, userID, pending, false)
+}
+
+// startSession creates a Session for a user that's already proven who they are. twoFactor
+// Sessions are only started once they've given a second factor too.
+func (a Authenticator) startSession(ctx context.Context, userID uuid.UUID, pending, twoFactor bool) (divulge.Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return divulge.Session{}, err
+	}
+
+	ttl := a.cfg.SessionTTL
+	if pending {
+		ttl = pendingSessionTTL
+	}
+
+	now := time.Now().UTC()
+	session := divulge.Session{
+		UserID:    userID,
+		Token:     token,
+		TokenHash: hashToken(token),
+		Pending:   pending,
+		TwoFactor: twoFactor,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	id, err := a.sessions.CreateSession(ctx, session)
+	if err != nil {
+		return divulge.Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	session.ID = id
+	return session, nil
+}
+
+// Authenticate returns the User an active Session token belongs to. Pending Sessions aren't
+// accepted.
+func (a Authenticator) Authenticate(ctx context.Context, token string) (divulge.User, divulge.Session, error) {
+	session, err := a.sessions.FetchSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.User{}, session, divulge.UnauthorizedError("session expired", err)
+		}
+
+		return divulge.User{}, session, err
+	}
+
+	if session.Pending {
+		return divulge.User{}, session, divulge.UnauthorizedError("two-factor verification required", nil)
+	}
+
+	user, err := a.users.FetchUser(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return user, session, divulge.UnauthorizedError("session expired", err)
+		}
+
+		return user, session, err
+	}
+
+	return user, session, nil
+}
+
+// Logout revokes the Session a token belongs to. Logging out of a Session that's already
+// expired or been revoked is not an error.
+func (a Authenticator) Logout(ctx context.Context, token string) error {
+	session, err := a.sessions.FetchSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := a.sessions.RevokeSession(ctx, session.UserID, session.ID); err != nil && !errors.Is(err, divulge.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// ListSessions passes off to a SessionService to list a user's active Sessions.
+func (a Authenticator) ListSessions(ctx context.Context, userID uuid.UUID) ([]divulge.Session, error) {
+	return a.sessions.ListSessionsByUser(ctx, userID)
+}
+
+// RevokeSession passes off to a SessionService to revoke one of a user's Sessions.
+func (a Authenticator) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	return a.sessions.RevokeSession(ctx, userID, id)
+}
+
+// RequestLoginLink emails a LoginLink to the User with the given email. To keep emails from
+// being discovered, asking for a link for an email nobody uses isn't an error, it just doesn't
+// send anything.
+func (a Authenticator) RequestLoginLink(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return divulge.ValidationError("email is required", nil)
+	}
+
+	user, err := a.users.FetchUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	linkURL, err := url.Parse(a.cfg.LoginLinkURL)
+	if err != nil {
+		return fmt.Errorf("invalid login link url: %w", err)
+	}
+
+	query := linkURL.Query()
+	query.Set("token", token)
+	linkURL.RawQuery = query.Encode()
+
+	now := time.Now().UTC()
+	link := divulge.LoginLink{
+		UserID:    user.ID,
+		Token:     token,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.cfg.LoginLinkTTL),
+	}
+
+	if _, err := a.links.CreateLoginLink(ctx, link); err != nil {
+		return fmt.Errorf("failed to create login link: %w", err)
+	}
+
+	msg := divulge.Message{
+		To:      user.Email,
+		Subject: "Your Divulge login link",
+		Body:    fmt.Sprintf(loginLinkBody, user.Name, minutes(a.cfg.LoginLinkTTL), linkURL),
+	}
+
+	if err := a.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send login link: %w", err)
+	}
+
+	return nil
+}
+
+const loginLinkBody = `Hi %s,
+
+Use the link below to log in to Divulge. It can only be used once and expires in %s.
+
+%s
+
+If you didn't ask to log in, you can safely ignore this email.
+`
+
+// LoginWithLink uses up a LoginLink's token and starts a new Session for its User, which is
+// Pending if they use two-factor authentication.
+func (a Authenticator) LoginWithLink(ctx context.Context, token string) (divulge.Session, error) {
+	link, err := a.links.ConsumeLoginLink(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.Session{}, divulge.UnauthorizedError("invalid or expired login link", err)
+		}
+
+		return divulge.Session{}, err
+	}
+
+	if _, err := a.users.FetchUser(ctx, link.UserID); err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.Session{}, divulge.UnauthorizedError("invalid or expired login link", err)
+		}
+
+		return divulge.Session{}, err
+	}
+
+	return a.login(ctx, link.UserID)
+}
+
+// VerifyLogin trades a Pending Session's token and a TOTP or recovery code for a new TwoFactor
+// Session. The Pending Session is used up either way, so each guess at a code costs a whole
+// login.
+func (a Authenticator) VerifyLogin(ctx context.Context, token, code string) (divulge.Session, error) {
+	pending, err := a.sessions.FetchSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.Session{}, divulge.UnauthorizedError("login expired", err)
+		}
+
+		return divulge.Session{}, err
+	}
+
+	if !pending.Pending {
+		return divulge.Session{}, divulge.UnauthorizedError("login expired", nil)
+	}
+
+	// revoking first means only one request can ever verify a Pending Session
+	if err := a.sessions.RevokeSession(ctx, pending.UserID, pending.ID); err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.Session{}, divulge.UnauthorizedError("login expired", err)
+		}
+
+		return divulge.Session{}, err
+	}
+
+	if err := a.verifyCode(ctx, pending.UserID, code); err != nil {
+		return divulge.Session{}, err
+	}
+
+	return a.startSession(ctx, pending.UserID, false, true)
+}
+
+// EnrollTOTP generates a new TOTP secret for a user. It doesn't count as a second factor until
+// it's confirmed, and enrolling again before then replaces it.
+func (a Authenticator) EnrollTOTP(ctx context.Context, userID uuid.UUID) (divulge.TOTPEnrollment, error) {
+	user, err := a.users.FetchUser(ctx, userID)
+	if err != nil {
+		return divulge.TOTPEnrollment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return divulge.TOTPEnrollment{}, err
+	}
+
+	if err := a.twoFactor.SaveTOTP(ctx, divulge.TOTP{UserID: userID, Secret: secret}); err != nil {
+		return divulge.TOTPEnrollment{}, err
+	}
+
+	return divulge.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(a.cfg.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP turns on two-factor authentication once a user enters a code from their newly
+// enrolled authenticator app, and returns their first recovery codes.
+//
+// Sessions and APITokens created before then never gave a second factor, and may belong to
+// whoever the user is turning two-factor authentication on to keep out. They're all revoked, and
+// the caller gets a new TwoFactor Session in place of the one they confirmed from.
+func (a Authenticator) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) (divulge.Session, []string, error) {
+	secret, err := a.twoFactor.FetchTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.Session{}, nil, divulge.ValidationError("enroll in two-factor authentication first", err)
+		}
+
+		return divulge.Session{}, nil, err
+	}
+
+	if secret.ConfirmedAt != nil {
+		return divulge.Session{}, nil, divulge.ConflictError("two-factor authentication is already enabled", nil)
+	}
+
+	step, ok := totp.Validate(secret.Secret, code, time.Now())
+	if !ok {
+		return divulge.Session{}, nil, errBadCode
+	}
+
+	if err := a.useStep(ctx, userID, step); err != nil {
+		return divulge.Session{}, nil, err
+	}
+
+	if err := a.sessions.RevokeSessionsByUser(ctx, userID); err != nil {
+		return divulge.Session{}, nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := a.tokens.RevokeAPITokensByUser(ctx, userID); err != nil {
+		return divulge.Session{}, nil, fmt.Errorf("failed to revoke api tokens: %w", err)
+	}
+
+	session, err := a.startSession(ctx, userID, false, true)
+	if err != nil {
+		return divulge.Session{}, nil, err
+	}
+
+	codes, err := a.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return divulge.Session{}, nil, err
+	}
+
+	return session, codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication given a current TOTP or recovery code.
+func (a Authenticator) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := a.verifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return a.twoFactor.RemoveTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes given a current TOTP or recovery
+// code.
+func (a Authenticator) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := a.verifyCode(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	return a.newRecoveryCodes(ctx, userID)
+}
+
+// verifyCode checks and uses up a TOTP or recovery code of a user with two-factor
+// authentication.
+func (a Authenticator) verifyCode(ctx context.Context, userID uuid.UUID, code string) error {
+	secret, err := a.twoFactor.FetchTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, divulge.ErrNotFound) {
+		return err
+	}
+
+	if err != nil || secret.ConfirmedAt == nil {
+		return divulge.ConflictError("two-factor authentication isn't enabled", err)
+	}
+
+	if step, ok := totp.Validate(secret.Secret, code, time.Now()); ok {
+		return a.useStep(ctx, userID, step)
+	}
+
+	if err := a.twoFactor.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return errBadCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// useStep records a TOTP code as used, refusing one that already has been.
+func (a Authenticator) useStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	if err := a.twoFactor.UseTOTPStep(ctx, userID, step); err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return errBadCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// newRecoveryCodes replaces a user's recovery codes with new ones. They look like
+// abcd-efgh-ijkl-mnop and are only stored hashed.
+func (a Authenticator) newRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	buf := make([]byte, recoveryCodeBytes)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))
+		codes[i] = raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:]
+		hashes[i] = hashToken(raw)
+	}
+
+	if err := a.twoFactor.SaveRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode forgives the ways people retype recovery codes.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// CreateAPIToken issues a new APIToken for token.UserID with the given name, scopes and expiry.
+// The returned APIToken is the only place its Token is available. It counts as verified with
+// two-factor authentication only if the credentials behind ctx were.
+func (a Authenticator) CreateAPIToken(ctx context.Context, token divulge.APIToken) (divulge.APIToken, error) {
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return token, divulge.ValidationError("name is required", nil)
+	}
+
+	scopes, err := cleanScopes(token.Scopes)
+	if err != nil {
+		return token, err
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return token, divulge.ValidationError("expiresAt must be in the future", nil)
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		return token, err
+	}
+
+	token.Token = APITokenPrefix + secret
+	token.Prefix = token.Token[:apiTokenPrefixLength]
+	token.TokenHash = hashToken(token.Token)
+	token.Scopes = scopes
+	token.CreatedAt = now
+	token.LastUsedAt = nil
+	token.RevokedAt = nil
+	token.TwoFactor = divulge.HasTwoFactor(ctx)
+
+	id, err := a.tokens.CreateAPIToken(ctx, token)
+	if err != nil {
+		return divulge.APIToken{}, fmt.Errorf("failed to create api token: %w", err)
+	}
+
+	token.ID = id
+	return token, nil
+}
+
+// AuthenticateAPIToken returns the User an active APIToken belongs to and records that it was
+// used.
+func (a Authenticator) AuthenticateAPIToken(ctx context.Context, raw string) (divulge.User, divulge.APIToken, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return divulge.User{}, divulge.APIToken{}, divulge.UnauthorizedError("invalid token", nil)
+	}
+
+	token, err := a.tokens.FetchAPITokenByHash(ctx, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return divulge.User{}, token, divulge.UnauthorizedError("invalid token", err)
+		}
+
+		return divulge.User{}, token, err
+	}
+
+	user, err := a.users.FetchUser(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, divulge.ErrNotFound) {
+			return user, token, divulge.UnauthorizedError("invalid token", err)
+		}
+
+		return user, token, err
+	}
+
+	if err := a.tokens.TouchAPIToken(ctx, token.ID); err != nil {
+		return user, token, fmt.Errorf("failed to record api token use: %w", err)
+	}
+
+	return user, token, nil
+}
+
+// ListAPITokens passes off to an APITokenService to list a user's APITokens.
+func (a Authenticator) ListAPITokens(ctx context.Context, userID uuid.UUID) ([]divulge.APIToken, error) {
+	return a.tokens.ListAPITokensByUser(ctx, userID)
+}
+
+// RevokeAPIToken passes off to an APITokenService to revoke one of a user's APITokens.
+func (a Authenticator) RevokeAPIToken(ctx context.Context, userID, id uuid.UUID) error {
+	return a.tokens.RevokeAPIToken(ctx, userID, id)
+}
+
+// cleanScopes dedupes scopes, making sure there's at least one and that they all exist.
+func cleanScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(divulge.Scopes))
+	for _, scope := range divulge.Scopes {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	var cleaned []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !known[scope] {
+			return nil, divulge.ValidationError(fmt.Sprintf("unknown scope: %q", scope), nil)
+		}
+
+		if seen[scope] {
+			continue
+		}
+
+		seen[scope] = true
+		cleaned = append(cleaned, scope)
+	}
+
+	if len(cleaned) == 0 {
+		return nil, divulge.ValidationError("at least one scope is required", nil)
+	}
+
+	return cleaned, nil
+}
+
+// minutes describes a duration for people, rounded to the minute.
+func minutes(d time.Duration) string {
+	n := int(d.Round(time.Minute) / time.Minute)
+	if n == 1 {
+		return "1 minute"
+	}
+
+	return fmt.Sprintf("%d minutes", n)
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func unknownUserHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	})
+
+	return dummyHash
+}
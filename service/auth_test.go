@@ -0,0 +1,848 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/eriktate/divulge/service"
+	"github.com/eriktate/divulge/totp"
+	"github.com/google/uuid"
+)
+
+// authFixture is an Authenticator backed by in-memory credentials, sessions, APITokens,
+// LoginLinks and second factors for a single user. Sent mail is collected rather than
+// delivered.
+type authFixture struct {
+	user     divulge.User
+	creds    map[uuid.UUID]divulge.Credentials
+	sessions map[string]divulge.Session
+	tokens   map[string]divulge.APIToken
+	links    map[string]divulge.LoginLink
+	totp     *divulge.TOTP
+	recovery map[string]bool
+	sent     []divulge.Message
+	revoked  []uuid.UUID
+
+	credService    *mock.CredentialService
+	sessionService *mock.SessionService
+	tokenService   *mock.APITokenService
+	linkService    *mock.LoginLinkService
+	twoFactor      *mock.TwoFactorService
+	auth           service.Authenticator
+}
+
+func newAuthFixture() *authFixture {
+	f := &authFixture{
+		user:     divulge.User{ID: uuid.New(), Email: "test@test.com"},
+		creds:    make(map[uuid.UUID]divulge.Credentials),
+		sessions: make(map[string]divulge.Session),
+		tokens:   make(map[string]divulge.APIToken),
+		links:    make(map[string]divulge.LoginLink),
+		recovery: make(map[string]bool),
+	}
+
+	users := &mock.UserService{
+		FetchUserFn: func(ctx context.Context, id uuid.UUID) (divulge.User, error) {
+			if id != f.user.ID {
+				return divulge.User{}, divulge.NotFoundError("user not found", nil)
+			}
+
+			return f.user, nil
+		},
+		FetchUserByEmailFn: func(ctx context.Context, email string) (divulge.User, error) {
+			if email != f.user.Email {
+				return divulge.User{}, divulge.NotFoundError("user not found", nil)
+			}
+
+			return f.user, nil
+		},
+	}
+	f.credService = &mock.CredentialService{
+		SaveCredentialsFn: func(ctx context.Context, creds divulge.Credentials) error {
+			f.creds[creds.UserID] = creds
+			return nil
+		},
+		FetchCredentialsFn: func(ctx context.Context, userID uuid.UUID) (divulge.Credentials, error) {
+			creds, ok := f.creds[userID]
+			if !ok {
+				return creds, divulge.NotFoundError("credentials not found", nil)
+			}
+
+			return creds, nil
+		},
+		FetchCredentialsByEmailFn: func(ctx context.Context, email string) (divulge.Credentials, error) {
+			creds, ok := f.creds[f.user.ID]
+			if !ok || email != f.user.Email {
+				return creds, divulge.NotFoundError("credentials not found", nil)
+			}
+
+			return creds, nil
+		},
+	}
+	f.sessionService = &mock.SessionService{
+		CreateSessionFn: func(ctx context.Context, session divulge.Session) (uuid.UUID, error) {
+			session.ID = uuid.New()
+			f.sessions[session.TokenHash] = session
+			return session.ID, nil
+		},
+		FetchSessionByTokenHashFn: func(ctx context.Context, tokenHash string) (divulge.Session, error) {
+			session, ok := f.sessions[tokenHash]
+			if !ok {
+				return session, divulge.NotFoundError("session not found", nil)
+			}
+
+			return session, nil
+		},
+		RevokeSessionFn: func(ctx context.Context, userID, id uuid.UUID) error {
+			for hash, session := range f.sessions {
+				if session.ID == id && session.UserID == userID {
+					delete(f.sessions, hash)
+					f.revoked = append(f.revoked, id)
+					return nil
+				}
+			}
+
+			return divulge.NotFoundError("session not found", nil)
+		},
+		RevokeSessionsByUserFn: func(ctx context.Context, userID uuid.UUID) error {
+			for hash, session := range f.sessions {
+				if session.UserID == userID {
+					delete(f.sessions, hash)
+					f.revoked = append(f.revoked, session.ID)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	f.tokenService = &mock.APITokenService{
+		CreateAPITokenFn: func(ctx context.Context, token divulge.APIToken) (uuid.UUID, error) {
+			token.ID = uuid.New()
+			f.tokens[token.TokenHash] = token
+			return token.ID, nil
+		},
+		FetchAPITokenByHashFn: func(ctx context.Context, tokenHash string) (divulge.APIToken, error) {
+			token, ok := f.tokens[tokenHash]
+			if !ok {
+				return token, divulge.NotFoundError("api token not found", nil)
+			}
+
+			return token, nil
+		},
+		RevokeAPITokensByUserFn: func(ctx context.Context, userID uuid.UUID) error {
+			for hash, token := range f.tokens {
+				if token.UserID == userID {
+					delete(f.tokens, hash)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	f.linkService = &mock.LoginLinkService{
+		CreateLoginLinkFn: func(ctx context.Context, link divulge.LoginLink) (uuid.UUID, error) {
+			link.ID = uuid.New()
+			f.links[link.TokenHash] = link
+			return link.ID, nil
+		},
+		ConsumeLoginLinkFn: func(ctx context.Context, tokenHash string) (divulge.LoginLink, error) {
+			link, ok := f.links[tokenHash]
+			if !ok || link.UsedAt != nil || time.Now().After(link.ExpiresAt) {
+				return link, divulge.NotFoundError("login link not found", nil)
+			}
+
+			now := time.Now()
+			link.UsedAt = &now
+			f.links[tokenHash] = link
+			return link, nil
+		},
+	}
+	f.twoFactor = &mock.TwoFactorService{
+		SaveTOTPFn: func(ctx context.Context, secret divulge.TOTP) error {
+			if f.totp != nil && f.totp.ConfirmedAt != nil {
+				return divulge.ConflictError("two-factor authentication is already enabled", nil)
+			}
+
+			f.totp = &secret
+			return nil
+		},
+		FetchTOTPFn: func(ctx context.Context, userID uuid.UUID) (divulge.TOTP, error) {
+			if f.totp == nil || userID != f.user.ID {
+				return divulge.TOTP{}, divulge.NotFoundError("totp not found", nil)
+			}
+
+			return *f.totp, nil
+		},
+		UseTOTPStepFn: func(ctx context.Context, userID uuid.UUID, step int64) error {
+			if f.totp == nil || step <= f.totp.LastUsedStep {
+				return divulge.NotFoundError("totp step not found", nil)
+			}
+
+			now := time.Now()
+			f.totp.LastUsedStep = step
+			if f.totp.ConfirmedAt == nil {
+				f.totp.ConfirmedAt = &now
+			}
+
+			return nil
+		},
+		RemoveTOTPFn: func(ctx context.Context, userID uuid.UUID) error {
+			f.totp = nil
+			f.recovery = make(map[string]bool)
+			return nil
+		},
+		SaveRecoveryCodesFn: func(ctx context.Context, userID uuid.UUID, hashes []string) error {
+			f.recovery = make(map[string]bool)
+			for _, hash := range hashes {
+				f.recovery[hash] = true
+			}
+
+			return nil
+		},
+		UseRecoveryCodeFn: func(ctx context.Context, userID uuid.UUID, hash string) error {
+			if !f.recovery[hash] {
+				return divulge.NotFoundError("recovery code not found", nil)
+			}
+
+			f.recovery[hash] = false
+			return nil
+		},
+	}
+	mailer := &mock.Mailer{
+		SendFn: func(ctx context.Context, msg divulge.Message) error {
+			f.sent = append(f.sent, msg)
+			return nil
+		},
+	}
+
+	f.auth = service.NewAuthenticator(users, f.credService, f.sessionService, f.tokenService, f.linkService, f.twoFactor, mailer, service.AuthConfig{
+		SessionTTL:   time.Hour,
+		LoginLinkURL: "https://example.com/login",
+		LoginLinkTTL: 15 * time.Minute,
+		TOTPIssuer:   "Divulge",
+	})
+	return f
+}
+
+func Test_Login(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	session, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	user, authenticated, err := f.auth.Authenticate(ctx, session.Token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if f.creds[f.user.ID].PasswordHash == "correct horse" {
+		t.Fatal("expected the password to be hashed")
+	}
+
+	if session.Token == "" || session.TokenHash == session.Token {
+		t.Fatalf("expected only a hash of the token to be stored, got %+v", session)
+	}
+
+	if _, ok := f.sessions[session.TokenHash]; !ok {
+		t.Fatal("expected the session to be stored by its token hash")
+	}
+
+	if expires := time.Until(session.ExpiresAt); expires < 59*time.Minute || expires > time.Hour {
+		t.Fatalf("unexpected expiry: %s", session.ExpiresAt)
+	}
+
+	if user.ID != f.user.ID || authenticated.ID != session.ID {
+		t.Fatalf("unexpected authentication: %+v, %+v", user, authenticated)
+	}
+}
+
+func Test_Login_Rejected(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	_, wrongPassword := f.auth.Login(ctx, f.user.Email, "battery staple")
+	_, unknownUser := f.auth.Login(ctx, "nobody@test.com", "correct horse")
+
+	// ASSERT
+	if !errors.Is(wrongPassword, divulge.ErrUnauthorized) || !errors.Is(unknownUser, divulge.ErrUnauthorized) {
+		t.Fatalf("unexpected errors: %v, %v", wrongPassword, unknownUser)
+	}
+
+	if wrongPassword.Error() != unknownUser.Error() {
+		t.Fatalf("expected failures to be indistinguishable, got %q and %q", wrongPassword, unknownUser)
+	}
+
+	if f.sessionService.CreateSessionCount != 0 {
+		t.Fatal("expected no session to be created")
+	}
+}
+
+func Test_SetPassword_Invalid(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	long := make([]byte, divulge.MaxPasswordLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	for _, password := range []string{"", "short", string(long)} {
+		// RUN
+		err := f.auth.SetPassword(ctx, f.user.ID, password)
+
+		// ASSERT
+		if !errors.Is(err, divulge.ErrValidation) {
+			t.Fatalf("expected a validation error for %q, got %v", password, err)
+		}
+	}
+
+	if f.credService.SaveCredentialsCount != 0 {
+		t.Fatal("expected nothing to be saved")
+	}
+}
+
+func Test_ChangePassword(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	session, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	wrongErr := f.auth.ChangePassword(ctx, f.user.ID, "battery staple", "new password")
+	changeErr := f.auth.ChangePassword(ctx, f.user.ID, "correct horse", "new password")
+	_, oldErr := f.auth.Login(ctx, f.user.Email, "correct horse")
+	_, newErr := f.auth.Login(ctx, f.user.Email, "new password")
+	_, _, authErr := f.auth.Authenticate(ctx, session.Token)
+
+	// ASSERT
+	if !errors.Is(wrongErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected the wrong current password to be rejected, got %v", wrongErr)
+	}
+
+	if changeErr != nil || newErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", changeErr, newErr)
+	}
+
+	if !errors.Is(oldErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected the old password to stop working, got %v", oldErr)
+	}
+
+	if !errors.Is(authErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected existing sessions to be revoked, got %v", authErr)
+	}
+}
+
+func Test_Logout(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	session, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	if err := f.auth.Logout(ctx, session.Token); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	againErr := f.auth.Logout(ctx, session.Token)
+	_, _, authErr := f.auth.Authenticate(ctx, session.Token)
+
+	// ASSERT
+	if len(f.revoked) != 1 || f.revoked[0] != session.ID {
+		t.Fatalf("unexpected revocations: %v", f.revoked)
+	}
+
+	if againErr != nil {
+		t.Fatalf("expected logging out twice to be fine, got %v", againErr)
+	}
+
+	if !errors.Is(authErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected the session to stop working, got %v", authErr)
+	}
+}
+
+func Test_Authenticate_RemovedUser(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	session, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f.user.ID = uuid.New()
+
+	// RUN
+	_, _, err = f.auth.Authenticate(ctx, session.Token)
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrUnauthorized) {
+		t.Fatalf("expected sessions of removed users to stop working, got %v", err)
+	}
+}
+
+// linkToken pulls the token out of the login link in a sent message.
+func linkToken(t *testing.T, msg divulge.Message) string {
+	start := strings.Index(msg.Body, "https://example.com/login?")
+	if start < 0 {
+		t.Fatalf("expected a login link, got:\n%s", msg.Body)
+	}
+
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return link.Query().Get("token")
+}
+
+func Test_LoginWithLink(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+
+	// RUN
+	if err := f.auth.RequestLoginLink(ctx, " "+f.user.Email+" "); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(f.sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(f.sent))
+	}
+
+	token := linkToken(t, f.sent[0])
+	session, err := f.auth.LoginWithLink(ctx, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, againErr := f.auth.LoginWithLink(ctx, token)
+
+	// ASSERT
+	if f.sent[0].To != f.user.Email || !strings.Contains(f.sent[0].Body, "15 minutes") {
+		t.Fatalf("unexpected email: %+v", f.sent[0])
+	}
+
+	if _, ok := f.links[token]; ok {
+		t.Fatal("expected only a hash of the token to be stored")
+	}
+
+	if session.UserID != f.user.ID || session.Token == "" {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+
+	if !errors.Is(againErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a link to only work once, got %v", againErr)
+	}
+}
+
+func Test_LoginWithLink_Rejected(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.RequestLoginLink(ctx, f.user.Email); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	token := linkToken(t, f.sent[0])
+	for hash, link := range f.links {
+		link.ExpiresAt = time.Now().Add(-time.Second)
+		f.links[hash] = link
+	}
+
+	// RUN
+	unknownErr := f.auth.RequestLoginLink(ctx, "nobody@test.com")
+	_, expiredErr := f.auth.LoginWithLink(ctx, token)
+	_, invalidErr := f.auth.LoginWithLink(ctx, "not-a-token")
+
+	// ASSERT
+	if unknownErr != nil {
+		t.Fatalf("expected unknown emails not to be revealed, got %v", unknownErr)
+	}
+
+	if len(f.sent) != 1 || f.linkService.CreateLoginLinkCount != 1 {
+		t.Fatal("expected nothing to be sent to unknown emails")
+	}
+
+	if !errors.Is(expiredErr, divulge.ErrUnauthorized) || !errors.Is(invalidErr, divulge.ErrUnauthorized) {
+		t.Fatalf("unexpected errors: %v, %v", expiredErr, invalidErr)
+	}
+
+	if f.sessionService.CreateSessionCount != 0 {
+		t.Fatal("expected no session to be created")
+	}
+}
+
+func Test_CreateAPIToken(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+
+	// RUN
+	token, err := f.auth.CreateAPIToken(ctx, divulge.APIToken{
+		UserID: f.user.ID,
+		Name:   "deploys",
+		Scopes: []string{divulge.ScopePostsWrite, divulge.ScopePostsRead, divulge.ScopePostsWrite},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	user, authenticated, err := f.auth.AuthenticateAPIToken(ctx, token.Token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if !strings.HasPrefix(token.Token, service.APITokenPrefix) || !strings.HasPrefix(token.Token, token.Prefix) {
+		t.Fatalf("unexpected token: %q with prefix %q", token.Token, token.Prefix)
+	}
+
+	if token.Prefix == token.Token || token.TokenHash == token.Token {
+		t.Fatalf("expected only a prefix and hash of the token to be stored, got %+v", token)
+	}
+
+	if _, ok := f.tokens[token.TokenHash]; !ok {
+		t.Fatal("expected the token to be stored by its hash")
+	}
+
+	if len(token.Scopes) != 2 {
+		t.Fatalf("expected duplicate scopes to be dropped, got %v", token.Scopes)
+	}
+
+	if user.ID != f.user.ID || authenticated.ID != token.ID {
+		t.Fatalf("unexpected authentication: %+v, %+v", user, authenticated)
+	}
+
+	if f.tokenService.TouchAPITokenCount != 1 {
+		t.Fatal("expected the token's last use to be recorded")
+	}
+}
+
+func Test_CreateAPIToken_Invalid(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	past := time.Now().Add(-time.Minute)
+	tokens := []divulge.APIToken{
+		{UserID: f.user.ID, Scopes: []string{divulge.ScopePostsRead}},
+		{UserID: f.user.ID, Name: "no scopes"},
+		{UserID: f.user.ID, Name: "unknown scope", Scopes: []string{"posts:delete"}},
+		{UserID: f.user.ID, Name: "expired", Scopes: []string{divulge.ScopePostsRead}, ExpiresAt: &past},
+	}
+
+	for _, token := range tokens {
+		// RUN
+		_, err := f.auth.CreateAPIToken(ctx, token)
+
+		// ASSERT
+		if !errors.Is(err, divulge.ErrValidation) {
+			t.Fatalf("expected a validation error for %q, got %v", token.Name, err)
+		}
+	}
+
+	if f.tokenService.CreateAPITokenCount != 0 {
+		t.Fatal("expected nothing to be saved")
+	}
+}
+
+func Test_AuthenticateAPIToken_Invalid(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+
+	for _, raw := range []string{"", "not-a-token", service.APITokenPrefix + "unknown"} {
+		// RUN
+		_, _, err := f.auth.AuthenticateAPIToken(ctx, raw)
+
+		// ASSERT
+		if !errors.Is(err, divulge.ErrUnauthorized) {
+			t.Fatalf("expected %q to be rejected, got %v", raw, err)
+		}
+	}
+
+	if f.tokenService.TouchAPITokenCount != 0 {
+		t.Fatal("expected no token to be touched")
+	}
+}
+
+// enableTwoFactor enrolls the fixture's user in two-factor authentication, returning their
+// secret and recovery codes.
+func (f *authFixture) enableTwoFactor(t *testing.T) (string, []string) {
+	ctx := context.TODO()
+	enrollment, err := f.auth.EnrollTOTP(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	_, codes, err := f.auth.ConfirmTOTP(ctx, f.user.ID, code)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return enrollment.Secret, codes
+}
+
+func Test_EnrollTOTP(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+
+	// RUN
+	enrollment, err := f.auth.EnrollTOTP(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wrongErr := func() error {
+		_, _, err := f.auth.ConfirmTOTP(ctx, f.user.ID, "000000")
+		return err
+	}()
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	session, codes, confirmErr := f.auth.ConfirmTOTP(ctx, f.user.ID, code)
+	_, againErr := f.auth.EnrollTOTP(ctx, f.user.ID)
+
+	// ASSERT
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/Divulge:test@test.com?") || !strings.Contains(enrollment.URI, enrollment.Secret) {
+		t.Fatalf("unexpected provisioning uri: %s", enrollment.URI)
+	}
+
+	if !errors.Is(wrongErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a wrong code to be rejected, got %v", wrongErr)
+	}
+
+	if confirmErr != nil {
+		t.Fatalf("unexpected error: %s", confirmErr)
+	}
+
+	if !session.TwoFactor || session.Token == "" {
+		t.Fatalf("expected a two-factor session, got %+v", session)
+	}
+
+	if len(codes) != service.RecoveryCodeCount || len(f.recovery) != service.RecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %v", service.RecoveryCodeCount, codes)
+	}
+
+	if f.recovery[codes[0]] {
+		t.Fatal("expected recovery codes to be stored hashed")
+	}
+
+	if !errors.Is(againErr, divulge.ErrConflict) {
+		t.Fatalf("expected a confirmed totp not to be replaced, got %v", againErr)
+	}
+}
+
+func Test_ConfirmTOTP_RevokesCredentials(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	before, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	token, err := f.auth.CreateAPIToken(ctx, divulge.APIToken{
+		UserID: f.user.ID,
+		Name:   "before",
+		Scopes: []string{divulge.ScopePostsRead},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	f.enableTwoFactor(t)
+	_, _, sessionErr := f.auth.Authenticate(ctx, before.Token)
+	_, _, tokenErr := f.auth.AuthenticateAPIToken(ctx, token.Token)
+
+	// ASSERT
+	if !errors.Is(sessionErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a session from before enrollment to be revoked, got %v", sessionErr)
+	}
+
+	if !errors.Is(tokenErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected an api token from before enrollment to be revoked, got %v", tokenErr)
+	}
+
+	if len(f.sessions) != 1 {
+		t.Fatalf("expected only the new session to remain, got %d", len(f.sessions))
+	}
+}
+
+func Test_Login_TwoFactor(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	secret, _ := f.enableTwoFactor(t)
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+	// RUN
+	pending, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, _, pendingErr := f.auth.Authenticate(ctx, pending.Token)
+	session, err := f.auth.VerifyLogin(ctx, pending.Token, code)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, _, authErr := f.auth.Authenticate(ctx, session.Token)
+	_, reusedErr := f.auth.VerifyLogin(ctx, pending.Token, code)
+
+	// ASSERT
+	if !pending.Pending || time.Until(pending.ExpiresAt) > 5*time.Minute {
+		t.Fatalf("expected a short lived pending session, got %+v", pending)
+	}
+
+	if !errors.Is(pendingErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a pending session not to authenticate, got %v", pendingErr)
+	}
+
+	if session.Pending || !session.TwoFactor || authErr != nil {
+		t.Fatalf("expected a real two-factor session, got %+v, %v", session, authErr)
+	}
+
+	if !errors.Is(reusedErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a pending session to only be verified once, got %v", reusedErr)
+	}
+}
+
+func Test_VerifyLogin_Rejected(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	secret, _ := f.enableTwoFactor(t)
+	used, _ := totp.Code(secret, totp.Step(time.Now())-1)
+	current, _ := totp.Code(secret, totp.Step(time.Now()))
+
+	first, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	second, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	_, wrongErr := f.auth.VerifyLogin(ctx, first.Token, "123456")
+	_, retryErr := f.auth.VerifyLogin(ctx, first.Token, current)
+	_, replayErr := f.auth.VerifyLogin(ctx, second.Token, used)
+
+	// ASSERT
+	if !errors.Is(wrongErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a wrong code to be rejected, got %v", wrongErr)
+	}
+
+	if !errors.Is(retryErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a wrong code to use up the pending session, got %v", retryErr)
+	}
+
+	if !errors.Is(replayErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected a used code to be rejected, got %v", replayErr)
+	}
+}
+
+func Test_RecoveryCodes(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	f := newAuthFixture()
+	if err := f.auth.SetPassword(ctx, f.user.ID, "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, codes := f.enableTwoFactor(t)
+	retyped := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+
+	first, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	second, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// RUN
+	_, recoveryErr := f.auth.VerifyLogin(ctx, first.Token, retyped)
+	_, reusedErr := f.auth.VerifyLogin(ctx, second.Token, codes[0])
+	disableErr := f.auth.DisableTOTP(ctx, f.user.ID, codes[1])
+
+	third, err := f.auth.Login(ctx, f.user.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ASSERT
+	if recoveryErr != nil {
+		t.Fatalf("unexpected error: %s", recoveryErr)
+	}
+
+	if !errors.Is(reusedErr, divulge.ErrUnauthorized) {
+		t.Fatalf("expected recovery codes to only work once, got %v", reusedErr)
+	}
+
+	if disableErr != nil || f.totp != nil {
+		t.Fatalf("expected two-factor authentication to be disabled, got %v", disableErr)
+	}
+
+	if third.Pending {
+		t.Fatal("expected logins without two-factor authentication not to be pending")
+	}
+}
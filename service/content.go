@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eriktate/divulge"
+)
+
+// DefaultGracePeriod is how old unreferenced content must be before a ContentReconciler
+// deletes it. It leaves saves that have written their content, but not yet committed the post,
+// alone.
+const DefaultGracePeriod = time.Hour
+
+// A ContentReconciler implements the divulge.ContentReconciler interface. It only ever touches
+// content stored by a PostService, so other files sharing the FileStore, like templates, are
+// left alone.
+type ContentReconciler struct {
+	index divulge.ContentIndex
+	fs    divulge.FileStore
+	grace time.Duration
+}
+
+// NewContentReconciler returns a new ContentReconciler that deletes unreferenced content once
+// it's older than grace.
+func NewContentReconciler(index divulge.ContentIndex, fs divulge.FileStore, grace time.Duration) ContentReconciler {
+	return ContentReconciler{
+		index: index,
+		fs:    fs,
+		grace: grace,
+	}
+}
+
+// ReconcileContent deletes orphaned content and reports every reference to content that's
+// missing. Missing content can't be recovered, so it's up to an operator to decide what to do.
+// FileStores that are also divulge.TempSweepers have their stale temporary files deleted too.
+func (r ContentReconciler) ReconcileContent(ctx context.Context) (divulge.ContentReport, error) {
+	report := divulge.ContentReport{
+		Deleted: []divulge.FileInfo{},
+		Missing: []divulge.ContentRef{},
+	}
+
+	// references are listed first so content saved while listing is only ever seen as too new
+	// to delete, never as an orphan
+	refs, err := r.index.ListContentRefs(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to list content references: %w", err)
+	}
+
+	stored := make(map[string]divulge.FileInfo)
+	for _, prefix := range []string{contentPrefix, revisionPrefix} {
+		files, err := r.fs.List(ctx, prefix)
+		if err != nil {
+			return report, fmt.Errorf("failed to list content: %w", err)
+		}
+
+		for _, file := range files {
+			stored[file.Key] = file
+		}
+	}
+
+	referenced := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		referenced[ref.Key] = true
+		if _, ok := stored[ref.Key]; ok {
+			continue
+		}
+
+		// content saved before keys were generated can live anywhere, including at keys the
+		// FileStore won't accept
+		exists := false
+		if ref.Key != "" {
+			exists, err = r.fs.Exists(ctx, ref.Key)
+			if err != nil && !errors.Is(err, divulge.ErrValidation) {
+				return report, fmt.Errorf("failed to check content: %w", err)
+			}
+		}
+
+		if !exists {
+			report.Missing = append(report.Missing, ref)
+		}
+	}
+
+	keys := make([]string, 0, len(stored))
+	for key := range stored {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cutoff := time.Now().Add(-r.grace)
+	for _, key := range keys {
+		file := stored[key]
+		if referenced[key] || file.ModTime.After(cutoff) {
+			continue
+		}
+
+		if err := r.fs.Delete(ctx, key); err != nil {
+			return report, fmt.Errorf("failed to delete orphaned content: %w", err)
+		}
+
+		report.Deleted = append(report.Deleted, file)
+	}
+
+	if sweeper, ok := r.fs.(divulge.TempSweeper); ok {
+		swept, err := sweeper.SweepTemp(ctx, cutoff)
+		report.Deleted = append(report.Deleted, swept...)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/eriktate/divulge/service"
+	"github.com/google/uuid"
+)
+
+func Test_ReconcileContent(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	old := time.Now().Add(-2 * time.Hour)
+	postID := uuid.New()
+	revisionID := uuid.New()
+	refs := []divulge.ContentRef{
+		{Key: "posts/a/current", PostID: postID},
+		{Key: "revisions/p/legacy", PostID: postID, RevisionID: &revisionID},
+		{Key: "posts/a/gone", PostID: postID, RevisionID: &revisionID},
+		{Key: "client/path.md", PostID: postID},
+		{Key: "", PostID: postID},
+	}
+	stored := map[string][]divulge.FileInfo{
+		"posts/": {
+			{Key: "posts/a/current", ModTime: old},
+			{Key: "posts/a/orphan", ModTime: old},
+			{Key: "posts/a/in-flight", ModTime: time.Now()},
+		},
+		"revisions/": {
+			{Key: "revisions/p/legacy", ModTime: old},
+			{Key: "revisions/p/orphan", ModTime: old},
+		},
+	}
+	var deleted []string
+	mockFS := &mock.FileStore{
+		ListFn: func(ctx context.Context, prefix string) ([]divulge.FileInfo, error) {
+			return stored[prefix], nil
+		},
+		ExistsFn: func(ctx context.Context, key string) (bool, error) {
+			return key == "client/path.md", nil
+		},
+		DeleteFn: func(ctx context.Context, key string) error {
+			deleted = append(deleted, key)
+			return nil
+		},
+	}
+	mockIndex := &mock.ContentIndex{
+		ListContentRefsFn: func(ctx context.Context) ([]divulge.ContentRef, error) {
+			return refs, nil
+		},
+	}
+	reconciler := service.NewContentReconciler(mockIndex, mockFS, time.Hour)
+
+	// RUN
+	report, err := reconciler.ReconcileContent(ctx)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(deleted) != 2 || deleted[0] != "posts/a/orphan" || deleted[1] != "revisions/p/orphan" {
+		t.Fatalf("unexpected deleted content: %v", deleted)
+	}
+
+	if len(report.Deleted) != 2 {
+		t.Fatalf("expected deletions to be reported: %+v", report.Deleted)
+	}
+
+	if len(report.Missing) != 2 || report.Missing[0].Key != "posts/a/gone" || report.Missing[1].Key != "" {
+		t.Fatalf("unexpected missing content: %+v", report.Missing)
+	}
+}
+
+func Test_ReconcileContent_IndexError(t *testing.T) {
+	// SETUP
+	mockFS := &mock.FileStore{}
+	reconciler := service.NewContentReconciler(&mock.ContentIndex{Error: errors.New("forced")}, mockFS, time.Hour)
+
+	// RUN
+	_, err := reconciler.ReconcileContent(context.TODO())
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if mockFS.DeleteCount != 0 {
+		t.Fatal("expected nothing to be deleted without knowing what's referenced")
+	}
+}
+
+func Test_ReconcileContent_SweepsTemp(t *testing.T) {
+	// SETUP
+	fs := struct {
+		*mock.FileStore
+		*mock.TempSweeper
+	}{
+		&mock.FileStore{},
+		&mock.TempSweeper{
+			SweepTempFn: func(ctx context.Context, cutoff time.Time) ([]divulge.FileInfo, error) {
+				if time.Since(cutoff) < time.Hour {
+					return nil, errors.New("expected the grace period to protect writes in progress")
+				}
+
+				return []divulge.FileInfo{{Key: "posts/a/.divulge-tmp-1"}}, nil
+			},
+		},
+	}
+	reconciler := service.NewContentReconciler(&mock.ContentIndex{}, fs, time.Hour)
+
+	// RUN
+	report, err := reconciler.ReconcileContent(context.TODO())
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(report.Deleted) != 1 || report.Deleted[0].Key != "posts/a/.divulge-tmp-1" {
+		t.Fatalf("expected swept files to be reported: %+v", report.Deleted)
+	}
+}
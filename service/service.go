@@ -3,38 +3,153 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/diff"
+	"github.com/eriktate/divulge/render"
+	"github.com/eriktate/divulge/slug"
 	"github.com/google/uuid"
 )
 
-// A PostService implements the divulge.PostService interface.
+// A PostService implements the divulge.PostService and divulge.RevisionHistory interfaces.
 type PostService struct {
 	ps divulge.PostService
+	rs divulge.RevisionService
 	fs divulge.FileStore
+
+	renderer *render.Renderer
 }
 
 // NewPostService returns a new PostService.
-func NewPostService(ps divulge.PostService, fs divulge.FileStore) PostService {
+func NewPostService(ps divulge.PostService, rs divulge.RevisionService, fs divulge.FileStore) PostService {
 	return PostService{
 		ps: ps,
+		rs: rs,
 		fs: fs,
+
+		renderer: render.New(render.DefaultCacheSize),
 	}
 }
 
-// SavePost saves the post content in a file store and then passes off to another PostService
-// to persist the metdata.
+// SavePost saves the post content in a file store and then passes off to a RevisionService to
+// persist the metdata. Every save also records a new Revision of the post. Updates without any
+// content keep the post's current content, which the new Revision shares.
+//
+// Saving happens in two phases so the FileStore and the database never disagree. New content is
+// always written under a new key, which the post and its Revision share, and only becomes
+// current once the post and Revision pointing at it are committed together. If that fails the
+// new content is deleted. The previous content stays, since the previous Revision still points
+// at it, and anything left behind by a crash is cleaned up by a ContentReconciler. Any
+// ContentPath set by the caller is ignored.
 func (s PostService) SavePost(ctx context.Context, post divulge.Post) (uuid.UUID, error) {
-	if err := s.fs.Write(ctx, post.ContentPath, []byte(post.Content)); err != nil {
-		return post.ID, fmt.Errorf("failed to write post content: %w", err)
+	if err := validatePost(post); err != nil {
+		return post.ID, err
 	}
 
-	id, err := s.ps.SavePost(ctx, post)
-	if err != nil {
-		return id, err
+	post.Category = strings.TrimSpace(post.Category)
+	post.Tags = cleanTags(post.Tags)
+
+	var current divulge.Post
+	updating := !divulge.IsEmpty(post.ID)
+	if updating {
+		var err error
+		if current, err = s.ps.FetchPost(ctx, post.ID); err != nil {
+			return post.ID, err
+		}
+
+		if current.DeletedAt != nil {
+			return post.ID, divulge.NotFoundError("post not found", nil)
+		}
+
+		// updates can't move a post between accounts, so the key uses the account it already has
+		post.AccountID = current.AccountID
+	}
+
+	revisionID := uuid.New()
+	written := !updating || post.Content != ""
+	if written {
+		post.ContentPath = contentKey(post.AccountID, revisionID)
+		if err := s.fs.Write(ctx, post.ContentPath, []byte(post.Content)); err != nil {
+			return post.ID, fmt.Errorf("failed to write post content: %w", err)
+		}
+	} else {
+		post.ContentPath = current.ContentPath
+	}
+
+	revision := divulge.Revision{
+		ID:          revisionID,
+		Title:       post.Title,
+		Summary:     post.Summary,
+		ContentPath: post.ContentPath,
+	}
+
+	id, err := s.rs.SavePostRevision(ctx, post, revision)
+	if err != nil && written {
+		// nothing points at the new content yet. If deleting it fails too, it's orphaned until
+		// the next reconciliation
+		s.fs.Delete(ctx, post.ContentPath)
+	}
+
+	return id, err
+}
+
+// contentPrefix is where post content is stored. Nothing is written under revisionPrefix
+// anymore, but Revisions saved before posts and Revisions shared content may still point there,
+// so it's only listed when reconciling.
+const (
+	contentPrefix  = "posts/"
+	revisionPrefix = "revisions/"
+)
+
+// contentKey returns the FileStore key for the content saved by a Revision. Keys are never
+// reused, so saving a post never overwrites content an older Revision points at.
+func contentKey(accountID, revisionID uuid.UUID) string {
+	return fmt.Sprintf("%s%s/%s", contentPrefix, accountID, revisionID)
+}
+
+// validatePost checks that a post has everything it needs to be saved. Every post needs a title,
+// and new posts must also belong to an account and author.
+func validatePost(post divulge.Post) error {
+	if divulge.IsEmpty(post.ID) {
+		if divulge.IsEmpty(post.AccountID) {
+			return divulge.ValidationError("accountId is required", nil)
+		}
+
+		if divulge.IsEmpty(post.AuthorID) {
+			return divulge.ValidationError("authorId is required", nil)
+		}
+	}
+
+	if strings.TrimSpace(post.Title) == "" {
+		return divulge.ValidationError("title is required", nil)
 	}
 
-	return id, nil
+	return nil
+}
+
+// cleanTags trims tag names and drops any that are blank or would share a slug with an earlier
+// one. A nil slice stays nil so updates can leave tags untouched.
+func cleanTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	cleaned := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := slug.Make(tag)
+		if key == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		cleaned = append(cleaned, tag)
+	}
+
+	return cleaned
 }
 
 // PublishPost passes off to another PostService to publish a Post.
@@ -47,28 +162,184 @@ func (s PostService) RedactPost(ctx context.Context, id uuid.UUID) error {
 	return s.ps.RedactPost(ctx, id)
 }
 
+// SchedulePost passes off to another PostService to publish a Post at a later time.
+func (s PostService) SchedulePost(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if at.IsZero() {
+		return divulge.ValidationError("a publish time is required", nil)
+	}
+
+	return s.ps.SchedulePost(ctx, id, at)
+}
+
+// UnschedulePost passes off to another PostService to cancel a scheduled publish.
+func (s PostService) UnschedulePost(ctx context.Context, id uuid.UUID) error {
+	return s.ps.UnschedulePost(ctx, id)
+}
+
 // FetchPost fetches the post content from a FileStore and then combines it with metadata
-// fetched from another PostService.
+// fetched from another PostService. The content is also rendered to HTML. Trashed posts can't be
+// fetched.
 func (s PostService) FetchPost(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
 	post, err := s.ps.FetchPost(ctx, id)
 	if err != nil {
 		return post, err
 	}
 
+	if post.DeletedAt != nil {
+		return divulge.Post{}, divulge.NotFoundError("post not found", nil)
+	}
+
+	return s.withContent(ctx, post)
+}
+
+// FetchPostBySlug is like FetchPost, but looks the post up by its slug within an account.
+func (s PostService) FetchPostBySlug(ctx context.Context, accountID uuid.UUID, slug string) (divulge.Post, error) {
+	post, err := s.ps.FetchPostBySlug(ctx, accountID, slug)
+	if err != nil {
+		return post, err
+	}
+
+	return s.withContent(ctx, post)
+}
+
+func (s PostService) withContent(ctx context.Context, post divulge.Post) (divulge.Post, error) {
 	content, err := s.fs.Read(ctx, post.ContentPath)
 	if err != nil {
 		return post, fmt.Errorf("failed to fetch post content: %w", err)
 	}
 
 	post.Content = string(content)
-	return post, err
+	doc, err := s.renderer.Render(post.Content)
+	if err != nil {
+		return post, err
+	}
+
+	post.HTML = doc.HTML
+	post.TOC = doc.TOC
+	return post, nil
 }
 
 func (s PostService) ListPostsByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
 	return s.ps.ListPostsByAccount(ctx, accountID)
 }
 
-// RemovePost passes off to another PostService to remove a Post.
+// RemovePost passes off to another PostService to move a Post to the trash. Its content is kept
+// until the Post is purged.
 func (s PostService) RemovePost(ctx context.Context, id uuid.UUID) error {
 	return s.ps.RemovePost(ctx, id)
 }
+
+// ListTrashByAccount passes off to another PostService to list an account's trashed Posts.
+func (s PostService) ListTrashByAccount(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+	return s.ps.ListTrashByAccount(ctx, accountID)
+}
+
+// RestorePost passes off to another PostService to take a Post back out of the trash.
+func (s PostService) RestorePost(ctx context.Context, id uuid.UUID) error {
+	return s.ps.RestorePost(ctx, id)
+}
+
+// PurgePost passes off to another PostService to permanently delete a trashed Post and then
+// deletes its content, along with the content of every Revision, from the FileStore.
+func (s PostService) PurgePost(ctx context.Context, id uuid.UUID) error {
+	post, err := s.ps.FetchPost(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if post.DeletedAt == nil {
+		return divulge.ConflictError("posts must be trashed before they're purged", nil)
+	}
+
+	revisions, err := s.rs.ListRevisionsByPost(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	if err := s.ps.PurgePost(ctx, id); err != nil {
+		return err
+	}
+
+	// the metadata is gone at this point, so content is deleted last to never leave a post
+	// pointing at missing content
+	keys := []string{post.ContentPath}
+	for _, revision := range revisions {
+		keys = append(keys, revision.ContentPath)
+	}
+
+	deleted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" || deleted[key] {
+			continue
+		}
+
+		deleted[key] = true
+
+		if err := s.fs.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to delete post content: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FetchRevision fetches a Revision along with its content.
+func (s PostService) FetchRevision(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+	revision, err := s.rs.FetchRevision(ctx, id)
+	if err != nil {
+		return revision, err
+	}
+
+	content, err := s.fs.Read(ctx, revision.ContentPath)
+	if err != nil {
+		return revision, fmt.Errorf("failed to fetch revision content: %w", err)
+	}
+
+	revision.Content = string(content)
+	return revision, nil
+}
+
+// ListRevisionsByPost passes off to a RevisionService to list a Post's revisions, newest first.
+// Content is not included.
+func (s PostService) ListRevisionsByPost(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error) {
+	return s.rs.ListRevisionsByPost(ctx, postID)
+}
+
+// DiffRevisions returns the line changes between the content of two revisions of the same Post.
+func (s PostService) DiffRevisions(ctx context.Context, fromID, toID uuid.UUID) ([]divulge.DiffLine, error) {
+	from, err := s.FetchRevision(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.FetchRevision(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.PostID != to.PostID {
+		return nil, divulge.ValidationError("revisions belong to different posts", nil)
+	}
+
+	return diff.Lines(from.Content, to.Content), nil
+}
+
+// RestoreRevision makes an old Revision the current version of its Post. Restoring is itself a
+// save, so it creates a new Revision rather than rewriting history.
+func (s PostService) RestoreRevision(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	revision, err := s.FetchRevision(ctx, id)
+	if err != nil {
+		return revision.PostID, err
+	}
+
+	post, err := s.ps.FetchPost(ctx, revision.PostID)
+	if err != nil {
+		return revision.PostID, err
+	}
+
+	post.Title = revision.Title
+	post.Summary = revision.Summary
+	post.Content = revision.Content
+
+	return s.SavePost(ctx, post)
+}
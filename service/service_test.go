@@ -3,7 +3,9 @@ package service_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/eriktate/divulge"
 	"github.com/eriktate/divulge/mock"
@@ -16,10 +18,12 @@ func Test_SavePost(t *testing.T) {
 	ctx := context.TODO()
 	mockFS := &mock.FileStore{}
 	mockPS := &mock.PostService{}
-	postService := service.NewPostService(mockPS, mockFS)
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
 
 	post := divulge.Post{
-		ID: uuid.New(),
+		ID:      uuid.New(),
+		Title:   "Saved",
+		Content: "some content",
 	}
 
 	// RUN
@@ -40,10 +44,12 @@ func Test_SavePost_FSError(t *testing.T) {
 	ctx := context.TODO()
 	mockFS := &mock.FileStore{Error: errors.New("forced")}
 	mockPS := &mock.PostService{}
-	postService := service.NewPostService(mockPS, mockFS)
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
 
 	post := divulge.Post{
-		ID: uuid.New(),
+		ID:      uuid.New(),
+		Title:   "Saved",
+		Content: "some content",
 	}
 
 	// RUN
@@ -60,10 +66,12 @@ func Test_SavePost_PSError(t *testing.T) {
 	ctx := context.TODO()
 	mockFS := &mock.FileStore{}
 	mockPS := &mock.PostService{Error: errors.New("forced")}
-	postService := service.NewPostService(mockPS, mockFS)
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
 
 	post := divulge.Post{
-		ID: uuid.New(),
+		ID:      uuid.New(),
+		Title:   "Saved",
+		Content: "some content",
 	}
 
 	// RUN
@@ -85,7 +93,7 @@ func Test_FetchPost(t *testing.T) {
 		},
 	}
 	mockPS := &mock.PostService{}
-	postService := service.NewPostService(mockPS, mockFS)
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
 
 	id := uuid.New()
 
@@ -100,6 +108,9 @@ func Test_FetchPost(t *testing.T) {
 	if post.Content != testContent {
 		t.Fatalf("expected post.Content to be: %s", testContent)
 	}
+	if post.HTML != "<p>"+testContent+"</p>\n" {
+		t.Fatalf("unexpected rendered content: %q", post.HTML)
+	}
 }
 
 func Test_FetchPost_FSError(t *testing.T) {
@@ -107,7 +118,7 @@ func Test_FetchPost_FSError(t *testing.T) {
 	ctx := context.TODO()
 	mockFS := &mock.FileStore{Error: errors.New("forced")}
 	mockPS := &mock.PostService{}
-	postService := service.NewPostService(mockPS, mockFS)
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
 
 	id := uuid.New()
 
@@ -125,7 +136,7 @@ func Test_FetchPost_PSError(t *testing.T) {
 	ctx := context.TODO()
 	mockFS := &mock.FileStore{}
 	mockPS := &mock.PostService{Error: errors.New("forced")}
-	postService := service.NewPostService(mockPS, mockFS)
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
 
 	id := uuid.New()
 
@@ -137,3 +148,637 @@ func Test_FetchPost_PSError(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func Test_SavePost_Validation(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockFS := &mock.FileStore{}
+	mockRS := &mock.RevisionService{}
+	postService := service.NewPostService(&mock.PostService{}, mockRS, mockFS)
+
+	post := divulge.Post{
+		AccountID: uuid.New(),
+		Title:     "Missing Author",
+	}
+
+	// RUN
+	_, err := postService.SavePost(ctx, post)
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrValidation) {
+		t.Fatalf("expected validation error, got: %v", err)
+	}
+
+	if mockFS.WriteCount != 0 || mockRS.SavePostRevisionCount != 0 {
+		t.Fatal("expected nothing to be saved")
+	}
+}
+
+func Test_SavePost_CreatesRevision(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	written := make(map[string]string)
+	mockFS := &mock.FileStore{
+		WriteFn: func(ctx context.Context, key string, data []byte) error {
+			written[key] = string(data)
+			return nil
+		},
+	}
+	var saved divulge.Post
+	var created divulge.Revision
+	mockRS := &mock.RevisionService{
+		SavePostRevisionFn: func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+			saved = post
+			created = revision
+			return post.ID, nil
+		},
+	}
+	postService := service.NewPostService(&mock.PostService{}, mockRS, mockFS)
+
+	post := divulge.Post{
+		ID:          uuid.New(),
+		Title:       "Revised",
+		ContentPath: "post.md",
+		Content:     "some content",
+	}
+
+	// RUN
+	_, err := postService.SavePost(ctx, post)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mockRS.SavePostRevisionCount != 1 {
+		t.Fatalf("expected one revision, got %d", mockRS.SavePostRevisionCount)
+	}
+
+	if created.Title != post.Title {
+		t.Fatalf("unexpected revision: %+v", created)
+	}
+
+	if created.ContentPath != saved.ContentPath || len(written) != 1 {
+		t.Fatal("expected the revision to share the post's content")
+	}
+
+	if written[created.ContentPath] != post.Content {
+		t.Fatalf("unexpected revision content: %s", written[created.ContentPath])
+	}
+}
+
+func Test_SavePost_ContentKey(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	accountID := uuid.New()
+	var written []string
+	mockFS := &mock.FileStore{
+		WriteFn: func(ctx context.Context, key string, data []byte) error {
+			written = append(written, key)
+			return nil
+		},
+	}
+	var saved []divulge.Post
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, AccountID: accountID}, nil
+		},
+	}
+	mockRS := &mock.RevisionService{
+		SavePostRevisionFn: func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+			saved = append(saved, post)
+			return uuid.New(), nil
+		},
+	}
+	postService := service.NewPostService(mockPS, mockRS, mockFS)
+
+	post := divulge.Post{
+		AccountID:   accountID,
+		AuthorID:    uuid.New(),
+		Title:       "Keyed",
+		ContentPath: "../../etc/passwd",
+		Content:     "some content",
+	}
+
+	// RUN
+	id, insertErr := postService.SavePost(ctx, post)
+	post.ID = id
+	post.AccountID = uuid.New()
+	_, updateErr := postService.SavePost(ctx, post)
+
+	// ASSERT
+	if insertErr != nil || updateErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", insertErr, updateErr)
+	}
+
+	prefix := "posts/" + accountID.String() + "/"
+	for i, key := range written {
+		if !strings.HasPrefix(key, prefix) || saved[i].ContentPath != key {
+			t.Fatalf("unexpected content key: %s", key)
+		}
+	}
+
+	if len(written) != 2 || written[0] == written[1] {
+		t.Fatalf("expected every save to use a new key: %v", written)
+	}
+}
+
+func Test_SavePost_RevisionError(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{}
+	mockRS := &mock.RevisionService{Error: errors.New("forced")}
+	postService := service.NewPostService(mockPS, mockRS, mockFS)
+
+	post := divulge.Post{
+		ID:      uuid.New(),
+		Title:   "Saved",
+		Content: "some content",
+	}
+
+	// RUN
+	_, err := postService.SavePost(ctx, post)
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func Test_DiffRevisions(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	postID := uuid.New()
+	fromID := uuid.New()
+	toID := uuid.New()
+	contents := map[string]string{
+		"from": "one\ntwo",
+		"to":   "one\nthree",
+	}
+	mockFS := &mock.FileStore{
+		ReadFn: func(ctx context.Context, key string) ([]byte, error) {
+			return []byte(contents[key]), nil
+		},
+	}
+	mockRS := &mock.RevisionService{
+		FetchRevisionFn: func(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+			key := "to"
+			if id == fromID {
+				key = "from"
+			}
+
+			return divulge.Revision{ID: id, PostID: postID, ContentPath: key}, nil
+		},
+	}
+	postService := service.NewPostService(&mock.PostService{}, mockRS, mockFS)
+
+	// RUN
+	lines, err := postService.DiffRevisions(ctx, fromID, toID)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []divulge.DiffLine{
+		{Op: divulge.DiffEqual, Text: "one"},
+		{Op: divulge.DiffDelete, Text: "two"},
+		{Op: divulge.DiffInsert, Text: "three"},
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected diff: %+v", lines)
+	}
+
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("unexpected diff: %+v", lines)
+		}
+	}
+}
+
+func Test_DiffRevisions_DifferentPosts(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockRS := &mock.RevisionService{
+		FetchRevisionFn: func(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+			return divulge.Revision{ID: id, PostID: uuid.New()}, nil
+		},
+	}
+	postService := service.NewPostService(&mock.PostService{}, mockRS, &mock.FileStore{})
+
+	// RUN
+	_, err := postService.DiffRevisions(ctx, uuid.New(), uuid.New())
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrValidation) {
+		t.Fatalf("expected validation error, got: %v", err)
+	}
+}
+
+func Test_RestoreRevision(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	postID := uuid.New()
+	revisionID := uuid.New()
+	mockFS := &mock.FileStore{
+		ReadFn: func(ctx context.Context, key string) ([]byte, error) {
+			return []byte("old content"), nil
+		},
+	}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, Title: "New Title", ContentPath: "post.md"}, nil
+		},
+	}
+	var saved divulge.Post
+	mockRS := &mock.RevisionService{
+		FetchRevisionFn: func(ctx context.Context, id uuid.UUID) (divulge.Revision, error) {
+			return divulge.Revision{ID: id, PostID: postID, Title: "Old Title"}, nil
+		},
+		SavePostRevisionFn: func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+			saved = post
+			return post.ID, nil
+		},
+	}
+	postService := service.NewPostService(mockPS, mockRS, mockFS)
+
+	// RUN
+	id, err := postService.RestoreRevision(ctx, revisionID)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if id != postID {
+		t.Fatalf("unexpected post ID: %s", id)
+	}
+
+	if saved.Title != "Old Title" || saved.Content != "old content" {
+		t.Fatalf("expected old revision to be saved, got: %+v", saved)
+	}
+
+	if mockRS.SavePostRevisionCount != 1 {
+		t.Fatal("expected restoring to create a new revision")
+	}
+}
+
+func Test_SchedulePost_NoTime(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockPS := &mock.PostService{}
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, &mock.FileStore{})
+
+	// RUN
+	err := postService.SchedulePost(ctx, uuid.New(), time.Time{})
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrValidation) {
+		t.Fatalf("expected validation error, got: %v", err)
+	}
+
+	if mockPS.SchedulePostCount != 0 {
+		t.Fatal("expected nothing to be scheduled")
+	}
+}
+
+func Test_FetchPostBySlug(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	accountID := uuid.New()
+	mockFS := &mock.FileStore{
+		ReadFn: func(ctx context.Context, key string) ([]byte, error) {
+			return []byte("slug content"), nil
+		},
+	}
+	mockPS := &mock.PostService{
+		FetchPostBySlugFn: func(ctx context.Context, id uuid.UUID, slug string) (divulge.Post, error) {
+			return divulge.Post{AccountID: id, Slug: slug}, nil
+		},
+	}
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
+
+	// RUN
+	post, err := postService.FetchPostBySlug(ctx, accountID, "hello-world")
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if post.AccountID != accountID || post.Slug != "hello-world" {
+		t.Fatalf("unexpected post: %+v", post)
+	}
+
+	if post.Content != "slug content" {
+		t.Fatalf("unexpected content: %s", post.Content)
+	}
+}
+
+func Test_SavePost_CleansTags(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	var saved divulge.Post
+	mockRS := &mock.RevisionService{
+		SavePostRevisionFn: func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+			saved = post
+			return post.ID, nil
+		},
+	}
+	postService := service.NewPostService(&mock.PostService{}, mockRS, &mock.FileStore{})
+
+	post := divulge.Post{
+		ID:       uuid.New(),
+		Title:    "Tagged",
+		Category: "  Recipes ",
+		Tags:     []string{" Go ", "", "go", "Crème Brûlée", "creme brulee", "!!!"},
+	}
+
+	// RUN
+	_, err := postService.SavePost(ctx, post)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if saved.Category != "Recipes" {
+		t.Fatalf("unexpected category: %q", saved.Category)
+	}
+
+	if len(saved.Tags) != 2 || saved.Tags[0] != "Go" || saved.Tags[1] != "Crème Brûlée" {
+		t.Fatalf("unexpected tags: %q", saved.Tags)
+	}
+}
+
+func Test_TagService_Validation(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	accountID := uuid.New()
+	mockTS := &mock.TagService{}
+	tagService := service.NewTagService(mockTS)
+
+	// RUN
+	renameErr := tagService.RenameTag(ctx, accountID, "go", "   ")
+	selfMergeErr := tagService.MergeTags(ctx, accountID, "go", "go")
+	mergeErr := tagService.MergeTags(ctx, accountID, "golang", "go")
+
+	// ASSERT
+	if !errors.Is(renameErr, divulge.ErrValidation) {
+		t.Fatalf("expected a validation error renaming to a blank name, got: %v", renameErr)
+	}
+
+	if !errors.Is(selfMergeErr, divulge.ErrValidation) {
+		t.Fatalf("expected a validation error merging a tag into itself, got: %v", selfMergeErr)
+	}
+
+	if mergeErr != nil {
+		t.Fatalf("unexpected error: %s", mergeErr)
+	}
+
+	if mockTS.RenameTagCount != 0 || mockTS.MergeTagsCount != 1 {
+		t.Fatal("expected only the valid merge to be passed along")
+	}
+}
+
+func Test_PurgePost(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	id := uuid.New()
+	trashed := time.Now()
+	var deleted []string
+	mockFS := &mock.FileStore{
+		DeleteFn: func(ctx context.Context, key string) error {
+			deleted = append(deleted, key)
+			return nil
+		},
+	}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, ContentPath: "posts/content.md", DeletedAt: &trashed}, nil
+		},
+	}
+	mockRS := &mock.RevisionService{
+		ListRevisionsByPostFn: func(ctx context.Context, postID uuid.UUID) ([]divulge.Revision, error) {
+			return []divulge.Revision{{ContentPath: "posts/content.md"}, {ContentPath: "revisions/1"}, {ContentPath: "revisions/2"}}, nil
+		},
+	}
+	postService := service.NewPostService(mockPS, mockRS, mockFS)
+
+	// RUN
+	err := postService.PurgePost(ctx, id)
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mockPS.PurgePostCount != 1 {
+		t.Fatal("expected the post to be purged")
+	}
+
+	if len(deleted) != 3 || deleted[0] != "posts/content.md" || deleted[1] != "revisions/1" || deleted[2] != "revisions/2" {
+		t.Fatalf("unexpected deleted content: %v", deleted)
+	}
+}
+
+func Test_PurgePost_PSError(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	trashed := time.Now()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, ContentPath: "posts/content.md", DeletedAt: &trashed}, nil
+		},
+		PurgePostFn: func(ctx context.Context, id uuid.UUID) error {
+			return errors.New("forced")
+		},
+	}
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
+
+	// RUN
+	err := postService.PurgePost(ctx, uuid.New())
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if mockFS.DeleteCount != 0 {
+		t.Fatal("expected content to be kept when the post can't be purged")
+	}
+}
+
+func Test_PurgePost_NotTrashed(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, ContentPath: "posts/content.md"}, nil
+		},
+	}
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
+
+	// RUN
+	err := postService.PurgePost(ctx, uuid.New())
+
+	// ASSERT
+	if !errors.Is(err, divulge.ErrConflict) {
+		t.Fatalf("expected conflict, got: %v", err)
+	}
+
+	if mockPS.PurgePostCount != 0 || mockFS.DeleteCount != 0 {
+		t.Fatal("expected nothing to be purged")
+	}
+}
+
+func Test_RemovePost_KeepsContent(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{}
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
+
+	// RUN
+	err := postService.RemovePost(ctx, uuid.New())
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mockPS.RemovePostCount != 1 || mockFS.DeleteCount != 0 {
+		t.Fatal("expected the post to be trashed without deleting its content")
+	}
+}
+
+func Test_Trashed(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	trashed := time.Now()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, ContentPath: "posts/content.md", DeletedAt: &trashed}, nil
+		},
+	}
+	mockRS := &mock.RevisionService{}
+	postService := service.NewPostService(mockPS, mockRS, mockFS)
+
+	// RUN
+	_, fetchErr := postService.FetchPost(ctx, uuid.New())
+	_, saveErr := postService.SavePost(ctx, divulge.Post{ID: uuid.New(), Title: "Trashed"})
+
+	// ASSERT
+	if !errors.Is(fetchErr, divulge.ErrNotFound) || !errors.Is(saveErr, divulge.ErrNotFound) {
+		t.Fatalf("expected trashed posts to be hidden, got: %v, %v", fetchErr, saveErr)
+	}
+
+	if mockFS.WriteCount != 0 || mockRS.SavePostRevisionCount != 0 {
+		t.Fatal("expected nothing to be saved")
+	}
+}
+
+func Test_SavePost_PSError_DeletesContent(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	var written, deleted string
+	mockFS := &mock.FileStore{
+		WriteFn: func(ctx context.Context, key string, data []byte) error {
+			written = key
+			return nil
+		},
+		DeleteFn: func(ctx context.Context, key string) error {
+			deleted = key
+			return nil
+		},
+	}
+	mockRS := &mock.RevisionService{
+		SavePostRevisionFn: func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+			return post.ID, errors.New("forced")
+		},
+	}
+	postService := service.NewPostService(&mock.PostService{}, mockRS, mockFS)
+
+	// RUN
+	_, err := postService.SavePost(ctx, divulge.Post{ID: uuid.New(), Title: "Failed", Content: "some content"})
+
+	// ASSERT
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if written == "" || deleted != written {
+		t.Fatalf("expected the new content to be deleted, wrote %q and deleted %q", written, deleted)
+	}
+}
+
+func Test_SavePost_KeepsPreviousContent(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, ContentPath: "posts/account/revision"}, nil
+		},
+	}
+	postService := service.NewPostService(mockPS, &mock.RevisionService{}, mockFS)
+
+	// RUN
+	_, err := postService.SavePost(ctx, divulge.Post{ID: uuid.New(), Title: "Updated", Content: "new content"})
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mockFS.DeleteCount != 0 {
+		t.Fatal("expected the previous revision's content to be kept")
+	}
+}
+
+func Test_SavePost_UpdateWithoutContent(t *testing.T) {
+	// SETUP
+	ctx := context.TODO()
+	mockFS := &mock.FileStore{}
+	mockPS := &mock.PostService{
+		FetchPostFn: func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+			return divulge.Post{ID: id, Title: "Current", ContentPath: "posts/account/revision"}, nil
+		},
+	}
+	var saved divulge.Post
+	var created divulge.Revision
+	mockRS := &mock.RevisionService{
+		SavePostRevisionFn: func(ctx context.Context, post divulge.Post, revision divulge.Revision) (uuid.UUID, error) {
+			saved = post
+			created = revision
+			return post.ID, errors.New("forced")
+		},
+	}
+	postService := service.NewPostService(mockPS, mockRS, mockFS)
+
+	// RUN
+	_, untitledErr := postService.SavePost(ctx, divulge.Post{ID: uuid.New(), Title: "  ", Content: "new content"})
+	_, err := postService.SavePost(ctx, divulge.Post{ID: uuid.New(), Title: "Renamed"})
+
+	// ASSERT
+	if !errors.Is(untitledErr, divulge.ErrValidation) {
+		t.Fatalf("expected updates to need a title, got %v", untitledErr)
+	}
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if saved.ContentPath != "posts/account/revision" || created.ContentPath != saved.ContentPath {
+		t.Fatalf("expected the current content to be kept, got %q and %q", saved.ContentPath, created.ContentPath)
+	}
+
+	if mockFS.WriteCount != 0 || mockFS.DeleteCount != 0 {
+		t.Fatal("expected the current content not to be written or deleted")
+	}
+}
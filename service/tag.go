@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eriktate/divulge"
+	"github.com/google/uuid"
+)
+
+// A TagService implements the divulge.TagService interface by validating requests before
+// passing them off to another TagService.
+type TagService struct {
+	ts divulge.TagService
+}
+
+// NewTagService returns a new TagService.
+func NewTagService(ts divulge.TagService) TagService {
+	return TagService{
+		ts: ts,
+	}
+}
+
+// ListTags passes off to another TagService to list an account's tags.
+func (s TagService) ListTags(ctx context.Context, accountID uuid.UUID) ([]divulge.Tag, error) {
+	return s.ts.ListTags(ctx, accountID)
+}
+
+// ListPostsByTag passes off to another TagService to list the posts with a tag.
+func (s TagService) ListPostsByTag(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error) {
+	return s.ts.ListPostsByTag(ctx, accountID, tag)
+}
+
+// RenameTag makes sure the new name isn't blank before renaming the tag.
+func (s TagService) RenameTag(ctx context.Context, accountID uuid.UUID, tag, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return divulge.ValidationError("name is required", nil)
+	}
+
+	return s.ts.RenameTag(ctx, accountID, tag, name)
+}
+
+// MergeTags makes sure two different tags were given before merging them.
+func (s TagService) MergeTags(ctx context.Context, accountID uuid.UUID, from, into string) error {
+	if into == "" {
+		return divulge.ValidationError("a tag to merge into is required", nil)
+	}
+
+	if from == into {
+		return divulge.ValidationError("can't merge a tag into itself", nil)
+	}
+
+	return s.ts.MergeTags(ctx, accountID, from, into)
+}
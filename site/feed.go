@@ -0,0 +1,156 @@
+package site
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/feed"
+	"github.com/google/uuid"
+)
+
+// FeedSize is how many of the most recently published posts are included in feeds.
+const FeedSize = 20
+
+// handleFeed serves an account's feed in the format produced by build. Responses carry an ETag so
+// readers polling for updates can make conditional requests. There's no Last-Modified, since the
+// newest post still in the feed says nothing about posts that were redacted or trashed since.
+func (s *Site) handleFeed(build func(feed.Feed) ([]byte, error), contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account, ok := s.account(w, r)
+		if !ok {
+			return
+		}
+
+		f, err := s.feed(r, account)
+		if err != nil {
+			s.handleError(w, r, account, err)
+			return
+		}
+
+		body, err := build(f)
+		if err != nil {
+			s.handleError(w, r, account, fmt.Errorf("failed to build feed: %w", err))
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}
+}
+
+// feed collects an account's most recently published posts into a Feed. Full content is only
+// included when the account has opted into it.
+func (s *Site) feed(r *http.Request, account divulge.Account) (feed.Feed, error) {
+	ctx := r.Context()
+	posts, err := s.posts.ListPostsByAccount(ctx, account.ID)
+	if err != nil {
+		return feed.Feed{}, err
+	}
+
+	posts = published(posts)
+	if len(posts) > FeedSize {
+		posts = posts[:FeedSize]
+	}
+
+	link := origin(r) + base(account)
+	f := feed.Feed{
+		ID:      account.ID,
+		Title:   account.Name,
+		Link:    link + "/",
+		FeedURL: origin(r) + r.URL.Path,
+	}
+
+	for _, post := range posts {
+		item, err := s.feedItem(ctx, account, link, post)
+		if err != nil {
+			return f, err
+		}
+
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+
+		f.Items = append(f.Items, item)
+	}
+
+	return f, nil
+}
+
+func (s *Site) feedItem(ctx context.Context, account divulge.Account, link string, post divulge.Post) (feed.Item, error) {
+	item := feed.Item{
+		ID:        post.ID,
+		Title:     post.Title,
+		Link:      link + "/posts/" + url.PathEscape(post.Slug),
+		Summary:   post.Summary,
+		Tags:      post.Tags,
+		Published: *post.PublishedAt,
+		Updated:   post.UpdatedAt,
+	}
+
+	// posts published after their last edit were still updated when they went live
+	if item.Updated.Before(item.Published) {
+		item.Updated = item.Published
+	}
+
+	if account.FeedContent != divulge.FeedFull {
+		return item, nil
+	}
+
+	html, err := s.postHTML(ctx, post)
+	if err != nil {
+		return item, err
+	}
+
+	item.ContentHTML = html
+	return item, nil
+}
+
+// postHTML returns a post's rendered content. Feeds are polled far more often than posts change,
+// so the HTML is cached until the post is next updated rather than fetched for every item.
+func (s *Site) postHTML(ctx context.Context, post divulge.Post) (string, error) {
+	s.mu.Lock()
+	cached, ok := s.html[post.ID]
+	s.mu.Unlock()
+	if ok && cached.updated.Equal(post.UpdatedAt) {
+		return cached.html, nil
+	}
+
+	full, err := s.posts.FetchPost(ctx, post.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch post for feed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.html[post.ID]; !ok && len(s.html) >= maxCachedTemplates {
+		s.html = make(map[uuid.UUID]cachedHTML)
+	}
+
+	s.html[post.ID] = cachedHTML{updated: post.UpdatedAt, html: full.HTML}
+	return full.HTML, nil
+}
+
+// origin returns the scheme and host the request was made to, trusting X-Forwarded-Proto so
+// links are right behind a TLS terminating proxy.
+func origin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,507 @@
+// Package site serves each account's published posts as a public, read-only blog.
+//
+// Pages are rendered from the templates embedded in the package. An account can override any of
+// them by storing its own version in the FileStore at OverrideKey, which is
+// templates/<accountID>/<name>.html for one of the Template names. There's no API for uploading
+// overrides, so they're written to the FileStore directly, and sites pick them up within
+// OverrideTTL. Overrides define the same templates as the defaults they replace: "layout" for the
+// layout and "content" for everything else.
+package site
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/feed"
+	"github.com/eriktate/divulge/slug"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Prefix is where sites are served from. An account's site lives at Prefix/<accountID>.
+const Prefix = "/sites"
+
+// Template names. Accounts can override any of them by storing a file at OverrideKey.
+const (
+	TemplateLayout   = "layout"
+	TemplateIndex    = "index"
+	TemplatePost     = "post"
+	TemplateTag      = "tag"
+	TemplateNotFound = "notfound"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+var funcs = template.FuncMap{
+	"date": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+
+		return t.Format("January 2, 2006")
+	},
+	"datetime": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	},
+	"slug": slug.Make,
+	// post HTML is sanitized when it's rendered, so it's safe to include as is
+	"trusted": func(s string) template.HTML {
+		return template.HTML(s)
+	},
+}
+
+// OverrideKey returns the FileStore key an account's override of the named template lives at.
+func OverrideKey(accountID uuid.UUID, name string) string {
+	return overridePrefix(accountID) + name + ".html"
+}
+
+func overridePrefix(accountID uuid.UUID) string {
+	return "templates/" + accountID.String() + "/"
+}
+
+// OverrideTTL is how long a Site trusts its listing of an account's overrides before checking the
+// FileStore for changes again.
+const OverrideTTL = time.Minute
+
+// maxCachedTemplates bounds how many parsed templates, override listings and feed items a Site
+// keeps. The caches are simply emptied when they fill up, since they're cheap to rebuild compared
+// to serving every page.
+const maxCachedTemplates = 1024
+
+// A templateKey identifies a page template. The zero account is used for the defaults.
+type templateKey struct {
+	accountID uuid.UUID
+	name      string
+}
+
+// A cachedTemplate is a parsed template and the version of the overrides it was built from.
+type cachedTemplate struct {
+	version string
+	tmpl    *template.Template
+}
+
+// A cachedListing is an account's overrides, by key, as of when they were last listed.
+type cachedListing struct {
+	files   map[string]divulge.FileInfo
+	expires time.Time
+}
+
+// A cachedHTML is a post's rendered content as of its last update.
+type cachedHTML struct {
+	updated time.Time
+	html    string
+}
+
+// A page is everything a template has access to.
+type page struct {
+	Account divulge.Account
+	Base    string
+	Title   string
+	Posts   []divulge.Post
+	Post    divulge.Post
+	Tag     divulge.Tag
+}
+
+// A Site serves account blogs. Only published posts are ever shown.
+type Site struct {
+	posts    divulge.PostService
+	tags     divulge.TagService
+	accounts divulge.AccountService
+	fs       divulge.FileStore
+	logger   *logrus.Logger
+	router   chi.Router
+
+	mu        sync.Mutex
+	templates map[templateKey]cachedTemplate
+	listings  map[uuid.UUID]cachedListing
+	html      map[uuid.UUID]cachedHTML
+}
+
+// New returns a new Site. Template overrides are read from fs.
+func New(posts divulge.PostService, tags divulge.TagService, accounts divulge.AccountService, fs divulge.FileStore, logger *logrus.Logger) *Site {
+	s := &Site{
+		posts:    posts,
+		tags:     tags,
+		accounts: accounts,
+		fs:       fs,
+		logger:   logger,
+		router:   chi.NewRouter(),
+
+		templates: make(map[templateKey]cachedTemplate),
+		listings:  make(map[uuid.UUID]cachedListing),
+		html:      make(map[uuid.UUID]cachedHTML),
+	}
+
+	s.routes()
+	return s
+}
+
+func (s *Site) routes() {
+	s.router.Route(Prefix+"/{accountID}", func(r chi.Router) {
+		r.Get("/", s.handleIndex)
+		r.Get("/posts/{slug}", s.handlePost)
+		r.Get("/tags/{tag}", s.handleTag)
+		r.Get("/feed.xml", s.handleFeed(feed.RSS, feed.ContentTypeRSS))
+		r.Get("/atom.xml", s.handleFeed(feed.Atom, feed.ContentTypeAtom))
+		r.Get("/feed.json", s.handleFeed(feed.JSON, feed.ContentTypeJSON))
+	})
+
+	s.router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		s.notFound(w, r, divulge.Account{})
+	})
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+func (s *Site) handleIndex(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.account(w, r)
+	if !ok {
+		return
+	}
+
+	posts, err := s.posts.ListPostsByAccount(r.Context(), account.ID)
+	if err != nil {
+		s.handleError(w, r, account, err)
+		return
+	}
+
+	s.render(w, r, account, http.StatusOK, TemplateIndex, page{Posts: published(posts)})
+}
+
+func (s *Site) handlePost(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.account(w, r)
+	if !ok {
+		return
+	}
+
+	postSlug := chi.URLParam(r, "slug")
+	post, err := s.posts.FetchPostBySlug(r.Context(), account.ID, postSlug)
+	if err != nil {
+		s.handleError(w, r, account, err)
+		return
+	}
+
+	// drafts and redacted posts don't exist as far as readers are concerned
+	if post.PublishedAt == nil {
+		s.notFound(w, r, account)
+		return
+	}
+
+	if post.Slug != postSlug {
+		http.Redirect(w, r, base(account)+"/posts/"+url.PathEscape(post.Slug), http.StatusMovedPermanently)
+		return
+	}
+
+	s.render(w, r, account, http.StatusOK, TemplatePost, page{Title: post.Title, Post: post})
+}
+
+func (s *Site) handleTag(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.account(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := s.tags.ListTags(r.Context(), account.ID)
+	if err != nil {
+		s.handleError(w, r, account, err)
+		return
+	}
+
+	tagSlug := chi.URLParam(r, "tag")
+	var tag divulge.Tag
+	for _, t := range tags {
+		if t.Slug == tagSlug {
+			tag = t
+			break
+		}
+	}
+
+	if tag.Slug == "" {
+		s.notFound(w, r, account)
+		return
+	}
+
+	posts, err := s.tags.ListPostsByTag(r.Context(), account.ID, tag.Slug)
+	if err != nil {
+		s.handleError(w, r, account, err)
+		return
+	}
+
+	posts = published(posts)
+	if len(posts) == 0 {
+		s.notFound(w, r, account)
+		return
+	}
+
+	s.render(w, r, account, http.StatusOK, TemplateTag, page{Title: tag.Name, Tag: tag, Posts: posts})
+}
+
+// account fetches the account whose site is being requested, responding with a 404 when it
+// doesn't exist.
+func (s *Site) account(w http.ResponseWriter, r *http.Request) (divulge.Account, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "accountID"))
+	if err != nil {
+		s.notFound(w, r, divulge.Account{})
+		return divulge.Account{}, false
+	}
+
+	account, err := s.accounts.FetchAccount(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, divulge.Account{}, err)
+		return account, false
+	}
+
+	return account, true
+}
+
+func (s *Site) handleError(w http.ResponseWriter, r *http.Request, account divulge.Account, err error) {
+	if errors.Is(err, divulge.ErrNotFound) {
+		s.notFound(w, r, account)
+		return
+	}
+
+	s.logger.WithError(err).WithField("path", r.URL.Path).Error("site request failed")
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (s *Site) notFound(w http.ResponseWriter, r *http.Request, account divulge.Account) {
+	s.render(w, r, account, http.StatusNotFound, TemplateNotFound, page{Title: "Not found"})
+}
+
+// render executes the named template inside the layout. Output is buffered so a failing
+// template never sends a partial page.
+func (s *Site) render(w http.ResponseWriter, r *http.Request, account divulge.Account, status int, name string, data page) {
+	data.Account = account
+	data.Base = base(account)
+
+	tmpl, err := s.template(r.Context(), account.ID, name)
+	if err != nil {
+		s.logger.WithError(err).WithField("template", name).Error("failed to load template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, TemplateLayout, data); err != nil {
+		s.logger.WithError(err).WithField("template", name).Error("failed to render template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// template builds the named page template, preferring the account's overrides. A broken
+// override is logged and the defaults are used instead so one bad edit can't take a site down.
+//
+// Parsed templates are cached, as is the listing of each account's overrides, so most renders
+// don't touch the FileStore at all. Once the listing is older than OverrideTTL it's fetched again,
+// and overrides are only read and parsed again if one of them has changed.
+func (s *Site) template(ctx context.Context, accountID uuid.UUID, name string) (*template.Template, error) {
+	defaults, err := s.defaultTemplate(name)
+	if err != nil || divulge.IsEmpty(accountID) {
+		return defaults, err
+	}
+
+	overrides, err := s.overrides(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the version changes whenever an override that's used is added, removed or changed
+	var version strings.Builder
+	names := []string{TemplateLayout, name}
+	for _, tmplName := range names {
+		if file, ok := overrides[OverrideKey(accountID, tmplName)]; ok {
+			fmt.Fprintf(&version, "%s:%d:%d:%s;", file.Key, file.Size, file.ModTime.UnixNano(), file.Checksum)
+		}
+	}
+
+	if version.Len() == 0 {
+		return defaults, nil
+	}
+
+	key := templateKey{accountID: accountID, name: name}
+	if cached, ok := s.cached(key); ok && cached.version == version.String() {
+		return cached.tmpl, nil
+	}
+
+	sources, err := defaultSources(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tmplName := range names {
+		if _, ok := overrides[OverrideKey(accountID, tmplName)]; !ok {
+			continue
+		}
+
+		override, err := s.fs.Read(ctx, OverrideKey(accountID, tmplName))
+		if errors.Is(err, divulge.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		sources[i] = string(override)
+	}
+
+	tmpl, err := parse(sources)
+	if err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"account":  accountID,
+			"template": name,
+		}).Warn("invalid template override, using defaults")
+
+		tmpl = defaults
+	}
+
+	s.cache(key, cachedTemplate{version: version.String(), tmpl: tmpl})
+	return tmpl, nil
+}
+
+// overrides returns an account's override files by key, listing them again once the cached
+// listing has expired.
+func (s *Site) overrides(ctx context.Context, accountID uuid.UUID) (map[string]divulge.FileInfo, error) {
+	now := time.Now()
+	s.mu.Lock()
+	listing, ok := s.listings[accountID]
+	s.mu.Unlock()
+	if ok && now.Before(listing.expires) {
+		return listing.files, nil
+	}
+
+	files, err := s.fs.List(ctx, overridePrefix(accountID))
+	if err != nil {
+		return nil, err
+	}
+
+	listing = cachedListing{files: make(map[string]divulge.FileInfo, len(files)), expires: now.Add(OverrideTTL)}
+	for _, file := range files {
+		listing.files[file.Key] = file
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.listings[accountID]; !ok && len(s.listings) >= maxCachedTemplates {
+		s.listings = make(map[uuid.UUID]cachedListing)
+	}
+
+	s.listings[accountID] = listing
+	return listing.files, nil
+}
+
+// defaultTemplate returns the named page template without any overrides.
+func (s *Site) defaultTemplate(name string) (*template.Template, error) {
+	key := templateKey{name: name}
+	if cached, ok := s.cached(key); ok {
+		return cached.tmpl, nil
+	}
+
+	sources, err := defaultSources(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := parse(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache(key, cachedTemplate{tmpl: tmpl})
+	return tmpl, nil
+}
+
+func (s *Site) cached(key templateKey) (cachedTemplate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.templates[key]
+	return cached, ok
+}
+
+func (s *Site) cache(key templateKey, cached cachedTemplate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[key]; !ok && len(s.templates) >= maxCachedTemplates {
+		s.templates = make(map[templateKey]cachedTemplate)
+	}
+
+	s.templates[key] = cached
+}
+
+// defaultSources returns the layout and the named page template embedded in the package.
+func defaultSources(name string) ([]string, error) {
+	layout, err := defaultTemplates.ReadFile("templates/" + TemplateLayout + ".html")
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := defaultTemplates.ReadFile("templates/" + name + ".html")
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{string(layout), string(content)}, nil
+}
+
+func parse(sources []string) (*template.Template, error) {
+	tmpl := template.New(TemplateLayout).Funcs(funcs)
+	for _, source := range sources {
+		if _, err := tmpl.Parse(source); err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+// published filters out unpublished posts and sorts the rest newest first.
+func published(posts []divulge.Post) []divulge.Post {
+	var visible []divulge.Post
+	for _, post := range posts {
+		if post.PublishedAt != nil {
+			visible = append(visible, post)
+		}
+	}
+
+	sort.SliceStable(visible, func(i, j int) bool {
+		return visible[i].PublishedAt.After(*visible[j].PublishedAt)
+	})
+
+	return visible
+}
+
+func base(account divulge.Account) string {
+	if divulge.IsEmpty(account.ID) {
+		return Prefix
+	}
+
+	return Prefix + "/" + account.ID.String()
+}
@@ -0,0 +1,386 @@
+package site_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge"
+	"github.com/eriktate/divulge/mock"
+	"github.com/eriktate/divulge/site"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// fixture is a site for a single account with one published post and one draft.
+type fixture struct {
+	account divulge.Account
+	posts   *mock.PostService
+	tags    *mock.TagService
+	fs      *mock.FileStore
+	site    *site.Site
+}
+
+func newFixture() *fixture {
+	published := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	account := divulge.Account{ID: uuid.New(), Name: "Test Blog"}
+	live := divulge.Post{
+		ID:          uuid.New(),
+		AccountID:   account.ID,
+		Title:       "Live Post",
+		Slug:        "live-post",
+		Tags:        []string{"Go"},
+		HTML:        "<p>live content</p>",
+		PublishedAt: &published,
+	}
+	draft := divulge.Post{
+		ID:        uuid.New(),
+		AccountID: account.ID,
+		Title:     "Draft Post",
+		Slug:      "draft-post",
+	}
+
+	f := &fixture{account: account}
+	f.posts = &mock.PostService{
+		ListPostsByAccountFn: func(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+			return []divulge.Post{live, draft}, nil
+		},
+		FetchPostBySlugFn: func(ctx context.Context, accountID uuid.UUID, slug string) (divulge.Post, error) {
+			switch slug {
+			case "live-post", "old-live-post":
+				return live, nil
+			case "draft-post":
+				return draft, nil
+			}
+
+			return divulge.Post{}, divulge.NotFoundError("post not found", nil)
+		},
+	}
+	f.tags = &mock.TagService{
+		ListTagsFn: func(ctx context.Context, accountID uuid.UUID) ([]divulge.Tag, error) {
+			return []divulge.Tag{{Name: "Go", Slug: "go", PostCount: 2}, {Name: "Drafts", Slug: "drafts", PostCount: 1}}, nil
+		},
+		ListPostsByTagFn: func(ctx context.Context, accountID uuid.UUID, tag string) ([]divulge.Post, error) {
+			if tag == "go" {
+				return []divulge.Post{live, draft}, nil
+			}
+
+			return []divulge.Post{draft}, nil
+		},
+	}
+	accounts := &mock.AccountService{
+		FetchAccountFn: func(ctx context.Context, id uuid.UUID) (divulge.Account, error) {
+			if id != f.account.ID {
+				return divulge.Account{}, divulge.NotFoundError("account not found", nil)
+			}
+
+			return f.account, nil
+		},
+	}
+	f.fs = &mock.FileStore{
+		ReadFn: func(ctx context.Context, key string) ([]byte, error) {
+			return nil, divulge.NotFoundError("content not found", nil)
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	f.site = site.New(f.posts, f.tags, accounts, f.fs, logger)
+	return f
+}
+
+func (f *fixture) get(path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, site.Prefix+"/"+f.account.ID.String()+path, nil)
+	rec := httptest.NewRecorder()
+	f.site.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_Index(t *testing.T) {
+	// SETUP
+	f := newFixture()
+
+	// RUN
+	rec := f.get("/")
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Live Post") || !strings.Contains(body, "Test Blog") {
+		t.Fatalf("expected the published post to be listed, got:\n%s", body)
+	}
+
+	if strings.Contains(body, "Draft Post") {
+		t.Fatal("expected drafts to be hidden")
+	}
+}
+
+func Test_Post(t *testing.T) {
+	// SETUP
+	f := newFixture()
+
+	// RUN
+	rec := f.get("/posts/live-post")
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "<p>live content</p>") {
+		t.Fatalf("expected rendered content to be included as HTML, got:\n%s", body)
+	}
+
+	if !strings.Contains(body, `/tags/go"`) {
+		t.Fatalf("expected a link to the post's tag, got:\n%s", body)
+	}
+}
+
+func Test_Post_Hidden(t *testing.T) {
+	// SETUP
+	f := newFixture()
+
+	for _, path := range []string{"/posts/draft-post", "/posts/missing", "/tags/drafts", "/tags/missing"} {
+		// RUN
+		rec := f.get(path)
+
+		// ASSERT
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("unexpected status for %s: %d", path, rec.Code)
+		}
+	}
+}
+
+func Test_Post_Redirect(t *testing.T) {
+	// SETUP
+	f := newFixture()
+
+	// RUN
+	rec := f.get("/posts/old-live-post")
+
+	// ASSERT
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	expected := site.Prefix + "/" + f.account.ID.String() + "/posts/live-post"
+	if location := rec.Header().Get("Location"); location != expected {
+		t.Fatalf("unexpected location: %s", location)
+	}
+}
+
+func Test_Tag(t *testing.T) {
+	// SETUP
+	f := newFixture()
+
+	// RUN
+	rec := f.get("/tags/go")
+
+	// ASSERT
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Posts tagged Go") || !strings.Contains(body, "Live Post") {
+		t.Fatalf("unexpected tag page:\n%s", body)
+	}
+
+	if strings.Contains(body, "Draft Post") {
+		t.Fatal("expected drafts to be hidden")
+	}
+}
+
+func Test_UnknownAccount(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	req := httptest.NewRequest(http.MethodGet, site.Prefix+"/"+uuid.New().String()+"/", nil)
+	rec := httptest.NewRecorder()
+
+	// RUN
+	f.site.ServeHTTP(rec, req)
+
+	// ASSERT
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
+
+// override stores templates in the fixture's FileStore, all modified at modTime.
+func (f *fixture) override(templates map[string]string, modTime time.Time) {
+	f.fs.ListFn = func(ctx context.Context, prefix string) ([]divulge.FileInfo, error) {
+		var files []divulge.FileInfo
+		for key, source := range templates {
+			if strings.HasPrefix(key, prefix) {
+				files = append(files, divulge.FileInfo{Key: key, Size: int64(len(source)), ModTime: modTime})
+			}
+		}
+
+		return files, nil
+	}
+	f.fs.ReadFn = func(ctx context.Context, key string) ([]byte, error) {
+		if source, ok := templates[key]; ok {
+			return []byte(source), nil
+		}
+
+		return nil, divulge.NotFoundError("content not found", nil)
+	}
+}
+
+func Test_TemplateOverride(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	f.override(map[string]string{
+		site.OverrideKey(f.account.ID, site.TemplateIndex): `{{define "content"}}custom index: {{len .Posts}} posts{{end}}`,
+		site.OverrideKey(f.account.ID, site.TemplatePost):  `{{define "content"}}{{.Post.Title}`,
+	}, time.Now())
+
+	// RUN
+	index := f.get("/")
+	post := f.get("/posts/live-post")
+
+	// ASSERT
+	if !strings.Contains(index.Body.String(), "custom index: 1 posts") {
+		t.Fatalf("expected the index override to be used, got:\n%s", index.Body.String())
+	}
+
+	if post.Code != http.StatusOK || !strings.Contains(post.Body.String(), "<p>live content</p>") {
+		t.Fatalf("expected a broken override to fall back to the default, got %d:\n%s", post.Code, post.Body.String())
+	}
+}
+
+func Test_TemplateOverride_Cache(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	key := site.OverrideKey(f.account.ID, site.TemplateIndex)
+	modTime := time.Now()
+	f.override(map[string]string{key: `{{define "content"}}first{{end}}`}, modTime)
+
+	// RUN
+	first := f.get("/")
+	cached := f.get("/")
+	post := f.get("/posts/live-post")
+	f.override(map[string]string{key: `{{define "content"}}second{{end}}`}, modTime.Add(time.Second))
+	changed := f.get("/")
+
+	// ASSERT
+	if !strings.Contains(first.Body.String(), "first") || !strings.Contains(cached.Body.String(), "first") {
+		t.Fatalf("expected the override to be used, got:\n%s", cached.Body.String())
+	}
+
+	if post.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", post.Code)
+	}
+
+	if f.fs.ListCount != 1 || f.fs.ReadCount != 1 {
+		t.Fatalf("expected overrides to be listed and read once, got %d lists and %d reads", f.fs.ListCount, f.fs.ReadCount)
+	}
+
+	if !strings.Contains(changed.Body.String(), "first") || f.fs.ListCount != 1 {
+		t.Fatalf("expected the listing to be reused for %s, got %d lists:\n%s", site.OverrideTTL, f.fs.ListCount, changed.Body.String())
+	}
+}
+
+func Test_Feed(t *testing.T) {
+	// SETUP
+	f := newFixture()
+
+	for _, path := range []string{"/feed.xml", "/atom.xml", "/feed.json"} {
+		// RUN
+		rec := f.get(path)
+
+		// ASSERT
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status for %s: %d", path, rec.Code)
+		}
+
+		body := rec.Body.String()
+		if !strings.Contains(body, "Live Post") || strings.Contains(body, "Draft Post") {
+			t.Fatalf("expected only published posts in %s, got:\n%s", path, body)
+		}
+
+		if !strings.Contains(body, "http://example.com"+site.Prefix) {
+			t.Fatalf("expected absolute links in %s, got:\n%s", path, body)
+		}
+
+		if strings.Contains(body, "live content") {
+			t.Fatalf("expected summaries only in %s, got:\n%s", path, body)
+		}
+	}
+
+	if f.posts.FetchPostCount != 0 {
+		t.Fatal("expected summary feeds not to fetch post content")
+	}
+}
+
+func Test_Feed_Full(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	f.account.FeedContent = divulge.FeedFull
+	f.posts.FetchPostFn = func(ctx context.Context, id uuid.UUID) (divulge.Post, error) {
+		return divulge.Post{ID: id, HTML: "<p>live content</p>"}, nil
+	}
+
+	// RUN
+	rec := f.get("/atom.xml")
+	again := f.get("/feed.json")
+
+	// ASSERT
+	if rec.Code != http.StatusOK || again.Code != http.StatusOK {
+		t.Fatalf("unexpected statuses: %d, %d", rec.Code, again.Code)
+	}
+
+	if f.posts.FetchPostCount != 1 {
+		t.Fatalf("expected post content to be fetched once, got %d fetches", f.posts.FetchPostCount)
+	}
+
+	if !strings.Contains(rec.Body.String(), "&lt;p&gt;live content&lt;/p&gt;") {
+		t.Fatalf("expected full content in the feed, got:\n%s", rec.Body.String())
+	}
+}
+
+func Test_Feed_ConditionalGet(t *testing.T) {
+	// SETUP
+	f := newFixture()
+	first := f.get("/feed.json")
+	etag := first.Header().Get("ETag")
+
+	path := site.Prefix + "/" + f.account.ID.String() + "/feed.json"
+	byETag := httptest.NewRequest(http.MethodGet, path, nil)
+	byETag.Header.Set("If-None-Match", etag)
+	byDate := httptest.NewRequest(http.MethodGet, path, nil)
+	byDate.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+
+	etagRec := httptest.NewRecorder()
+	dateRec := httptest.NewRecorder()
+
+	// RUN
+	f.site.ServeHTTP(etagRec, byETag)
+	f.site.ServeHTTP(dateRec, byDate)
+	f.posts.ListPostsByAccountFn = func(ctx context.Context, accountID uuid.UUID) ([]divulge.Post, error) {
+		return nil, nil
+	}
+	emptied := httptest.NewRecorder()
+	f.site.ServeHTTP(emptied, byETag)
+
+	// ASSERT
+	if etag == "" || first.Header().Get("Last-Modified") != "" {
+		t.Fatalf("expected only an etag, got %v", first.Header())
+	}
+
+	if etagRec.Code != http.StatusNotModified || dateRec.Code != http.StatusOK {
+		t.Fatalf("unexpected statuses: %d, %d", etagRec.Code, dateRec.Code)
+	}
+
+	if emptied.Code != http.StatusOK {
+		t.Fatalf("expected removing a post to change the feed, got %d", emptied.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make will return, in runes. Combining marks count toward it.
+const MaxLength = 96
+
+// Make turns s into a URL friendly slug. Letters and digits from any script are kept and
+// lowercased, accents are stripped from Latin letters, and everything else collapses into
+// single hyphens. The result may be empty if s contains no letters or digits.
+func Make(s string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	latinBase := false
+	length := 0
+
+	// decompose so accents become separate marks that can be dropped
+loop:
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me):
+			// other scripts rely on their marks, so only Latin accents are dropped
+			if latinBase || pendingHyphen || length == 0 || length >= MaxLength {
+				continue
+			}
+
+			b.WriteRune(r)
+			length++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			hyphen := pendingHyphen && length > 0
+			if hyphen && length+2 > MaxLength || length+1 > MaxLength {
+				break loop
+			}
+
+			if hyphen {
+				b.WriteRune('-')
+				length++
+			}
+
+			pendingHyphen = false
+			latinBase = unicode.Is(unicode.Latin, r)
+			b.WriteRune(unicode.ToLower(r))
+			length++
+		default:
+			pendingHyphen = true
+		}
+	}
+
+	// recompose so scripts that rely on combining characters stay readable
+	return norm.NFC.String(b.String())
+}
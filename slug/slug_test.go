@@ -0,0 +1,76 @@
+package slug_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/eriktate/divulge/slug"
+)
+
+func Test_Make(t *testing.T) {
+	// SETUP
+	cases := map[string]string{
+		"Hello, World!":            "hello-world",
+		"  leading and trailing  ": "leading-and-trailing",
+		"Crème Brûlée Recipes":     "creme-brulee-recipes",
+		"Straße & Ærø":             "straße-ærø",
+		"Привет мир":               "привет-мир",
+		"日本語のタイトル":                 "日本語のタイトル",
+		"Go 1.16 -- what's new?":   "go-1-16-what-s-new",
+		"ﬁle":                      "file",
+		"ダンスの歴史":                   "ダンスの歴史",
+		"हिन्दी ब्लॉग":             "हिन्दी-ब्लॉग",
+		"!!!":                      "",
+	}
+
+	for input, expected := range cases {
+		// RUN
+		result := slug.Make(input)
+
+		// ASSERT
+		if result != expected {
+			t.Fatalf("unexpected slug for %q: %q", input, result)
+		}
+	}
+}
+
+func Test_Make_MaxLength(t *testing.T) {
+	// SETUP
+	title := strings.Repeat("word ", 50)
+
+	// RUN
+	result := slug.Make(title)
+
+	// ASSERT
+	if utf8.RuneCountInString(result) > slug.MaxLength {
+		t.Fatalf("slug too long: %d", utf8.RuneCountInString(result))
+	}
+
+	if strings.HasSuffix(result, "-") {
+		t.Fatalf("unexpected trailing hyphen: %q", result)
+	}
+}
+
+func Test_Make_MaxLength_Marks(t *testing.T) {
+	// SETUP
+	titles := []string{
+		strings.Repeat("हिन्दी ", 60),
+		strings.Repeat("ภาษาไทยที่นี่ ", 30),
+		strings.Repeat("a ", 47) + "bc",
+	}
+
+	for _, title := range titles {
+		// RUN
+		result := slug.Make(title)
+
+		// ASSERT
+		if utf8.RuneCountInString(result) > slug.MaxLength {
+			t.Fatalf("slug too long: %d", utf8.RuneCountInString(result))
+		}
+
+		if strings.HasSuffix(result, "-") {
+			t.Fatalf("unexpected trailing hyphen: %q", result)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eriktate/divulge/totp"
+)
+
+// rfcSecret is the SHA-1 secret from the test vectors in RFC 6238, appendix B.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func Test_Code(t *testing.T) {
+	// SETUP
+	// the RFC's codes are eight digits, these are their last six
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, c := range cases {
+		// RUN
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(c.unix, 0)))
+
+		// ASSERT
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if code != c.code {
+			t.Fatalf("expected %s at %d, got %s", c.code, c.unix, code)
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	// SETUP
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	now := time.Unix(1600000000, 0)
+	current, _ := totp.Code(secret, totp.Step(now))
+	previous, _ := totp.Code(secret, totp.Step(now)-1)
+	stale, _ := totp.Code(secret, totp.Step(now)-2)
+
+	// RUN
+	step, currentOK := totp.Validate(secret, current, now)
+	_, previousOK := totp.Validate(secret, previous, now)
+	_, staleOK := totp.Validate(secret, stale, now)
+	_, shortOK := totp.Validate(secret, current[:5], now)
+
+	// ASSERT
+	if !currentOK || step != totp.Step(now) {
+		t.Fatalf("expected the current code to match step %d, got %d", totp.Step(now), step)
+	}
+
+	if !previousOK {
+		t.Fatal("expected a code from the previous period to be accepted")
+	}
+
+	if staleOK || shortOK {
+		t.Fatal("expected stale and short codes to be rejected")
+	}
+}
+
+func Test_URI(t *testing.T) {
+	// RUN
+	uri := totp.URI("Divulge", "test@test.com", "JBSWY3DPEHPK3PXP")
+
+	// ASSERT
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/Divulge:test@test.com" {
+		t.Fatalf("unexpected uri: %s", uri)
+	}
+
+	query := parsed.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Divulge" || query.Get("digits") != "6" {
+		t.Fatalf("unexpected parameters: %s", parsed.RawQuery)
+	}
+}